@@ -9,6 +9,7 @@ import (
 	"flag"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -22,7 +23,9 @@ import (
 	"golang.org/x/pkgsite/internal/database"
 	"golang.org/x/pkgsite/internal/dcensus"
 	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/fetch"
 	"golang.org/x/pkgsite/internal/frontend"
+	"golang.org/x/pkgsite/internal/localdatasource"
 	"golang.org/x/pkgsite/internal/log"
 	"golang.org/x/pkgsite/internal/middleware"
 	"golang.org/x/pkgsite/internal/postgres"
@@ -42,6 +45,12 @@ var (
 		"for direct proxy mode and frontend fetches")
 	directProxy = flag.Bool("direct_proxy", false, "if set to true, uses the module proxy referred to by this URL "+
 		"as a direct backend, bypassing the database")
+	localPaths = flag.String("local", "", "comma-separated list of module directories to serve documentation for, "+
+		"bypassing the database and the module proxy")
+	gopathRoots = flag.String("gopath", "", "list of GOPATH roots, separated as in $GOPATH, in which to look up "+
+		"packages that are not in a -local module directory")
+	enablePreview = flag.Bool("preview", false, "if set to true, serves /preview/, where module zips can be uploaded "+
+		"to preview their documentation")
 )
 
 func main() {
@@ -66,7 +75,19 @@ func main() {
 	if err != nil {
 		log.Fatal(ctx, err)
 	}
-	if *directProxy {
+	if *localPaths != "" || *gopathRoots != "" {
+		lds := localdatasource.New(filepath.SplitList(*gopathRoots)...)
+		for _, dir := range strings.Split(*localPaths, ",") {
+			if dir == "" {
+				continue
+			}
+			if err := lds.AddModuleDir(dir); err != nil {
+				log.Fatal(ctx, err)
+			}
+		}
+		ds = lds
+		exp = internal.NewLocalExperimentSource(readLocalExperiments(ctx))
+	} else if *directProxy {
 		ds = proxydatasource.New(proxyClient)
 		exp = internal.NewLocalExperimentSource(readLocalExperiments(ctx))
 	} else {
@@ -80,13 +101,15 @@ func main() {
 			log.Fatal(ctx, err)
 		}
 		db := postgres.New(ddb)
+		db.SetSearchSettings(cfg.Search)
 		defer db.Close()
 		ds = db
 		exp = db
 		sourceClient := source.NewClient(config.SourceTimeout)
+		fetchOpts := fetch.Options{Settings: cfg.Fetch, RenderCache: db, Symbols: db, APIHistory: db}
 		fetchQueue, err = queue.New(ctx, cfg, queueName, *workers, db,
 			func(ctx context.Context, modulePath, version string) (int, error) {
-				return frontend.FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, db)
+				return frontend.FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, fetchOpts, db)
 			})
 		if err != nil {
 			log.Fatalf(ctx, "queue.New: %v", err)
@@ -107,6 +130,7 @@ func main() {
 		ThirdPartyPath:       *thirdPartyPath,
 		DevMode:              *devMode,
 		AppVersionLabel:      cfg.AppVersionLabel(),
+		EnablePreview:        *enablePreview,
 	})
 	if err != nil {
 		log.Fatalf(ctx, "frontend.NewServer: %v", err)
@@ -127,6 +151,7 @@ func main() {
 		middleware.CacheResultCount,
 		middleware.CacheErrorCount,
 		middleware.QuotaResultCount,
+		fetch.RenderCacheResultCount,
 	)
 	if err := dcensus.Init(cfg, views...); err != nil {
 		log.Fatal(ctx, err)
@@ -22,6 +22,7 @@ import (
 	"golang.org/x/pkgsite/internal/config"
 	"golang.org/x/pkgsite/internal/database"
 	"golang.org/x/pkgsite/internal/dcensus"
+	"golang.org/x/pkgsite/internal/fetch"
 	"golang.org/x/pkgsite/internal/index"
 	"golang.org/x/pkgsite/internal/queue"
 	"golang.org/x/pkgsite/internal/source"
@@ -84,9 +85,10 @@ func main() {
 		log.Fatal(ctx, err)
 	}
 	sourceClient := source.NewClient(config.SourceTimeout)
+	fetchOpts := fetch.Options{Settings: cfg.Fetch, RenderCache: db, Symbols: db, APIHistory: db}
 	fetchQueue, err := queue.New(ctx, cfg, queueName, *workers, db,
 		func(ctx context.Context, modulePath, version string) (int, error) {
-			return worker.FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, db, cfg.AppVersionLabel())
+			return worker.FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, fetchOpts, db, cfg.AppVersionLabel())
 		})
 	if err != nil {
 		log.Fatalf(ctx, "queue.New: %v", err)
@@ -100,6 +102,7 @@ func main() {
 		IndexClient:          indexClient,
 		ProxyClient:          proxyClient,
 		SourceClient:         sourceClient,
+		FetchOptions:         fetchOpts,
 		RedisHAClient:        redisHAClient,
 		RedisCacheClient:     redisCacheClient,
 		Queue:                fetchQueue,
@@ -114,6 +117,7 @@ func main() {
 	server.Install(router.Handle)
 
 	views := append(dcensus.ClientViews, dcensus.ServerViews...)
+	views = append(views, fetch.RenderCacheResultCount)
 	if err := dcensus.Init(cfg, views...); err != nil {
 		log.Fatal(ctx, err)
 	}
@@ -18,6 +18,8 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
+	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -103,6 +105,12 @@ type Config struct {
 	UseProfiler bool
 
 	Quota QuotaSettings
+
+	// Search holds the weights used to rank search results.
+	Search SearchSettings
+
+	// Fetch holds the limits on processing a module version.
+	Fetch FetchSettings
 }
 
 // AppVersionLabel returns the version label for the current instance.  This is
@@ -188,6 +196,16 @@ type configOverride struct {
 	DBSecondaryHost string
 	DBName          string
 	Quota           QuotaSettings
+	Search          searchOverride
+}
+
+// searchOverride holds overrides for SearchSettings. The floats are pointers,
+// so we can distinguish "not present" from 0, which disables the recency
+// factor.
+type searchOverride struct {
+	RecencyWeight       *float64
+	RecencyHalfLifeDays *float64
+	ActiveVersions      int
 }
 
 // QuotaSettings is config for internal/middleware/quota.go
@@ -203,6 +221,70 @@ type QuotaSettings struct {
 	AcceptedURLs []string
 }
 
+// SearchSettings is config for ranking search results in
+// internal/postgres/search.go.
+//
+// The score of each search result is multiplied by a recency factor between
+// 1-RecencyWeight and 1. The factor is 1 for a module whose latest version was
+// just committed, and decays towards 1-RecencyWeight as that commit ages. A
+// module that has tagged at least ActiveVersions release versions in the past
+// year is not penalized, and one that has tagged fewer is penalized
+// proportionally less.
+type SearchSettings struct {
+	// RecencyWeight is the largest fraction of a search score that can be lost
+	// to inactivity. It must be between 0 and 1. Zero disables the recency
+	// factor.
+	RecencyWeight float64
+	// RecencyHalfLifeDays is the age in days of a module's latest commit at
+	// which half of RecencyWeight is lost.
+	RecencyHalfLifeDays float64
+	// ActiveVersions is the number of release versions tagged in the past year
+	// at which a module is considered fully active.
+	ActiveVersions int
+}
+
+// validate reports whether the search settings are usable.
+func (s SearchSettings) validate() error {
+	if s.RecencyWeight < 0 || s.RecencyWeight > 1 {
+		return fmt.Errorf("search recency weight %g is not between 0 and 1", s.RecencyWeight)
+	}
+	if s.RecencyHalfLifeDays <= 0 {
+		return fmt.Errorf("search recency half-life %g is not positive", s.RecencyHalfLifeDays)
+	}
+	if s.ActiveVersions <= 0 {
+		return fmt.Errorf("search active version count %d is not positive", s.ActiveVersions)
+	}
+	return nil
+}
+
+// FetchSettings is config for processing module versions in
+// internal/fetch.
+//
+// The packages of a module are loaded concurrently, by at most
+// PackageWorkers goroutines at a time. Loading a package uses memory in
+// proportion to the size of its source, so the total size of the .go files of
+// the packages being loaded at once is also limited.
+type FetchSettings struct {
+	// PackageWorkers is the maximum number of packages of a module that are
+	// loaded at the same time.
+	PackageWorkers int
+	// PackageMemoryBudgetMB is the maximum total size, in megabytes, of the
+	// .go files of the packages that are loaded at the same time. A package
+	// whose files are larger than the budget is loaded by itself.
+	PackageMemoryBudgetMB int
+}
+
+// validate reports whether the fetch settings are usable.
+func (s FetchSettings) validate() error {
+	if s.PackageWorkers <= 0 {
+		return fmt.Errorf("fetch package worker count %d is not positive", s.PackageWorkers)
+	}
+	if s.PackageMemoryBudgetMB <= 0 {
+		return fmt.Errorf("fetch package memory budget %dMB is not positive", s.PackageMemoryBudgetMB)
+	}
+	return nil
+}
+
 const overrideBucket = "go-discovery"
 
 // Init resolves all configuration values provided by the config package. It
@@ -249,6 +331,26 @@ func Init(ctx context.Context) (_ *Config, err error) {
 		},
 		UseProfiler: os.Getenv("GO_DISCOVERY_USE_PROFILER") == "TRUE",
 	}
+	cfg.Search.RecencyWeight, err = strconv.ParseFloat(GetEnv("GO_DISCOVERY_SEARCH_RECENCY_WEIGHT", "0.2"), 64)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Search.RecencyHalfLifeDays, err = strconv.ParseFloat(GetEnv("GO_DISCOVERY_SEARCH_RECENCY_HALF_LIFE_DAYS", "730"), 64)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Search.ActiveVersions, err = strconv.Atoi(GetEnv("GO_DISCOVERY_SEARCH_ACTIVE_VERSIONS", "3"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Fetch.PackageWorkers, err = strconv.Atoi(GetEnv("GO_DISCOVERY_FETCH_PACKAGE_WORKERS", strconv.Itoa(runtime.NumCPU())))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Fetch.PackageMemoryBudgetMB, err = strconv.Atoi(GetEnv("GO_DISCOVERY_FETCH_PACKAGE_MEMORY_BUDGET_MB", "200"))
+	if err != nil {
+		return nil, err
+	}
 	cfg.AppMonitoredResource = &mrpb.MonitoredResource{
 		Type: "gae_app",
 		Labels: map[string]string{
@@ -292,6 +394,12 @@ func Init(ctx context.Context) (_ *Config, err error) {
 			processOverrides(cfg, overrideBytes)
 		}
 	}
+	if err := cfg.Search.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Fetch.validate(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
 
@@ -324,6 +432,9 @@ func processOverrides(cfg *Config, bytes []byte) {
 	overrideInt("Quota.Burst", &cfg.Quota.Burst, ov.Quota.Burst)
 	overrideInt("Quota.MaxEntries", &cfg.Quota.MaxEntries, ov.Quota.MaxEntries)
 	overrideBool("Quota.RecordOnly", &cfg.Quota.RecordOnly, ov.Quota.RecordOnly)
+	overrideFloat("Search.RecencyWeight", &cfg.Search.RecencyWeight, ov.Search.RecencyWeight)
+	overrideFloat("Search.RecencyHalfLifeDays", &cfg.Search.RecencyHalfLifeDays, ov.Search.RecencyHalfLifeDays)
+	overrideInt("Search.ActiveVersions", &cfg.Search.ActiveVersions, ov.Search.ActiveVersions)
 }
 
 func overrideString(name string, field *string, val string) {
@@ -340,6 +451,13 @@ func overrideInt(name string, field *int, val int) {
 	}
 }
 
+func overrideFloat(name string, field *float64, val *float64) {
+	if val != nil {
+		*field = *val
+		log.Printf("overriding %s with %g", name, *val)
+	}
+}
+
 func overrideBool(name string, field **bool, val *bool) {
 	if val != nil {
 		*field = val
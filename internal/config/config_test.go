@@ -59,12 +59,15 @@ func TestProcessOverrides(t *testing.T) {
 		DBHost: "origHost",
 		DBName: "origName",
 		Quota:  QuotaSettings{QPS: 1, Burst: 2, MaxEntries: 3, RecordOnly: &tr},
+		Search: SearchSettings{RecencyWeight: 0.2, RecencyHalfLifeDays: 730, ActiveVersions: 3},
 	}
 	ov := `
         DBHost: newHost
         Quota:
            MaxEntries: 17
            RecordOnly: false
+        Search:
+           RecencyWeight: 0
     `
 	processOverrides(&cfg, []byte(ov))
 	got := cfg
@@ -72,6 +75,7 @@ func TestProcessOverrides(t *testing.T) {
 		DBHost: "newHost",
 		DBName: "origName",
 		Quota:  QuotaSettings{QPS: 1, Burst: 2, MaxEntries: 17, RecordOnly: &f},
+		Search: SearchSettings{RecencyWeight: 0, RecencyHalfLifeDays: 730, ActiveVersions: 3},
 	}
 	if diff := cmp.Diff(want, got); diff != "" {
 		t.Errorf("mismatch (-want, +got):\n%s", diff)
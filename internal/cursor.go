@@ -0,0 +1,61 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package internal
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// SearchCursor is the position of a search result in the order of search
+// results: by decreasing score, then by decreasing commit time, then by
+// package path. A page of search results that starts after a cursor does not
+// depend on the number of results before it, so it can be read without
+// scanning them, and it does not repeat or skip results if their scores
+// change between requests.
+type SearchCursor struct {
+	Score       float64
+	CommitTime  time.Time
+	PackagePath string
+}
+
+// Encode returns an opaque string representation of c, which is safe to use
+// in a URL. It can be decoded with DecodeSearchCursor.
+func (c *SearchCursor) Encode() string {
+	s := fmt.Sprintf("%s %d %s", strconv.FormatFloat(c.Score, 'g', -1, 64), c.CommitTime.UnixNano(), c.PackagePath)
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+// DecodeSearchCursor decodes a cursor returned by SearchCursor.Encode.
+func DecodeSearchCursor(s string) (_ *SearchCursor, err error) {
+	defer derrors.Wrap(&err, "DecodeSearchCursor(%q)", s)
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, derrors.InvalidArgument)
+	}
+	parts := strings.SplitN(string(b), " ", 3)
+	if len(parts) != 3 || parts[2] == "" {
+		return nil, fmt.Errorf("malformed cursor: %w", derrors.InvalidArgument)
+	}
+	score, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, derrors.InvalidArgument)
+	}
+	nanos, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, derrors.InvalidArgument)
+	}
+	return &SearchCursor{
+		Score:       score,
+		CommitTime:  time.Unix(0, nanos).UTC(),
+		PackagePath: parts[2],
+	}, nil
+}
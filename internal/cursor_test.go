@@ -0,0 +1,39 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package internal
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+func TestSearchCursor(t *testing.T) {
+	for _, c := range []*SearchCursor{
+		{Score: 0.123456789, CommitTime: time.Date(2020, 6, 1, 12, 30, 0, 123456000, time.UTC), PackagePath: "github.com/foo/bar"},
+		{Score: 3, CommitTime: time.Unix(0, 0).UTC(), PackagePath: "golang.org/x/tools/go/packages"},
+		{Score: 1e-20, CommitTime: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), PackagePath: "example.com/path with spaces"},
+		// A score that needs all 17 significant digits.
+		{Score: math.Nextafter(0.1, 1), CommitTime: time.Unix(0, 0).UTC(), PackagePath: "example.com/a"},
+	} {
+		got, err := DecodeSearchCursor(c.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(c, got); diff != "" {
+			t.Errorf("DecodeSearchCursor(%q) mismatch (-want +got):\n%s", c.Encode(), diff)
+		}
+	}
+
+	for _, s := range []string{"", "!!", "MSAy", "eCAxIGE", "MSB4IGE"} {
+		if _, err := DecodeSearchCursor(s); !errors.Is(err, derrors.InvalidArgument) {
+			t.Errorf("DecodeSearchCursor(%q): got error %v, want InvalidArgument", s, err)
+		}
+	}
+}
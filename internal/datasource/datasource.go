@@ -0,0 +1,218 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package datasource implements the parts of an internal.DataSource that are
+// shared by the datasources that process whole modules and hold them in
+// memory, such as proxydatasource and localdatasource.
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/licenses"
+)
+
+// ModuleDataSource implements the methods of internal.DataSource that can be
+// answered from a single processed module. Datasources embed it and
+// implement the methods that list versions themselves.
+type ModuleDataSource struct {
+	getModule        func(ctx context.Context, modulePath, version string) (*internal.Module, error)
+	getModuleForPath func(ctx context.Context, fullPath, modulePath, version string) (*internal.Module, error)
+}
+
+// NewModuleDataSource returns a ModuleDataSource that gets modules with
+// getModule and getModuleForPath.
+//
+// getModule returns the module with modulePath at version. getModuleForPath
+// does the same, except that if modulePath is internal.UnknownModulePath, it
+// returns the module at version containing the package or directory with
+// fullPath.
+func NewModuleDataSource(
+	getModule func(ctx context.Context, modulePath, version string) (*internal.Module, error),
+	getModuleForPath func(ctx context.Context, fullPath, modulePath, version string) (*internal.Module, error),
+) *ModuleDataSource {
+	return &ModuleDataSource{
+		getModule:        getModule,
+		getModuleForPath: getModuleForPath,
+	}
+}
+
+// LegacyGetDirectory returns packages contained in the given subdirectory of a module version.
+func (ds *ModuleDataSource) LegacyGetDirectory(ctx context.Context, dirPath, modulePath, version string, _ internal.FieldSet) (_ *internal.LegacyDirectory, err error) {
+	defer derrors.Wrap(&err, "LegacyGetDirectory(%q, %q, %q)", dirPath, modulePath, version)
+
+	m, err := ds.getModuleForPath(ctx, dirPath, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	var pkgs []*internal.LegacyPackage
+	for _, p := range m.LegacyPackages {
+		if p.Path == dirPath || strings.HasPrefix(p.Path, dirPath+"/") {
+			pkgs = append(pkgs, p)
+		}
+	}
+	return &internal.LegacyDirectory{
+		LegacyModuleInfo: internal.LegacyModuleInfo{ModuleInfo: m.ModuleInfo},
+		Path:             dirPath,
+		Packages:         pkgs,
+	}, nil
+}
+
+// GetDirectoryNew returns information about a directory at a path.
+func (ds *ModuleDataSource) GetDirectoryNew(ctx context.Context, dirPath, modulePath, version string) (_ *internal.VersionedDirectory, err error) {
+	defer derrors.Wrap(&err, "GetDirectoryNew(%q, %q, %q)", dirPath, modulePath, version)
+
+	m, err := ds.getModule(ctx, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	return &internal.VersionedDirectory{
+		ModuleInfo: m.ModuleInfo,
+		DirectoryNew: internal.DirectoryNew{
+			DirectoryMeta: internal.DirectoryMeta{
+				Path:   dirPath,
+				V1Path: internal.V1Path(modulePath, strings.TrimPrefix(dirPath, modulePath+"/")),
+			},
+		},
+	}, nil
+}
+
+// GetImports returns package imports as extracted from the module zip.
+func (ds *ModuleDataSource) GetImports(ctx context.Context, pkgPath, modulePath, version string) (_ []string, err error) {
+	defer derrors.Wrap(&err, "GetImports(%q, %q, %q)", pkgPath, modulePath, version)
+
+	vp, err := ds.LegacyGetPackage(ctx, pkgPath, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	return vp.Imports, nil
+}
+
+// LegacyGetModuleLicenses returns root-level licenses detected within the module zip
+// for modulePath and version.
+func (ds *ModuleDataSource) LegacyGetModuleLicenses(ctx context.Context, modulePath, version string) (_ []*licenses.License, err error) {
+	defer derrors.Wrap(&err, "LegacyGetModuleLicenses(%q, %q)", modulePath, version)
+
+	m, err := ds.getModule(ctx, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	var filtered []*licenses.License
+	for _, lic := range m.Licenses {
+		if !strings.Contains(lic.FilePath, "/") {
+			filtered = append(filtered, lic)
+		}
+	}
+	return filtered, nil
+}
+
+// LegacyGetPackage returns a LegacyVersionedPackage for the given pkgPath and
+// version. If modulePath is unknown, the package is looked up in the module
+// with the longest path that contains it.
+func (ds *ModuleDataSource) LegacyGetPackage(ctx context.Context, pkgPath, modulePath, version string) (_ *internal.LegacyVersionedPackage, err error) {
+	defer derrors.Wrap(&err, "LegacyGetPackage(%q, %q, %q)", pkgPath, modulePath, version)
+
+	m, err := ds.getModuleForPath(ctx, pkgPath, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range m.LegacyPackages {
+		if p.Path == pkgPath {
+			return &internal.LegacyVersionedPackage{
+				LegacyPackage:    *p,
+				LegacyModuleInfo: m.LegacyModuleInfo,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("package missing from module %s: %w", m.ModulePath, derrors.NotFound)
+}
+
+// LegacyGetPackageLicenses returns the Licenses that apply to pkgPath within the
+// module version specified by modulePath and version.
+func (ds *ModuleDataSource) LegacyGetPackageLicenses(ctx context.Context, pkgPath, modulePath, version string) (_ []*licenses.License, err error) {
+	defer derrors.Wrap(&err, "LegacyGetPackageLicenses(%q, %q, %q)", pkgPath, modulePath, version)
+
+	m, err := ds.getModule(ctx, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range m.LegacyPackages {
+		if p.Path != pkgPath {
+			continue
+		}
+		var lics []*licenses.License
+		for _, lmd := range p.Licenses {
+			// lmd is just license metadata; the module has the actual licenses.
+			for _, lic := range m.Licenses {
+				if lic.FilePath == lmd.FilePath {
+					lics = append(lics, lic)
+					break
+				}
+			}
+		}
+		return lics, nil
+	}
+	return nil, fmt.Errorf("package %s is missing from module %s: %w", pkgPath, modulePath, derrors.NotFound)
+}
+
+// LegacyGetPackagesInModule returns LegacyPackages contained in the module zip corresponding to modulePath and version.
+func (ds *ModuleDataSource) LegacyGetPackagesInModule(ctx context.Context, modulePath, version string) (_ []*internal.LegacyPackage, err error) {
+	defer derrors.Wrap(&err, "LegacyGetPackagesInModule(%q, %q)", modulePath, version)
+
+	m, err := ds.getModule(ctx, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	return m.LegacyPackages, nil
+}
+
+// GetModuleInfo returns the ModuleInfo of the module version specified by
+// modulePath and version.
+func (ds *ModuleDataSource) GetModuleInfo(ctx context.Context, modulePath, version string) (_ *internal.ModuleInfo, err error) {
+	defer derrors.Wrap(&err, "GetModuleInfo(%q, %q)", modulePath, version)
+
+	m, err := ds.getModule(ctx, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	return &m.ModuleInfo, nil
+}
+
+// LegacyGetModuleInfo returns the LegacyModuleInfo of the module version
+// specified by modulePath and version.
+func (ds *ModuleDataSource) LegacyGetModuleInfo(ctx context.Context, modulePath, version string) (_ *internal.LegacyModuleInfo, err error) {
+	defer derrors.Wrap(&err, "LegacyGetModuleInfo(%q, %q)", modulePath, version)
+
+	m, err := ds.getModule(ctx, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	return &m.LegacyModuleInfo, nil
+}
+
+// GetExperiments is unimplemented.
+func (*ModuleDataSource) GetExperiments(ctx context.Context) ([]*internal.Experiment, error) {
+	return nil, nil
+}
+
+// GetPathInfo returns information about the given path.
+func (ds *ModuleDataSource) GetPathInfo(ctx context.Context, path, inModulePath, inVersion string) (outModulePath, outVersion string, isPackage bool, err error) {
+	defer derrors.Wrap(&err, "GetPathInfo(%q, %q, %q)", path, inModulePath, inVersion)
+
+	m, err := ds.getModuleForPath(ctx, path, inModulePath, inVersion)
+	if err != nil {
+		return "", "", false, err
+	}
+	for _, p := range m.LegacyPackages {
+		if p.Path == path {
+			isPackage = true
+			break
+		}
+	}
+	return m.ModulePath, m.Version, isPackage, nil
+}
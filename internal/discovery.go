@@ -10,6 +10,7 @@ import (
 
 	"github.com/google/safehtml"
 	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
 	"golang.org/x/pkgsite/internal/licenses"
 	"golang.org/x/pkgsite/internal/source"
 	"golang.org/x/pkgsite/internal/stdlib"
@@ -40,6 +41,19 @@ type ModuleInfo struct {
 	IsRedistributable bool
 	HasGoMod          bool // whether the module zip has a go.mod file
 	SourceInfo        *source.Info
+
+	// Retracted reports whether this version is retracted by the go.mod file
+	// of the latest version of the module, for the reason in
+	// RetractionRationale, which may be empty.
+	Retracted           bool
+	RetractionRationale string
+	// Deprecation is the deprecation message in the go.mod file of the latest
+	// version of the module. It is empty if the module is not deprecated.
+	Deprecation string
+	// GoVersion is the version of the go directive in the go.mod file, such
+	// as "1.14": the minimum Go release the module expects. It is empty if
+	// the go.mod file has no go directive.
+	GoVersion string
 }
 
 // LegacyModuleInfo holds metadata associated with a module.
@@ -100,10 +114,69 @@ type Module struct {
 	// that may be contained in nested subdirectories.
 	Licenses    []*licenses.License
 	Directories []*DirectoryNew
+	// Dependencies holds the require, replace and exclude directives of the
+	// module's go.mod file.
+	Dependencies []*ModuleDependency
+	// GoModRetractions and GoModDeprecation hold the retract directives and
+	// the deprecation message of the module's go.mod file. They apply to the
+	// module only if this is its latest version; see ModuleInfo.Retracted and
+	// ModuleInfo.Deprecation.
+	GoModRetractions []*Retraction
+	GoModDeprecation string
 
 	LegacyPackages []*LegacyPackage
 }
 
+// DependencyKind is the kind of go.mod directive that a ModuleDependency
+// comes from.
+type DependencyKind string
+
+const (
+	DependencyRequire DependencyKind = "require"
+	DependencyReplace DependencyKind = "replace"
+	DependencyExclude DependencyKind = "exclude"
+)
+
+// ModuleDependency is a directive in the go.mod file of a module version that
+// refers to another module.
+type ModuleDependency struct {
+	Kind       DependencyKind
+	ModulePath string
+	// Version is the required or excluded version of ModulePath. For a
+	// replace directive, it is the version that is replaced, and is empty if
+	// all versions are replaced.
+	Version string
+	// Indirect reports whether a requirement is marked "// indirect".
+	Indirect bool
+	// ReplacementPath and ReplacementVersion are the target of a replace
+	// directive. ReplacementVersion is empty if ReplacementPath is a
+	// directory.
+	ReplacementPath    string
+	ReplacementVersion string
+}
+
+// Retraction is a retract directive in a go.mod file. It retracts the
+// versions from Low to High, inclusive.
+type Retraction struct {
+	Low       string
+	High      string
+	Rationale string
+}
+
+// Contains reports whether r retracts version.
+func (r *Retraction) Contains(version string) bool {
+	return semver.Compare(r.Low, version) <= 0 && semver.Compare(version, r.High) <= 0
+}
+
+// ModuleDependent is a module version that requires another module.
+type ModuleDependent struct {
+	ModulePath string
+	Version    string
+	// RequiredVersion is the version of the other module that ModulePath
+	// requires.
+	RequiredVersion string
+}
+
 // VersionedDirectory is a DirectoryNew along with its corresponding module
 // information.
 type VersionedDirectory struct {
@@ -132,10 +205,22 @@ type DirectoryNew struct {
 // header. A PackageNew is part of a directory.
 // It will replace LegacyPackage once everything has been migrated.
 type PackageNew struct {
-	Name          string
-	Path          string
+	Name string
+	Path string
+	// Documentation is the documentation for the first of BuildContexts in
+	// which the package builds.
 	Documentation *Documentation
-	Imports       []string
+	// OtherDocumentation holds the documentation for the other build
+	// contexts in which the package builds, in the order of BuildContexts.
+	// Documentation that is the same as that of an earlier build context is
+	// left out.
+	OtherDocumentation []*Documentation
+	Imports            []string
+	// UsesCgo reports whether any file of the package imports "C".
+	UsesCgo bool
+	// GoReleaseTags holds the Go release tags, such as "go1.14", that the
+	// build constraints of the package's files require, in increasing order.
+	GoReleaseTags []string
 }
 
 // Documentation is the rendered documentation for a given package
@@ -149,6 +234,84 @@ type Documentation struct {
 	HTML     safehtml.HTML
 }
 
+// A BuildContext is a GOOS/GOARCH pair for which package documentation is
+// rendered.
+type BuildContext struct {
+	GOOS, GOARCH string
+}
+
+// BuildContexts are the build contexts in which packages are loaded. The
+// documentation of the first one in which a package builds is shown by
+// default.
+var BuildContexts = []BuildContext{
+	{"linux", "amd64"},
+	{"windows", "amd64"},
+	{"darwin", "amd64"},
+	{"js", "wasm"},
+	{"linux", "js"},
+}
+
+// String returns c in the form GOOS/GOARCH.
+func (c BuildContext) String() string {
+	return c.GOOS + "/" + c.GOARCH
+}
+
+// BuildContextIndex returns the position of the build context with the given
+// GOOS and GOARCH in BuildContexts, or len(BuildContexts) if it is not one of
+// them.
+func BuildContextIndex(goos, goarch string) int {
+	for i, c := range BuildContexts {
+		if c.GOOS == goos && c.GOARCH == goarch {
+			return i
+		}
+	}
+	return len(BuildContexts)
+}
+
+// SymbolKind is the kind of declaration that a Symbol refers to.
+type SymbolKind string
+
+const (
+	SymbolKindConstant SymbolKind = "constant"
+	SymbolKindVariable SymbolKind = "variable"
+	SymbolKindFunction SymbolKind = "function"
+	SymbolKindType     SymbolKind = "type"
+	SymbolKindMethod   SymbolKind = "method"
+	// SymbolKindField is a field of a struct type, including an embedded
+	// field, or a type embedded in an interface type.
+	SymbolKindField SymbolKind = "field"
+)
+
+// A Symbol is an exported identifier declared in a package: a constant,
+// variable, function, type, method of a type or of an interface type, or
+// field of a struct type.
+type Symbol struct {
+	Name string
+	Kind SymbolKind
+	// ParentName is the name of the type that a method or field belongs to.
+	// It is empty for all other kinds of symbols.
+	ParentName string
+	// Synopsis is a one-line summary of the symbol's declaration, as shown in
+	// the index of the package documentation.
+	Synopsis string
+}
+
+// Anchor returns the id of the symbol's documentation on the package page.
+func (s *Symbol) Anchor() string {
+	if s.ParentName != "" {
+		return s.ParentName + "." + s.Name
+	}
+	return s.Name
+}
+
+// PackageSymbols holds the name and exported identifiers of a package, so
+// that the documentation of other packages can link to them.
+type PackageSymbols struct {
+	Path    string
+	Name    string
+	Symbols []*Symbol
+}
+
 // Readme is a README at a given directory.
 type Readme struct {
 	Filepath string
@@ -225,6 +388,18 @@ type SearchResult struct {
 	Synopsis    string
 	Licenses    []string
 
+	// IsModule reports whether the result is a module that has no package at
+	// its root, rather than a package. PackagePath is then the module path,
+	// and Synopsis is taken from the module README.
+	IsModule bool
+
+	// ReadmeSection and ReadmeAnchor are the heading and heading anchor of the
+	// section of the result's README that best matches the search query. They
+	// are empty if no section of the README matches, or if the README is not
+	// indexed by section.
+	ReadmeSection string
+	ReadmeAnchor  string
+
 	CommitTime time.Time
 	// Score is used to sort items in an array of SearchResult.
 	Score float64
@@ -239,6 +414,138 @@ type SearchResult struct {
 	// can be approximate if search scanned only a subset of documents, and
 	// result count is estimated using the hyperloglog algorithm.
 	Approximate bool
+	// Suggestion is a correction of the search query, set when the query
+	// has few exact matches and the results include packages whose path or
+	// name is only similar to it.
+	Suggestion string
+
+	// OtherMajor holds the same package in the other major versions of its
+	// module series, newest first. Only the path, version, IsModule and
+	// imported-by fields are set.
+	OtherMajor []*SearchResult
+
+	// Cursor is the position of the result in the order of search results.
+	// The next page of results starts after the cursor of the last result.
+	Cursor *SearchCursor
+}
+
+// SearchFilters restricts the results of a search to packages with the given
+// properties. The zero value places no restrictions on the results.
+type SearchFilters struct {
+	// Licenses restricts results to packages with at least one of these
+	// license types.
+	Licenses []string
+
+	// ModulePath restricts results to packages in the module with this path,
+	// or in a module whose path has this path as a prefix.
+	ModulePath string
+
+	// Imports restricts results to packages that import this package.
+	Imports string
+
+	// HasGoMod, if non-nil, restricts results to packages whose module does or
+	// does not have a go.mod file.
+	HasGoMod *bool
+
+	// VersionType, if non-empty, restricts results to packages whose latest
+	// version has this type.
+	VersionType version.Type
+
+	// GoVersion, if non-empty, restricts results to packages whose module
+	// requires at most this version of Go in its go directive, such as "1.13".
+	// Modules without a go directive match any version.
+	GoVersion string
+
+	// MajorVersion, if non-empty, restricts results to packages whose latest
+	// version has this major version, such as "v2".
+	MajorVersion string
+}
+
+// SearchFacets holds counts of the packages matching a search, grouped by
+// properties that can be used to refine the search.
+type SearchFacets struct {
+	Licenses []*FacetValue // license types
+	Hosts    []*FacetValue // first element of the module path
+	Modules  []*FacetValue // module paths
+	Majors   []*FacetValue // major versions of the latest version, like "v2"
+	GoMod    []*FacetValue // "true" or "false": whether the module has a go.mod file
+
+	// Approximate reports whether the counts are estimated from a sample of
+	// the matching packages.
+	Approximate bool
+}
+
+// FacetValue is the number of search results that have a given value for
+// a facet.
+type FacetValue struct {
+	Value string
+	Count uint64
+}
+
+// SearchExplanation describes how a search was executed and how the scores
+// of its results were computed. It is used to debug search ranking.
+type SearchExplanation struct {
+	// Searchers holds the searchers that finished before the search
+	// returned, in the order they finished.
+	Searchers []*SearcherTiming
+	// Results holds the explanation of each search result, in the same order
+	// as the results.
+	Results []*ScoreExplanation
+}
+
+// SearcherTiming describes the execution of a single searcher.
+type SearcherTiming struct {
+	Name    string
+	Latency time.Duration
+	Err     string // the error returned by the searcher, if any
+}
+
+// ScoreExplanation holds the factors of the score of a search result. For
+// grouped results, the factors are those of the package that is shown, and
+// the score is that of the best scoring package of the group.
+type ScoreExplanation struct {
+	PackagePath string
+	// Source is the searcher that returned the result.
+	Source string
+	Score  float64
+	// TextRank is the relevance of the package to the query, as computed by
+	// ts_rank. It is a factor of the score for all searchers except trigram.
+	TextRank float64
+	// Similarity is the trigram similarity of the package to the query. It is
+	// a factor of the score for the trigram searcher only.
+	Similarity float64
+	// Popularity is the factor computed from the imported-by count.
+	Popularity float64
+	// RedistributablePenalty, GoModPenalty and Recency are the penalty
+	// factors; each is 1 if the penalty does not apply.
+	RedistributablePenalty float64
+	GoModPenalty           float64
+	Recency                float64
+	// Boost is the multiplier of the search boost that matches the package,
+	// or 1 if none does.
+	Boost float64
+}
+
+// SearchBoost is a multiplier of the search score of the packages whose path
+// begins with Prefix, or whose module path is Prefix (so that "std" matches
+// the standard library). A Multiplier greater than 1 ranks the packages
+// higher, and one less than 1 ranks them lower. If several boosts match a
+// package, the one with the longest prefix is used.
+type SearchBoost struct {
+	Prefix     string
+	Multiplier float64
+	CreatedBy  string
+	Reason     string
+}
+
+// SymbolSearchResult represents a single symbol returned by SymbolSearch.
+type SymbolSearchResult struct {
+	Symbol
+	PackageName   string
+	PackagePath   string
+	ModulePath    string
+	Version       string
+	NumImportedBy uint64
 }
 
 // A FieldSet is a bit set of struct fields. It is used to avoid reading large
@@ -296,9 +603,22 @@ type LegacyPackage struct {
 	GOOS   string
 	GOARCH string
 
+	// OtherDocumentation holds the documentation for the other build
+	// contexts in which the package builds, in the order of BuildContexts.
+	// Documentation that is the same as that of an earlier build context is
+	// left out.
+	OtherDocumentation []*Documentation
+
 	// V1Path is the package path of a package with major version 1 in a given
 	// series.
 	V1Path string
+
+	// Symbols are the exported identifiers declared in the package.
+	Symbols []*Symbol
+
+	// UsesCgo and GoReleaseTags are described in PackageNew.
+	UsesCgo       bool
+	GoReleaseTags []string
 }
 
 // LegacyVersionedPackage is a LegacyPackage along with its corresponding module
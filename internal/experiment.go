@@ -13,6 +13,7 @@ const (
 	ExperimentFrontendFetch               = "frontend-fetch"
 	ExperimentInsertPlaygroundLinks       = "insert-playground-links"
 	ExperimentMasterVersion               = "master-version"
+	ExperimentSearchExplain               = "search-explain"
 	ExperimentSidenav                     = "sidenav"
 	ExperimentTeeProxyMakePkgGoDevRequest = "teeproxy-make-pkg-go-dev-request"
 	ExperimentTranslateHTML               = "translate-html"
@@ -28,6 +29,7 @@ var Experiments = map[string]string{
 	ExperimentFrontendFetch:               "Enable ability to fetch a package that doesn't exist on pkg.go.dev.",
 	ExperimentInsertPlaygroundLinks:       "Insert Go playground links for examples.",
 	ExperimentMasterVersion:               "Enable viewing path@master.",
+	ExperimentSearchExplain:               "Enable the explain query parameter on the search page, which shows how results are scored.",
 	ExperimentSidenav:                     "Display documentation index on the left sidenav.",
 	ExperimentTeeProxyMakePkgGoDevRequest: "Enable teeproxy to make requests to pkg.go.dev.",
 	ExperimentTranslateHTML:               "Parse HTML text in READMEs, to properly display images.",
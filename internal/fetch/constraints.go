@@ -0,0 +1,100 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// releaseTagRE matches the Go release tags, such as "go1.14", that may appear
+// in build constraints.
+var releaseTagRE = regexp.MustCompile(`^go1\.(0|[1-9][0-9]*)$`)
+
+// toolchainRequirements reports whether any of the non-test files in files,
+// which maps file names to contents, imports "C", and returns the Go release
+// tags that their build constraints require, sorted by release. Negated tags
+// are not requirements, so they are ignored.
+//
+// The files must already be known to parse.
+func toolchainRequirements(files map[string][]byte) (usesCgo bool, releaseTags []string) {
+	fset := token.NewFileSet()
+	tags := map[string]bool{}
+	for name, b := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, b, parser.ImportsOnly|parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		for _, imp := range f.Imports {
+			if imp.Path.Value == `"C"` {
+				usesCgo = true
+			}
+		}
+		// Build constraints must appear before the package clause.
+		for _, g := range f.Comments {
+			if g.Pos() >= f.Package {
+				break
+			}
+			for _, c := range g.List {
+				for _, tag := range constraintReleaseTags(c.Text) {
+					tags[tag] = true
+				}
+			}
+		}
+	}
+	for tag := range tags {
+		releaseTags = append(releaseTags, tag)
+	}
+	sort.Slice(releaseTags, func(i, j int) bool {
+		return releaseMinor(releaseTags[i]) < releaseMinor(releaseTags[j])
+	})
+	return usesCgo, releaseTags
+}
+
+// constraintReleaseTags returns the non-negated Go release tags in comment,
+// if it is a "//go:build" or "// +build" line.
+func constraintReleaseTags(comment string) []string {
+	x, err := constraint.Parse(comment)
+	if err != nil {
+		return nil
+	}
+	var (
+		tags []string
+		walk func(x constraint.Expr, negated bool)
+	)
+	walk = func(x constraint.Expr, negated bool) {
+		switch x := x.(type) {
+		case *constraint.TagExpr:
+			if !negated && releaseTagRE.MatchString(x.Tag) {
+				tags = append(tags, x.Tag)
+			}
+		case *constraint.NotExpr:
+			walk(x.X, !negated)
+		case *constraint.AndExpr:
+			walk(x.X, negated)
+			walk(x.Y, negated)
+		case *constraint.OrExpr:
+			walk(x.X, negated)
+			walk(x.Y, negated)
+		}
+	}
+	walk(x, false)
+	return tags
+}
+
+// releaseMinor returns the minor version of a release tag that matches
+// releaseTagRE: 14 for "go1.14".
+func releaseMinor(tag string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(tag, "go1."))
+	return n
+}
@@ -69,6 +69,9 @@ func moduleDirectories(modulePath string,
 					Synopsis: pkg.Synopsis,
 					HTML:     pkg.DocumentationHTML,
 				},
+				OtherDocumentation: pkg.OtherDocumentation,
+				UsesCgo:            pkg.UsesCgo,
+				GoReleaseTags:      pkg.GoReleaseTags,
 			}
 		}
 		directories = append(directories, dir)
@@ -23,6 +23,7 @@ import (
 	"sort"
 	"strings"
 
+	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/fetch/dochtml/internal/render"
 	"golang.org/x/pkgsite/internal/fetch/internal/doc"
 )
@@ -45,6 +46,33 @@ type RenderOptions struct {
 	SourceLinkFunc func(ast.Node) string
 	PlayURLFunc    func(*doc.Example) string // If set, returns the Go playground URL for the example
 	Limit          int64                     // If zero, a default limit of 10 megabytes is used.
+
+	// PackageURLFunc optionally specifies a function that returns the URL
+	// of the documentation of the package with the given import path.
+	// If it is nil, or returns the empty string, the URL is "/pkg/"
+	// followed by the import path.
+	PackageURLFunc func(importPath string) (url string)
+
+	// RelatedPackages describes packages imported by the package, so that
+	// references to their identifiers in the documentation are linked.
+	RelatedPackages []*RelatedPackage
+
+	// SinceVersionFunc optionally specifies a function that returns the
+	// version in which the function, type or method with the given anchor
+	// ID ("F", "T" or "T.M") was added to the package. If it is nil, or
+	// returns the empty string, no version is shown.
+	SinceVersionFunc func(id string) string
+}
+
+// A RelatedPackage describes a package imported by the package being
+// rendered.
+type RelatedPackage struct {
+	ImportPath string
+	Name       string
+	// IDs are the exported top-level identifiers of the package, in the
+	// form used for anchors: "Reader" for a type, "Reader.Read" for a
+	// method.
+	IDs []string
 }
 
 // Render renders package documentation HTML for the
@@ -82,11 +110,25 @@ func Render(fset *token.FileSet, p *doc.Package, opt RenderOptions) (string, err
 		delete(p.Notes, k)
 	}
 
+	var related []*render.RelatedPackageIDs
+	for _, rp := range opt.RelatedPackages {
+		related = append(related, &render.RelatedPackageIDs{
+			ImportPath: rp.ImportPath,
+			Name:       rp.Name,
+			IDs:        rp.IDs,
+		})
+	}
 	r := render.New(fset, p, &render.Options{
 		PackageURL: func(path string) (url string) {
+			if opt.PackageURLFunc != nil {
+				if u := opt.PackageURLFunc(path); u != "" {
+					return u
+				}
+			}
 			return pathpkg.Join("/pkg", path)
 		},
 		DisableHotlinking: true,
+		RelatedPackageIDs: related,
 	})
 
 	fileLink := func(name string) template.HTML {
@@ -105,6 +147,12 @@ func Render(fset *token.FileSet, p *doc.Package, opt RenderOptions) (string, err
 			return ""
 		}
 	}
+	sinceVersionFunc := opt.SinceVersionFunc
+	if sinceVersionFunc == nil {
+		sinceVersionFunc = func(string) string {
+			return ""
+		}
+	}
 	buf := &limitBuffer{
 		B:      new(bytes.Buffer),
 		Remain: opt.Limit,
@@ -118,6 +166,7 @@ func Render(fset *token.FileSet, p *doc.Package, opt RenderOptions) (string, err
 		"file_link":             fileLink,
 		"source_link":           sourceLink,
 		"play_url":              playURLFunc,
+		"since_version":         sinceVersionFunc,
 	}).Execute(buf, struct {
 		RootURL string
 		*doc.Package
@@ -197,6 +246,118 @@ func WalkExamples(p *doc.Package, fn func(id string, ex *doc.Example)) {
 	}
 }
 
+// Symbols returns the exported constants, variables, functions, types and
+// methods of p, in the order they appear in the rendered documentation. Each
+// symbol's synopsis is the one shown in the documentation index. The exported
+// fields of struct types, and the methods and embedded types of interface
+// types, follow their type, with a synopsis of their own declaration.
+//
+// Symbols returns nil for commands, whose declarations are not rendered.
+func Symbols(fset *token.FileSet, p *doc.Package) []*internal.Symbol {
+	if p.Name == "main" {
+		return nil
+	}
+	r := render.New(fset, p, nil)
+	var syms []*internal.Symbol
+	addValues := func(vals []*doc.Value, kind internal.SymbolKind) {
+		for _, v := range vals {
+			for _, spec := range v.Decl.Specs {
+				// Summarize each spec on its own, so that the synopsis of a
+				// name in a group shows its own declaration.
+				syn := r.Synopsis(&ast.GenDecl{Tok: v.Decl.Tok, Specs: []ast.Spec{spec}})
+				for _, name := range spec.(*ast.ValueSpec).Names {
+					if !ast.IsExported(name.Name) {
+						continue
+					}
+					syms = append(syms, &internal.Symbol{Name: name.Name, Kind: kind, Synopsis: syn})
+				}
+			}
+		}
+	}
+	addFuncs := func(funcs []*doc.Func) {
+		for _, f := range funcs {
+			syms = append(syms, &internal.Symbol{Name: f.Name, Kind: internal.SymbolKindFunction, Synopsis: r.Synopsis(f.Decl)})
+		}
+	}
+	addValues(p.Consts, internal.SymbolKindConstant)
+	addValues(p.Vars, internal.SymbolKindVariable)
+	addFuncs(p.Funcs)
+	for _, t := range p.Types {
+		syms = append(syms, &internal.Symbol{Name: t.Name, Kind: internal.SymbolKindType, Synopsis: r.Synopsis(t.Decl)})
+		for _, spec := range t.Decl.Specs {
+			if ts := spec.(*ast.TypeSpec); ts.Name.Name == t.Name {
+				syms = append(syms, memberSymbols(r, ts)...)
+			}
+		}
+		addValues(t.Consts, internal.SymbolKindConstant)
+		addValues(t.Vars, internal.SymbolKindVariable)
+		addFuncs(t.Funcs)
+		for _, m := range t.Methods {
+			syms = append(syms, &internal.Symbol{
+				Name:       m.Name,
+				Kind:       internal.SymbolKindMethod,
+				ParentName: t.Name,
+				Synopsis:   r.Synopsis(m.Decl),
+			})
+		}
+	}
+	return syms
+}
+
+// memberSymbols returns the exported fields of the struct type declared by
+// spec, or the exported methods and embedded types of the interface type.
+func memberSymbols(r *render.Renderer, spec *ast.TypeSpec) []*internal.Symbol {
+	var (
+		fields *ast.FieldList
+		kind   = internal.SymbolKindField
+	)
+	switch t := spec.Type.(type) {
+	case *ast.StructType:
+		fields = t.Fields
+	case *ast.InterfaceType:
+		fields = t.Methods
+		kind = internal.SymbolKindMethod
+	default:
+		return nil
+	}
+	var syms []*internal.Symbol
+	for _, f := range fields.List {
+		if len(f.Names) == 0 {
+			// An embedded field, or a type embedded in an interface.
+			name := embeddedName(f.Type)
+			if ast.IsExported(name) {
+				syms = append(syms, &internal.Symbol{Name: name, Kind: internal.SymbolKindField, ParentName: spec.Name.Name, Synopsis: r.Synopsis(f.Type)})
+			}
+			continue
+		}
+		for _, n := range f.Names {
+			if !ast.IsExported(n.Name) {
+				continue
+			}
+			syn := n.Name + " " + r.Synopsis(f.Type)
+			if kind == internal.SymbolKindMethod {
+				syn = n.Name + strings.TrimPrefix(r.Synopsis(f.Type), "func")
+			}
+			syms = append(syms, &internal.Symbol{Name: n.Name, Kind: kind, ParentName: spec.Name.Name, Synopsis: syn})
+		}
+	}
+	return syms
+}
+
+// embeddedName returns the name of the embedded type x, like "Reader" for
+// *io.Reader.
+func embeddedName(x ast.Expr) string {
+	switch x := x.(type) {
+	case *ast.StarExpr:
+		return embeddedName(x.X)
+	case *ast.SelectorExpr:
+		return x.Sel.Name
+	case *ast.Ident:
+		return x.Name
+	}
+	return ""
+}
+
 // collectExamples extracts examples from p
 // into the internal examples representation.
 func collectExamples(p *doc.Package) *examples {
@@ -17,6 +17,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"golang.org/x/net/html"
+	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/fetch/internal/doc"
 )
 
@@ -44,6 +45,65 @@ func TestRender(t *testing.T) {
 	})
 }
 
+func TestRenderSinceVersion(t *testing.T) {
+	fset, d := mustLoadPackage("everydecl")
+
+	since := map[string]string{"F": "v1.2.0", "T.M": "v1.3.0"}
+	rawDoc, err := Render(fset, d, RenderOptions{
+		FileLinkFunc:     func(string) string { return "file" },
+		SourceLinkFunc:   func(ast.Node) string { return "src" },
+		SinceVersionFunc: func(id string) string { return since[id] },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<a href="#F">¶</a> <span class="Documentation-sinceVersion">Added in v1.2.0</span></h3>`,
+		`<a href="#T.M">¶</a> <span class="Documentation-sinceVersion">Added in v1.3.0</span></h3>`,
+		`<a href="#T">¶</a></h3>`,
+	} {
+		if !strings.Contains(rawDoc, want) {
+			t.Errorf("documentation does not contain %q", want)
+		}
+	}
+	if got, want := strings.Count(rawDoc, "Documentation-sinceVersion"), len(since); got != want {
+		t.Errorf("got %d versions, want %d", got, want)
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	fset, d := mustLoadPackage("everydecl")
+	want := []*internal.Symbol{
+		{Name: "C", Kind: internal.SymbolKindConstant, Synopsis: "const C = 1"},
+		{Name: "V", Kind: internal.SymbolKindVariable, Synopsis: "var V = 2"},
+		{Name: "F", Kind: internal.SymbolKindFunction, Synopsis: "func F()"},
+		{Name: "I1", Kind: internal.SymbolKindType, Synopsis: "type I1 interface{ ... }"},
+		{Name: "M1", Kind: internal.SymbolKindMethod, ParentName: "I1", Synopsis: "M1()"},
+		{Name: "I2", Kind: internal.SymbolKindType, Synopsis: "type I2 interface{ ... }"},
+		{Name: "I1", Kind: internal.SymbolKindField, ParentName: "I2", Synopsis: "I1"},
+		{Name: "M2", Kind: internal.SymbolKindMethod, ParentName: "I2", Synopsis: "M2()"},
+		{Name: "S1", Kind: internal.SymbolKindType, Synopsis: "type S1 struct{ ... }"},
+		{Name: "F", Kind: internal.SymbolKindField, ParentName: "S1", Synopsis: "F int"},
+		{Name: "S2", Kind: internal.SymbolKindType, Synopsis: "type S2 struct{ ... }"},
+		{Name: "S1", Kind: internal.SymbolKindField, ParentName: "S2", Synopsis: "S1"},
+		{Name: "G", Kind: internal.SymbolKindField, ParentName: "S2", Synopsis: "G int"},
+		{Name: "T", Kind: internal.SymbolKindType, Synopsis: "type T int"},
+		{Name: "CT", Kind: internal.SymbolKindConstant, Synopsis: "const CT T = 3"},
+		{Name: "VT", Kind: internal.SymbolKindVariable, Synopsis: "var VT T"},
+		{Name: "TF", Kind: internal.SymbolKindFunction, Synopsis: "func TF() T"},
+		{Name: "M", Kind: internal.SymbolKindMethod, ParentName: "T", Synopsis: "func (T) M()"},
+	}
+	got := Symbols(fset, d)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	d.Name = "main"
+	if got := Symbols(fset, d); got != nil {
+		t.Errorf("Symbols(main package) = %v, want nil", got)
+	}
+}
+
 func TestFileLinkHTML(t *testing.T) {
 	for _, test := range []struct {
 		name string
@@ -81,6 +81,11 @@ type packageIDs struct {
 	// E.g., pkgIDs["json"]["Encoder.Encode"] == true
 	pkgIDs map[string]map[string]bool // map[name]map[topLevelID]bool
 
+	// imported is the set of names of the packages added by addIDs.
+	//
+	// E.g., imported["context"] == true
+	imported map[string]bool
+
 	// topLevelDecls is the set of all AST declarations for the this package.
 	topLevelDecls map[interface{}]bool // map[T]bool where T is *ast.FuncDecl | *ast.GenDecl | *ast.TypeSpec | *ast.ValueSpec
 }
@@ -92,6 +97,7 @@ func newPackageIDs(pkg *doc.Package, related ...*doc.Package) *packageIDs {
 		name:          pkg.Name,
 		impPaths:      make(map[string]string),
 		pkgIDs:        make(map[string]map[string]bool),
+		imported:      make(map[string]bool),
 		topLevelDecls: make(map[interface{}]bool),
 	}
 
@@ -121,6 +127,21 @@ func newPackageIDs(pkg *doc.Package, related ...*doc.Package) *packageIDs {
 	return pids
 }
 
+// addIDs adds the top-level identifiers ids of the related package with the
+// given import path and name, unless a package with that name was already
+// added.
+func (pids *packageIDs) addIDs(importPath, name string, ids []string) {
+	if _, ok := pids.pkgIDs[name]; ok {
+		return // package name conflicts, ignore this package
+	}
+	pids.impPaths[name] = importPath
+	pids.pkgIDs[name] = make(map[string]bool)
+	pids.imported[name] = true
+	for _, id := range ids {
+		pids.pkgIDs[name][id] = true
+	}
+}
+
 // declIDs is a collection of identifiers that are related to the ast.Decl
 // currently being processed. Using Decl-level variables allows us to provide
 // greater accuracy in linking when comments refer to the variable names.
@@ -309,6 +330,18 @@ linkify:
 	return safehtml.HTMLConcat(outs...)
 }
 
+// toImportedHTML is like toHTML, but only links identifiers qualified by the
+// name of a package added by addIDs, like "context.Context". Other words are
+// escaped.
+func (r identifierResolver) toImportedHTML(word string) safehtml.HTML {
+	i := strings.IndexByte(word, '.')
+	if i < 0 || !r.imported[word[:i]] {
+		return safehtml.HTMLEscaped(word)
+	}
+	// Resolve the word by itself, not as a variable of the declaration.
+	return identifierResolver{r.packageIDs, newDeclIDs(nil), r.packageURL}.toHTML(word)
+}
+
 type link struct {
 	Href, Text string
 }
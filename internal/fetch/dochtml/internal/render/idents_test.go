@@ -108,6 +108,38 @@ func TestResolveIdentifier(t *testing.T) {
 	}
 }
 
+func TestResolveRelatedPackageIDs(t *testing.T) {
+	pids := newPackageIDs(pkgTar, pkgIO)
+	pids.addIDs("context", "context", []string{"Context", "Context.Done"})
+	// The name conflicts with pkgIO, so this package is ignored.
+	pids.addIDs("example.com/io", "io", []string{"Other"})
+	idr := &identifierResolver{pids, newDeclIDs(nil), func(path string) string { return "/pkg/" + path }}
+	const (
+		contextLink = `<a href="/pkg/context">context</a>.<a href="/pkg/context#Context">Context</a>`
+		doneLink    = contextLink + `.<a href="/pkg/context#Context.Done">Done</a>`
+	)
+	for _, test := range []struct {
+		in, want string
+		// wantImported is the result of toImportedHTML, which only links
+		// the identifiers added by addIDs.
+		wantImported string
+	}{
+		{`context.Context`, contextLink, contextLink},
+		{`context.Context.Done`, doneLink, doneLink},
+		{`context.Background`, `context.Background`, `context.Background`},
+		{`io.EOF`, `<a href="/pkg/io">io</a>.<a href="/pkg/io#EOF">EOF</a>`, `io.EOF`},
+		{`io.Other`, `io.Other`, `io.Other`},
+		{`Reader`, `<a href="#Reader">Reader</a>`, `Reader`},
+	} {
+		if got := idr.toHTML(test.in).String(); got != test.want {
+			t.Errorf("toHTML(%q):\ngot  `%s`\nwant `%s`", test.in, got, test.want)
+		}
+		if got := idr.toImportedHTML(test.in).String(); got != test.wantImported {
+			t.Errorf("toImportedHTML(%q):\ngot  `%s`\nwant `%s`", test.in, got, test.wantImported)
+		}
+	}
+}
+
 func findDecl(pkg *doc.Package, id string) ast.Decl {
 	for _, f := range pkg.Funcs {
 		if f.Name == id {
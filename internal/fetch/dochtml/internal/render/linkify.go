@@ -251,6 +251,11 @@ func (r *Renderer) formatLineHTML(w io.Writer, line string, idr *identifierResol
 				}
 			case !forbidLinking && !r.disableHotlinking && idr != nil: // && numQuotes%2 == 0:
 				io.WriteString(w, idr.toHTML(word).String())
+			case !forbidLinking && idr != nil:
+				// Identifiers of the packages described by
+				// Options.RelatedPackageIDs are linked even without
+				// hotlinking.
+				io.WriteString(w, idr.toImportedHTML(word).String())
 			default:
 				io.WriteString(w, template.HTMLEscapeString(word))
 			}
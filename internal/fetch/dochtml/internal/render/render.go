@@ -46,6 +46,14 @@ type Options struct {
 	// Only relevant for HTML formatting.
 	RelatedPackages []*doc.Package
 
+	// RelatedPackageIDs lists more related packages, described by their
+	// identifiers, for packages whose *doc.Package is not available.
+	// Their identifiers are linked in comments even if DisableHotlinking
+	// is set.
+	//
+	// Only relevant for HTML formatting.
+	RelatedPackageIDs []*RelatedPackageIDs
+
 	// PackageURL is a function that given a package path,
 	// returns a URL for navigating to the godoc for that package.
 	//
@@ -63,8 +71,17 @@ type Options struct {
 	DisablePermalinks bool
 }
 
+// RelatedPackageIDs describes a related package by the top-level identifiers
+// it declares, in the form used for anchors: "Reader" or "Reader.Read".
+type RelatedPackageIDs struct {
+	ImportPath string
+	Name       string
+	IDs        []string
+}
+
 func New(fset *token.FileSet, pkg *doc.Package, opts *Options) *Renderer {
 	var others []*doc.Package
+	var otherIDs []*RelatedPackageIDs
 	var packageURL func(string) string
 	var disableHotlinking bool
 	var disablePermalinks bool
@@ -72,6 +89,7 @@ func New(fset *token.FileSet, pkg *doc.Package, opts *Options) *Renderer {
 		if len(opts.RelatedPackages) > 0 {
 			others = opts.RelatedPackages
 		}
+		otherIDs = opts.RelatedPackageIDs
 		if opts.PackageURL != nil {
 			packageURL = opts.PackageURL
 		}
@@ -79,6 +97,9 @@ func New(fset *token.FileSet, pkg *doc.Package, opts *Options) *Renderer {
 		disablePermalinks = opts.DisablePermalinks
 	}
 	pids := newPackageIDs(pkg, others...)
+	for _, r := range otherIDs {
+		pids.addIDs(r.ImportPath, r.Name, r.IDs)
+	}
 	return &Renderer{
 		fset:              fset,
 		pids:              pids,
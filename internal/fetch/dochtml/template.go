@@ -33,6 +33,7 @@ var htmlPackage = template.Must(template.New("package").Funcs(
 		"file_link":             func() string { return "" },
 		"source_link":           func() string { return "" },
 		"play_url":              func(*doc.Example) string { return "" },
+		"since_version":         func(string) string { return "" },
 	},
 ).Parse(`{{- "" -}}
 {{- if or .Doc .Consts .Vars .Funcs .Types .Examples.List -}}
@@ -224,7 +225,7 @@ var htmlPackage = template.Must(template.New("package").Funcs(
 	<section class="Documentation-functions">
 		{{- range .Funcs -}}
 		<div class="Documentation-function">
-			<h3 id="{{.Name}}" data-kind="function" class="Documentation-functionHeader">func {{source_link .Name .Decl}} <a href="#{{.Name}}">¶</a></h3>{{"\n"}}
+			<h3 id="{{.Name}}" data-kind="function" class="Documentation-functionHeader">func {{source_link .Name .Decl}} <a href="#{{.Name}}">¶</a>{{with since_version .Name}} <span class="Documentation-sinceVersion">Added in {{.}}</span>{{end}}</h3>{{"\n"}}
 			{{- $out := render_decl .Doc .Decl -}}
 			{{- $out.Decl -}}
 			{{- $out.Doc -}}
@@ -240,7 +241,7 @@ var htmlPackage = template.Must(template.New("package").Funcs(
 		{{- range .Types -}}
 		<div class="Documentation-type">
 			{{- $tname := .Name -}}
-			<h3 id="{{.Name}}" data-kind="type" class="Documentation-typeHeader">type {{source_link .Name .Decl}} <a href="#{{.Name}}">¶</a></h3>{{"\n"}}
+			<h3 id="{{.Name}}" data-kind="type" class="Documentation-typeHeader">type {{source_link .Name .Decl}} <a href="#{{.Name}}">¶</a>{{with since_version .Name}} <span class="Documentation-sinceVersion">Added in {{.}}</span>{{end}}</h3>{{"\n"}}
 			{{- $out := render_decl .Doc .Decl -}}
 			{{- $out.Decl -}}
 			{{- $out.Doc -}}
@@ -267,7 +268,7 @@ var htmlPackage = template.Must(template.New("package").Funcs(
 
 			{{- range .Funcs -}}
 			<div class="Documentation-typeFunc">
-				<h3 id="{{.Name}}" data-kind="function" class="Documentation-typeFuncHeader">func {{source_link .Name .Decl}} <a href="#{{.Name}}">¶</a></h3>{{"\n"}}
+				<h3 id="{{.Name}}" data-kind="function" class="Documentation-typeFuncHeader">func {{source_link .Name .Decl}} <a href="#{{.Name}}">¶</a>{{with since_version .Name}} <span class="Documentation-sinceVersion">Added in {{.}}</span>{{end}}</h3>{{"\n"}}
 				{{- $out := render_decl .Doc .Decl -}}
 				{{- $out.Decl -}}
 				{{- $out.Doc -}}
@@ -279,7 +280,7 @@ var htmlPackage = template.Must(template.New("package").Funcs(
 			{{- range .Methods -}}
 			<div class="Documentation-typeMethod">
 				{{- $name := (printf "%s.%s" $tname .Name) -}}
-				<h3 id="{{$name}}" data-kind="method" class="Documentation-typeMethodHeader">func ({{.Recv}}) {{source_link .Name .Decl}} <a href="#{{$name}}">¶</a></h3>{{"\n"}}
+				<h3 id="{{$name}}" data-kind="method" class="Documentation-typeMethodHeader">func ({{.Recv}}) {{source_link .Name .Decl}} <a href="#{{$name}}">¶</a>{{with since_version $name}} <span class="Documentation-sinceVersion">Added in {{.}}</span>{{end}}</h3>{{"\n"}}
 				{{- $out := render_decl .Doc .Decl -}}
 				{{- $out.Decl -}}
 				{{- $out.Doc -}}
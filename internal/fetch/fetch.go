@@ -25,6 +25,7 @@ import (
 	"runtime/debug"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/safehtml"
@@ -34,6 +35,7 @@ import (
 	"golang.org/x/mod/modfile"
 	"golang.org/x/mod/module"
 	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/config"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/experiment"
 	"golang.org/x/pkgsite/internal/fetch/dochtml"
@@ -44,6 +46,7 @@ import (
 	"golang.org/x/pkgsite/internal/source"
 	"golang.org/x/pkgsite/internal/stdlib"
 	"golang.org/x/pkgsite/internal/version"
+	"golang.org/x/sync/semaphore"
 )
 
 var (
@@ -62,12 +65,30 @@ type FetchResult struct {
 	PackageVersionStates []*internal.PackageVersionState
 }
 
+// Options holds the settings used when processing the contents of a module.
+// The zero Options uses default settings.
+type Options struct {
+	// Settings limits the concurrency of loading the packages of a module.
+	Settings config.FetchSettings
+	// RenderCache, if not nil, stores the packages that are loaded, so that
+	// packages with the same source in other versions are not rendered
+	// again.
+	RenderCache RenderCache
+	// Symbols, if not nil, provides the identifiers of imported packages,
+	// so that references to them in documentation can be linked.
+	Symbols SymbolSource
+	// APIHistory, if not nil, provides the versions in which the
+	// identifiers of packages were added, so that they can be shown in
+	// documentation.
+	APIHistory APIHistorySource
+}
+
 // FetchModule queries the proxy or the Go repo for the requested module
 // version, downloads the module zip, and processes the contents to return an
 // *internal.Module and related information.
 //
 // Even if err is non-nil, the result may contain useful information, like the go.mod path.
-func FetchModule(ctx context.Context, modulePath, requestedVersion string, proxyClient *proxy.Client, sourceClient *source.Client) (fr *FetchResult) {
+func FetchModule(ctx context.Context, modulePath, requestedVersion string, proxyClient *proxy.Client, sourceClient *source.Client, opts Options) (fr *FetchResult) {
 	fr = &FetchResult{
 		ModulePath:       modulePath,
 		RequestedVersion: requestedVersion,
@@ -86,6 +107,7 @@ func FetchModule(ctx context.Context, modulePath, requestedVersion string, proxy
 	var (
 		commitTime time.Time
 		zipReader  *zip.Reader
+		goModBytes []byte
 		err        error
 	)
 	if modulePath == stdlib.ModulePath {
@@ -104,7 +126,7 @@ func FetchModule(ctx context.Context, modulePath, requestedVersion string, proxy
 		fr.ResolvedVersion = info.Version
 		commitTime = info.Time
 
-		goModBytes, err := proxyClient.GetMod(ctx, modulePath, fr.ResolvedVersion)
+		goModBytes, err = proxyClient.GetMod(ctx, modulePath, fr.ResolvedVersion)
 		if err != nil {
 			fr.Error = err
 			return fr
@@ -122,24 +144,47 @@ func FetchModule(ctx context.Context, modulePath, requestedVersion string, proxy
 			fr.Error = fmt.Errorf("module path=%s, go.mod path=%s: %w", modulePath, goModPath, derrors.AlternativeModule)
 			return fr
 		}
-		zipReader, err = proxyClient.GetZip(ctx, modulePath, fr.ResolvedVersion)
+		var cleanup func()
+		zipReader, cleanup, err = proxyClient.GetZip(ctx, modulePath, fr.ResolvedVersion)
 		if err != nil {
 			fr.Error = err
 			return fr
 		}
+		defer cleanup()
 	}
 	versionType, err := version.ParseType(fr.ResolvedVersion)
 	if err != nil {
 		fr.Error = fmt.Errorf("%v: %w", err, derrors.BadModule)
 		return fr
 	}
-	mod, pvs, err := processZipFile(ctx, modulePath, versionType, fr.ResolvedVersion, commitTime, zipReader, sourceClient)
+	var goMod *goModInfo
+	if goModBytes != nil {
+		goMod, err = parseGoMod(goModBytes)
+		if err != nil {
+			// The go.mod file was good enough to determine the module path,
+			// so don't reject the module; just omit the rest of the go.mod
+			// information.
+			log.Infof(ctx, "%s@%s: %v", modulePath, fr.ResolvedVersion, err)
+			goMod = nil
+		}
+	}
+	var deps []*internal.ModuleDependency
+	if goMod != nil {
+		deps = goMod.dependencies
+	}
+	mod, pvs, err := processZipFile(ctx, modulePath, versionType, fr.ResolvedVersion, commitTime, zipReader, sourceClient, deps, opts)
 	if err != nil {
 		fr.Error = err
 		return fr
 	}
 	fr.Module = mod
 	fr.PackageVersionStates = pvs
+	if goMod != nil {
+		fr.Module.Dependencies = goMod.dependencies
+		fr.Module.GoModRetractions = goMod.retractions
+		fr.Module.GoModDeprecation = goMod.deprecation
+		fr.Module.GoVersion = goMod.goVersion
+	}
 	if modulePath == stdlib.ModulePath {
 		fr.Module.HasGoMod = true
 	}
@@ -151,16 +196,22 @@ func FetchModule(ctx context.Context, modulePath, requestedVersion string, proxy
 	return fr
 }
 
-// processZipFile extracts information from the module version zip.
-func processZipFile(ctx context.Context, modulePath string, versionType version.Type, resolvedVersion string, commitTime time.Time, zipReader *zip.Reader, sourceClient *source.Client) (_ *internal.Module, _ []*internal.PackageVersionState, err error) {
+// processZipFile extracts information from the module version zip. deps are
+// the dependencies in the module's go.mod file, which determine the versions
+// that the documentation links to. If sourceClient is nil, no source
+// information is recorded for the module.
+func processZipFile(ctx context.Context, modulePath string, versionType version.Type, resolvedVersion string, commitTime time.Time, zipReader *zip.Reader, sourceClient *source.Client, deps []*internal.ModuleDependency, opts Options) (_ *internal.Module, _ []*internal.PackageVersionState, err error) {
 	defer derrors.Wrap(&err, "processZipFile(%q, %q)", modulePath, resolvedVersion)
 
 	ctx, span := trace.StartSpan(ctx, "fetch.processZipFile")
 	defer span.End()
 
-	sourceInfo, err := source.ModuleInfo(ctx, sourceClient, modulePath, resolvedVersion)
-	if err != nil {
-		log.Infof(ctx, "error getting source info: %v", err)
+	var sourceInfo *source.Info
+	if sourceClient != nil {
+		sourceInfo, err = source.ModuleInfo(ctx, sourceClient, modulePath, resolvedVersion)
+		if err != nil {
+			log.Infof(ctx, "error getting source info: %v", err)
+		}
 	}
 	readmes, err := extractReadmesFromZip(modulePath, resolvedVersion, zipReader)
 	if err != nil {
@@ -171,7 +222,7 @@ func processZipFile(ctx context.Context, modulePath string, versionType version.
 	}
 	d := licenses.NewDetector(modulePath, resolvedVersion, zipReader, logf)
 	allLicenses := d.AllLicenses()
-	packages, packageVersionStates, err := extractPackagesFromZip(ctx, modulePath, resolvedVersion, zipReader, d, sourceInfo)
+	packages, packageVersionStates, err := extractPackagesFromZip(ctx, modulePath, resolvedVersion, zipReader, d, sourceInfo, deps, opts)
 	if errors.Is(err, errModuleContainsNoPackages) || errors.Is(err, errMalformedZip) {
 		return nil, nil, fmt.Errorf("%v: %w", err.Error(), derrors.BadModule)
 	}
@@ -259,9 +310,9 @@ func isReadme(file string) bool {
 // that they contained .go files but couldn't be processed due to current
 // limitations of this site. The limitations are:
 // * a maximum file size (MaxFileSize)
-// * the particular set of build contexts we consider (goEnvs)
+// * the particular set of build contexts we consider (internal.BuildContexts)
 // * whether the import path is valid.
-func extractPackagesFromZip(ctx context.Context, modulePath, resolvedVersion string, r *zip.Reader, d *licenses.Detector, sourceInfo *source.Info) (_ []*internal.LegacyPackage, _ []*internal.PackageVersionState, err error) {
+func extractPackagesFromZip(ctx context.Context, modulePath, resolvedVersion string, r *zip.Reader, d *licenses.Detector, sourceInfo *source.Info, deps []*internal.ModuleDependency, opts Options) (_ []*internal.LegacyPackage, _ []*internal.PackageVersionState, err error) {
 	ctx, span := trace.StartSpan(ctx, "fetch.extractPackagesFromZip")
 	defer span.End()
 	defer func() {
@@ -373,20 +424,34 @@ func extractPackagesFromZip(ctx context.Context, modulePath, resolvedVersion str
 	// Phase 2.
 	// If we got this far, the file metadata was okay.
 	// Start reading the file contents now to extract information
-	// about Go packages.
-	var pkgs []*internal.LegacyPackage
-	for innerPath, goFiles := range dirs {
+	// about Go packages. The packages are loaded concurrently, but their
+	// results are processed in order of their paths, so that the
+	// PackageVersionStates are deterministic.
+	var innerPaths []string
+	for innerPath := range dirs {
 		if incompleteDirs[innerPath] {
 			// Something went wrong when processing this directory, so we skip.
 			log.Infof(ctx, "Skipping %q because it is incomplete", innerPath)
 			continue
 		}
+		innerPaths = append(innerPaths, innerPath)
+	}
+	sort.Strings(innerPaths)
+	links := newImportLinker(modulePath, resolvedVersion, deps, opts.Symbols)
+	results := loadPackages(ctx, dirs, innerPaths, modulePath, resolvedVersion, sourceInfo, links, opts)
 
+	var pkgs []*internal.LegacyPackage
+	for i, innerPath := range innerPaths {
 		var (
 			status error
 			errMsg string
 		)
-		pkg, err := loadPackage(ctx, goFiles, innerPath, modulePath, sourceInfo)
+		r := results[i]
+		if r.panicErr != nil {
+			return nil, nil, r.panicErr
+		}
+		pkg, err := r.pkg, r.err
+		goFiles := dirs[innerPath]
 		if bpe := (*BadPackageError)(nil); errors.As(err, &bpe) {
 			incompleteDirs[innerPath] = true
 			status = derrors.PackageInvalidContents
@@ -436,6 +501,76 @@ func extractPackagesFromZip(ctx context.Context, modulePath, resolvedVersion str
 	return pkgs, packageVersionStates, nil
 }
 
+// A loadResult is the result of loading the package in a directory.
+type loadResult struct {
+	pkg *internal.LegacyPackage
+	err error
+	// panicErr describes a panic while loading the package, if there was one.
+	panicErr error
+}
+
+// loadPackages loads the package in each of innerPaths, with the Go files in
+// dirs, using loadPackageCached, and returns the results in the same order.
+//
+// At most opts.Settings.PackageWorkers packages are loaded at a time, and a
+// package is loaded only when the total size of the Go files of the packages
+// being loaded, including its own, is within
+// opts.Settings.PackageMemoryBudgetMB. A package that is larger than the
+// budget waits until it can be loaded by itself.
+//
+// A panic while loading a package is recovered and returned in its
+// loadResult, since it would otherwise crash the process.
+func loadPackages(ctx context.Context, dirs map[string][]*zip.File, innerPaths []string, modulePath, resolvedVersion string, sourceInfo *source.Info, links *importLinker, opts Options) []loadResult {
+	workers, budget := opts.packageLimits()
+	var (
+		results = make([]loadResult, len(innerPaths))
+		sem     = semaphore.NewWeighted(budget)
+		indexes = make(chan int)
+		wg      sync.WaitGroup
+	)
+	load := func(i int) (r loadResult) {
+		defer func() {
+			if e := recover(); e != nil {
+				r = loadResult{panicErr: fmt.Errorf("internal panic: %v\n\n%s", e, debug.Stack())}
+			}
+		}()
+		goFiles := dirs[innerPaths[i]]
+		var size int64
+		for _, f := range goFiles {
+			size += int64(f.UncompressedSize64)
+		}
+		if size > budget {
+			size = budget
+		}
+		if err := sem.Acquire(ctx, size); err != nil {
+			return loadResult{err: err}
+		}
+		defer sem.Release(size)
+		importPath := path.Join(modulePath, innerPaths[i])
+		if modulePath == stdlib.ModulePath {
+			importPath = innerPaths[i]
+		}
+		history := loadPackageHistory(ctx, opts.APIHistory, importPath, modulePath, resolvedVersion)
+		pkg, err := loadPackageCached(ctx, opts.RenderCache, goFiles, innerPaths[i], modulePath, sourceInfo, links, history)
+		return loadResult{pkg: pkg, err: err}
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = load(i)
+			}
+		}()
+	}
+	for i := range innerPaths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
 // ignoredByGoTool reports whether the given import path corresponds
 // to a directory that would be ignored by the go tool.
 //
@@ -485,35 +620,98 @@ type BadPackageError struct {
 
 func (bpe *BadPackageError) Error() string { return bpe.Err.Error() }
 
-// Go environments used to construct build contexts in loadPackage.
-var goEnvs = []struct{ GOOS, GOARCH string }{
-	{"linux", "amd64"},
-	{"windows", "amd64"},
-	{"darwin", "amd64"},
-	{"js", "wasm"},
-	{"linux", "js"},
-}
-
-// loadPackage loads a Go package by calling loadPackageWithBuildContext, trying
-// several build contexts in turn. The first build context in the list to produce
-// a non-empty package is used. If none of them result in a package, then
-// loadPackage returns nil, nil.
+// loadPackage loads a Go package by calling loadPackageWithBuildContext for
+// each of internal.BuildContexts in turn. The first build context in the list
+// to produce a non-empty package determines the package. The documentation
+// for each later build context in which the package builds is added to the
+// package's OtherDocumentation, unless it is the same as that of an earlier
+// build context. If none of them result in a package, then loadPackage
+// returns nil, nil.
+//
+// The package's UsesCgo and GoReleaseTags are computed from the files that
+// match any of the build contexts.
 //
 // If the package is fine except that its documentation is too large, loadPackage
 // returns both a package and a non-nil error with dochtml.ErrTooLarge in its chain.
-func loadPackage(ctx context.Context, zipGoFiles []*zip.File, innerPath, modulePath string, sourceInfo *source.Info) (*internal.LegacyPackage, error) {
+func loadPackage(ctx context.Context, zipGoFiles []*zip.File, innerPath, modulePath string, sourceInfo *source.Info, links *importLinker, history *packageHistory) (*internal.LegacyPackage, error) {
 	ctx, span := trace.StartSpan(ctx, "fetch.loadPackage")
 	defer span.End()
-	for _, env := range goEnvs {
-		pkg, err := loadPackageWithBuildContext(ctx, env.GOOS, env.GOARCH, zipGoFiles, innerPath, modulePath, sourceInfo)
-		if err != nil && !errors.Is(err, dochtml.ErrTooLarge) {
-			return nil, err
+	var (
+		pkg    *internal.LegacyPackage
+		pkgErr error
+		// loaded holds the sets of files that have been loaded. Build
+		// contexts that match the same files produce the same package, so
+		// each set is loaded only once.
+		loaded = map[string]bool{}
+		// allFiles holds the files that match any of the build contexts.
+		allFiles = map[string][]byte{}
+	)
+	for _, bc := range internal.BuildContexts {
+		files, err := matchingFiles(bc.GOOS, bc.GOARCH, zipGoFiles)
+		if err != nil {
+			if pkg == nil {
+				return nil, err
+			}
+			continue
+		}
+		for name, b := range files {
+			allFiles[name] = b
+		}
+		key := fileSetKey(files)
+		if loaded[key] {
+			continue
+		}
+		loaded[key] = true
+		p, err := loadPackageWithBuildContext(ctx, bc.GOOS, bc.GOARCH, files, innerPath, modulePath, sourceInfo, links, history)
+		if pkg == nil {
+			if err != nil && !errors.Is(err, dochtml.ErrTooLarge) {
+				return nil, err
+			}
+			pkg, pkgErr = p, err
+			continue
 		}
-		if pkg != nil {
-			return pkg, err
+		// The package was loaded in an earlier build context, so a failure
+		// in this one only leaves out its documentation.
+		if p == nil || err != nil {
+			continue
+		}
+		if !hasDocumentation(pkg, p.DocumentationHTML) {
+			pkg.OtherDocumentation = append(pkg.OtherDocumentation, &internal.Documentation{
+				GOOS:     p.GOOS,
+				GOARCH:   p.GOARCH,
+				Synopsis: p.Synopsis,
+				HTML:     p.DocumentationHTML,
+			})
 		}
 	}
-	return nil, nil
+	if pkg != nil {
+		pkg.UsesCgo, pkg.GoReleaseTags = toolchainRequirements(allFiles)
+	}
+	return pkg, pkgErr
+}
+
+// fileSetKey returns a string that identifies the set of file names in files.
+func fileSetKey(files map[string][]byte) string {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "\x00")
+}
+
+// hasDocumentation reports whether pkg already has documentation with the
+// given HTML.
+func hasDocumentation(pkg *internal.LegacyPackage, html safehtml.HTML) bool {
+	if pkg.DocumentationHTML.String() == html.String() {
+		return true
+	}
+	for _, d := range pkg.OtherDocumentation {
+		if d.HTML.String() == html.String() {
+			return true
+		}
+	}
+	return false
 }
 
 // httpPost allows package fetch tests to stub out playground URL fetches.
@@ -521,13 +719,14 @@ var httpPost = http.Post
 
 const docTooLargeReplacement = `<p>Documentation is too large to display.</p>`
 
-// loadPackageWithBuildContext loads a Go package made of .go files in zipGoFiles
-// using a build context constructed from the given GOOS and GOARCH values.
-// modulePath is stdlib.ModulePath for the Go standard library and the module
-// path for all other modules. innerPath is the path of the Go package directory
-// relative to the module root.
+// loadPackageWithBuildContext loads a Go package made of the .go files in
+// files, which maps file names to contents and holds the files that match the
+// build context constructed from the given GOOS and GOARCH values (see
+// matchingFiles). modulePath is stdlib.ModulePath for the Go standard library
+// and the module path for all other modules. innerPath is the path of the Go
+// package directory relative to the module root.
 //
-// zipGoFiles must contain only .go files that have been verified
+// files must contain only .go files that have been verified
 // to be of reasonable size.
 //
 // The returned LegacyPackage.Licenses field is not populated.
@@ -536,14 +735,9 @@ const docTooLargeReplacement = `<p>Documentation is too large to display.</p>`
 // or all .go files have been excluded by constraints.
 // A *BadPackageError error is returned if the directory
 // contains .go files but do not make up a valid package.
-func loadPackageWithBuildContext(ctx context.Context, goos, goarch string, zipGoFiles []*zip.File, innerPath, modulePath string, sourceInfo *source.Info) (_ *internal.LegacyPackage, err error) {
-	defer derrors.Wrap(&err, "loadPackageWithBuildContext(%q, %q, zipGoFiles, %q, %q, %+v)",
+func loadPackageWithBuildContext(ctx context.Context, goos, goarch string, files map[string][]byte, innerPath, modulePath string, sourceInfo *source.Info, links *importLinker, history *packageHistory) (_ *internal.LegacyPackage, err error) {
+	defer derrors.Wrap(&err, "loadPackageWithBuildContext(%q, %q, files, %q, %q, %+v)",
 		goos, goarch, innerPath, modulePath, sourceInfo)
-	// Apply build constraints to get a map from matching file names to their contents.
-	files, err := matchingFiles(goos, goarch, zipGoFiles)
-	if err != nil {
-		return nil, err
-	}
 
 	// Parse .go files and add them to the goFiles slice.
 	var (
@@ -553,8 +747,15 @@ func loadPackageWithBuildContext(ctx context.Context, goos, goarch string, zipGo
 		packageName     string
 		packageNameFile string // Name of file where packageName came from.
 	)
-	for name, b := range files {
-		pf, err := parser.ParseFile(fset, name, b, parser.ParseComments)
+	// Parse the files in order of their names, so that the results,
+	// including errors, do not depend on map iteration order.
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pf, err := parser.ParseFile(fset, name, files[name], parser.ParseComments)
 		if err != nil {
 			if pf == nil {
 				return nil, fmt.Errorf("internal error: the source couldn't be read: %v", err)
@@ -665,10 +866,13 @@ func loadPackageWithBuildContext(ctx context.Context, goos, goarch string, zipGo
 	}
 
 	docHTML, err := dochtml.Render(fset, d, dochtml.RenderOptions{
-		FileLinkFunc:   fileLinkFunc,
-		SourceLinkFunc: sourceLinkFunc,
-		PlayURLFunc:    playURLFunc,
-		Limit:          int64(MaxDocumentationHTML),
+		FileLinkFunc:     fileLinkFunc,
+		SourceLinkFunc:   sourceLinkFunc,
+		PlayURLFunc:      playURLFunc,
+		Limit:            int64(MaxDocumentationHTML),
+		PackageURLFunc:   links.packageURL,
+		RelatedPackages:  links.relatedPackages(ctx, fileImports(allGoFiles)),
+		SinceVersionFunc: history.sinceVersion,
 	})
 	var safeDocHTML safehtml.HTML
 	if errors.Is(err, dochtml.ErrTooLarge) {
@@ -691,6 +895,7 @@ func loadPackageWithBuildContext(ctx context.Context, goos, goarch string, zipGo
 		DocumentationHTML: safeDocHTML,
 		GOOS:              goos,
 		GOARCH:            goarch,
+		Symbols:           dochtml.Symbols(fset, d),
 	}, err
 }
 
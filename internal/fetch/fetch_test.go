@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
@@ -21,7 +22,9 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/google/safehtml"
 	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/config"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/experiment"
 	"golang.org/x/pkgsite/internal/fetch/internal/doc"
@@ -67,6 +70,9 @@ func TestFetchModule(t *testing.T) {
 		{name: "has go.mod", mod: moduleMultiPackage},
 		{name: "module with bad packages", mod: moduleBadPackages},
 		{name: "module with build constraints", mod: moduleBuildConstraints},
+		{name: "module with platform-specific documentation", mod: modulePlatformSpecific},
+		{name: "module with dependencies", mod: moduleDependencies},
+		{name: "module with toolchain requirements", mod: moduleToolchain},
 		{name: "module with packages with bad import paths", mod: moduleBadImportPath},
 		{name: "module with documentation", mod: moduleDocTest},
 		{name: "documentation too large", mod: moduleDocTooLarge},
@@ -90,7 +96,7 @@ func TestFetchModule(t *testing.T) {
 				Files:      test.mod.mod.Files,
 			}})
 			defer teardownProxy()
-			got := FetchModule(ctx, modulePath, version, proxyClient, sourceClient)
+			got := FetchModule(ctx, modulePath, version, proxyClient, sourceClient, Options{})
 			if got.Error != nil {
 				t.Fatal(got.Error)
 			}
@@ -99,7 +105,7 @@ func TestFetchModule(t *testing.T) {
 			sortFetchResult(fr)
 			sortFetchResult(got)
 			opts := []cmp.Option{
-				cmpopts.IgnoreFields(internal.LegacyPackage{}, "DocumentationHTML"),
+				cmpopts.IgnoreFields(internal.LegacyPackage{}, "DocumentationHTML", "Symbols"),
 				cmpopts.IgnoreFields(internal.Documentation{}, "HTML"),
 				cmpopts.IgnoreFields(internal.PackageVersionState{}, "Error"),
 				cmp.AllowUnexported(source.Info{}),
@@ -138,7 +144,7 @@ func TestFetchModule_Errors(t *testing.T) {
 			defer teardownProxy()
 
 			sourceClient := source.NewClient(sourceTimeout)
-			got := FetchModule(ctx, modulePath, "v1.0.0", proxyClient, sourceClient)
+			got := FetchModule(ctx, modulePath, "v1.0.0", proxyClient, sourceClient, Options{})
 			if !errors.Is(got.Error, test.wantErr) {
 				t.Fatalf("FetchModule(ctx, %q, v1.0.0, proxyClient, sourceClient): %v; wantErr = %v)", modulePath, got.Error, test.wantErr)
 			}
@@ -151,6 +157,58 @@ func TestFetchModule_Errors(t *testing.T) {
 	}
 }
 
+func TestExtractPackagesFromZipConcurrently(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	const modulePath = "example.com/many"
+	files := map[string]string{"LICENSE": testhelper.BSD0License}
+	for i := 0; i < 30; i++ {
+		dir := fmt.Sprintf("p%02d", i)
+		files[dir+"/p.go"] = fmt.Sprintf("// Package %[1]s is package number %[2]d.\npackage %[1]s\n\nconst N = %[2]d", dir, i)
+	}
+	// A package that is invalid, so its state is not 200.
+	files["bad/a.go"] = "package a"
+	files["bad/b.go"] = "package b"
+	proxyClient, teardownProxy := proxy.SetupTestProxy(t, []*proxy.TestModule{{
+		ModulePath: modulePath,
+		Files:      files,
+	}})
+	defer teardownProxy()
+	r, cleanup, err := proxyClient.GetZip(ctx, modulePath, "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	extract := func(settings config.FetchSettings) ([]*internal.LegacyPackage, []*internal.PackageVersionState) {
+		t.Helper()
+		pkgs, states, err := extractPackagesFromZip(ctx, modulePath, "v1.0.0", r, nil, nil, nil, Options{Settings: settings})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pkgs, states
+	}
+	wantPkgs, wantStates := extract(config.FetchSettings{PackageWorkers: 1, PackageMemoryBudgetMB: 1})
+	if len(wantPkgs) != 30 || len(wantStates) != 31 {
+		t.Fatalf("got %d packages and %d states, want 30 and 31", len(wantPkgs), len(wantStates))
+	}
+	if !sort.SliceIsSorted(wantStates, func(i, j int) bool {
+		return wantStates[i].PackagePath < wantStates[j].PackagePath
+	}) {
+		t.Error("package version states are not sorted by path")
+	}
+	for i := 0; i < 3; i++ {
+		gotPkgs, gotStates := extract(config.FetchSettings{PackageWorkers: 8, PackageMemoryBudgetMB: 1})
+		if diff := cmp.Diff(wantPkgs, gotPkgs, cmp.AllowUnexported(safehtml.HTML{})); diff != "" {
+			t.Errorf("packages mismatch (-sequential +concurrent):\n%s", diff)
+		}
+		if diff := cmp.Diff(wantStates, gotStates); diff != "" {
+			t.Errorf("states mismatch (-sequential +concurrent):\n%s", diff)
+		}
+	}
+}
+
 func TestExtractReadmesFromZip(t *testing.T) {
 	stdlib.UseTestData = true
 
@@ -220,10 +278,12 @@ func TestExtractReadmesFromZip(t *testing.T) {
 				proxyClient, teardownProxy := proxy.SetupTestProxy(t, []*proxy.TestModule{
 					{ModulePath: test.modulePath, Files: test.files}})
 				defer teardownProxy()
-				reader, err = proxyClient.GetZip(ctx, test.modulePath, "v1.0.0")
+				var cleanup func()
+				reader, cleanup, err = proxyClient.GetZip(ctx, test.modulePath, "v1.0.0")
 				if err != nil {
 					t.Fatal(err)
 				}
+				defer cleanup()
 			}
 
 			got, err := extractReadmesFromZip(test.modulePath, test.version, reader)
@@ -340,6 +340,14 @@ var moduleBuildConstraints = &testModule{
 							Synopsis: "Package cpu implements processor feature detection used by the Go standard library.",
 							HTML:     html("const CacheLinePadSize = 3"),
 						},
+						// The cpu_x86.go file is excluded for js/wasm.
+						OtherDocumentation: []*internal.Documentation{
+							{
+								GOOS:     "js",
+								GOARCH:   "wasm",
+								Synopsis: "Package cpu implements processor feature detection used by the Go standard library.",
+							},
+						},
 					},
 				},
 			},
@@ -361,6 +369,201 @@ var moduleBuildConstraints = &testModule{
 	},
 }
 
+var modulePlatformSpecific = &testModule{
+	mod: &proxy.TestModule{
+		ModulePath: "platform.specific/module",
+		Files: map[string]string{
+			"LICENSE": testhelper.BSD0License,
+			"sys/sys.go": `
+					// Package sys provides system calls.
+					package sys
+
+					// Getpid returns the process id.
+					func Getpid() int { return 0 }`,
+			"sys/sys_windows.go": `
+					package sys
+
+					// GetConsoleMode returns the console mode.
+					func GetConsoleMode() uint32 { return 0 }`,
+		},
+	},
+	fr: &FetchResult{
+		Module: &internal.Module{
+			LegacyModuleInfo: internal.LegacyModuleInfo{
+				ModuleInfo: internal.ModuleInfo{
+					ModulePath: "platform.specific/module",
+					HasGoMod:   false,
+				},
+			},
+			Directories: []*internal.DirectoryNew{
+				{
+					DirectoryMeta: internal.DirectoryMeta{
+						Path:   "platform.specific/module",
+						V1Path: "platform.specific/module",
+					},
+				},
+				{
+					DirectoryMeta: internal.DirectoryMeta{
+						Path:   "platform.specific/module/sys",
+						V1Path: "platform.specific/module/sys",
+					},
+					Package: &internal.PackageNew{
+						Name: "sys",
+						Documentation: &internal.Documentation{
+							Synopsis: "Package sys provides system calls.",
+							HTML:     html("Getpid"),
+						},
+						// The other build contexts match the same files as
+						// linux/amd64, so their documentation is left out.
+						OtherDocumentation: []*internal.Documentation{
+							{
+								GOOS:     "windows",
+								GOARCH:   "amd64",
+								Synopsis: "Package sys provides system calls.",
+								HTML:     html("GetConsoleMode"),
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+var moduleDependencies = &testModule{
+	mod: &proxy.TestModule{
+		ModulePath: "example.com/deps",
+		Files: map[string]string{
+			"go.mod": `// Deprecated: use example.com/deps/v2.
+			module example.com/deps
+
+			go 1.14
+
+			retract v0.9.0 // Published too early.
+
+			require (
+				github.com/a/b v1.2.3
+				github.com/c/d v0.1.0 // indirect
+			)
+
+			replace github.com/a/b => ../b
+
+			replace github.com/c/d v0.1.0 => github.com/e/f v0.2.0
+
+			exclude github.com/a/b v1.2.2`,
+			"LICENSE": testhelper.BSD0License,
+			"deps.go": `
+			// Package deps has dependencies.
+			package deps`,
+		},
+	},
+	fr: &FetchResult{
+		Module: &internal.Module{
+			LegacyModuleInfo: internal.LegacyModuleInfo{
+				ModuleInfo: internal.ModuleInfo{
+					ModulePath: "example.com/deps",
+					HasGoMod:   true,
+					GoVersion:  "1.14",
+				},
+			},
+			Directories: []*internal.DirectoryNew{
+				{
+					DirectoryMeta: internal.DirectoryMeta{
+						Path:   "example.com/deps",
+						V1Path: "example.com/deps",
+					},
+					Package: &internal.PackageNew{
+						Name: "deps",
+						Documentation: &internal.Documentation{
+							Synopsis: "Package deps has dependencies.",
+						},
+					},
+				},
+			},
+			Dependencies: []*internal.ModuleDependency{
+				{Kind: internal.DependencyRequire, ModulePath: "github.com/a/b", Version: "v1.2.3"},
+				{Kind: internal.DependencyRequire, ModulePath: "github.com/c/d", Version: "v0.1.0", Indirect: true},
+				{Kind: internal.DependencyReplace, ModulePath: "github.com/a/b", ReplacementPath: "../b"},
+				{Kind: internal.DependencyReplace, ModulePath: "github.com/c/d", Version: "v0.1.0",
+					ReplacementPath: "github.com/e/f", ReplacementVersion: "v0.2.0"},
+				{Kind: internal.DependencyExclude, ModulePath: "github.com/a/b", Version: "v1.2.2"},
+			},
+			GoModRetractions: []*internal.Retraction{
+				{Low: "v0.9.0", High: "v0.9.0", Rationale: "Published too early."},
+			},
+			GoModDeprecation: "use example.com/deps/v2.",
+		},
+	},
+}
+
+var moduleToolchain = &testModule{
+	mod: &proxy.TestModule{
+		ModulePath: "example.com/toolchain",
+		Files: map[string]string{
+			"go.mod":  "module example.com/toolchain\n\ngo 1.15",
+			"LICENSE": testhelper.BSD0License,
+			"cgo/cgo.go": `
+			// Package cgo uses cgo.
+			package cgo
+
+			import "C"`,
+			"tags/new.go": `// +build go1.14,linux go1.13
+
+			// Package tags has files for newer Go releases.
+			package tags`,
+			"tags/newer.go":    "//go:build go1.16 && !windows\n\npackage tags",
+			"tags/old.go":      "// +build !go1.13\n\npackage tags",
+			"tags/new_test.go": "// +build go1.15\n\npackage tags",
+		},
+	},
+	fr: &FetchResult{
+		Module: &internal.Module{
+			LegacyModuleInfo: internal.LegacyModuleInfo{
+				ModuleInfo: internal.ModuleInfo{
+					ModulePath: "example.com/toolchain",
+					HasGoMod:   true,
+					GoVersion:  "1.15",
+				},
+			},
+			Directories: []*internal.DirectoryNew{
+				{
+					DirectoryMeta: internal.DirectoryMeta{
+						Path:   "example.com/toolchain",
+						V1Path: "example.com/toolchain",
+					},
+				},
+				{
+					DirectoryMeta: internal.DirectoryMeta{
+						Path:   "example.com/toolchain/cgo",
+						V1Path: "example.com/toolchain/cgo",
+					},
+					Package: &internal.PackageNew{
+						Name:    "cgo",
+						Imports: []string{"C"},
+						Documentation: &internal.Documentation{
+							Synopsis: "Package cgo uses cgo.",
+						},
+						UsesCgo: true,
+					},
+				},
+				{
+					DirectoryMeta: internal.DirectoryMeta{
+						Path:   "example.com/toolchain/tags",
+						V1Path: "example.com/toolchain/tags",
+					},
+					Package: &internal.PackageNew{
+						Name: "tags",
+						Documentation: &internal.Documentation{
+							Synopsis: "Package tags has files for newer Go releases.",
+						},
+						GoReleaseTags: []string{"go1.13", "go1.14", "go1.16"},
+					},
+				},
+			},
+		},
+	},
+}
+
 var moduleNonRedist = &testModule{
 	mod: &proxy.TestModule{
 		ModulePath: "nonredistributable.mod/module",
@@ -411,6 +614,7 @@ var moduleNonRedist = &testModule{
 				ModuleInfo: internal.ModuleInfo{
 					ModulePath: "nonredistributable.mod/module",
 					HasGoMod:   true,
+					GoVersion:  "1.13",
 				},
 				LegacyReadmeFilePath: "README.md",
 				LegacyReadmeContents: "README FILE FOR TESTING.",
@@ -0,0 +1,262 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/mod/modfile"
+	modzip "golang.org/x/mod/zip"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/version"
+)
+
+// LocalVersion is the version assigned to modules that are read from a
+// directory on the local filesystem.
+const LocalVersion = "v0.0.0"
+
+// FetchLocalModule reads the module in the directory localPath and processes
+// its contents as FetchModule does for a module zip from the proxy. If
+// localPath has a go.mod file, its module path must match modulePath, unless
+// modulePath is empty. If there is no go.mod file, modulePath must be
+// provided; this is how packages in a GOPATH are loaded.
+//
+// The module is given the version LocalVersion, and has no source
+// information.
+func FetchLocalModule(ctx context.Context, modulePath, localPath string) (fr *FetchResult) {
+	fr = &FetchResult{
+		ModulePath:       modulePath,
+		RequestedVersion: LocalVersion,
+		ResolvedVersion:  LocalVersion,
+	}
+	defer func() {
+		if fr.Error != nil {
+			derrors.Wrap(&fr.Error, "FetchLocalModule(%q, %q)", modulePath, localPath)
+			fr.Status = derrors.ToHTTPStatus(fr.Error)
+		}
+		if fr.Status == 0 {
+			fr.Status = http.StatusOK
+		}
+	}()
+
+	goModBytes, err := ioutil.ReadFile(filepath.Join(localPath, "go.mod"))
+	switch {
+	case err == nil:
+		if modulePath == "" {
+			fr.ModulePath = modfile.ModulePath(goModBytes)
+			if fr.ModulePath == "" {
+				fr.Error = fmt.Errorf("go.mod has no module path: %w", derrors.BadModule)
+				return fr
+			}
+		}
+	case os.IsNotExist(err):
+		if modulePath == "" {
+			fr.Error = fmt.Errorf("no go.mod file and no module path: %w", derrors.BadModule)
+			return fr
+		}
+	default:
+		fr.Error = err
+		return fr
+	}
+	zipReader, err := zipLocalModule(fr.ModulePath, localPath)
+	if err != nil {
+		fr.Error = err
+		return fr
+	}
+	fetchFromZip(ctx, fr, zipReader)
+	return fr
+}
+
+// FetchZip processes the module zip in zipReader, which must hold the given
+// version of the module with modulePath, as FetchModule does for a module zip
+// from the proxy. It is used to preview modules that have not been published.
+// The module has no source information.
+func FetchZip(ctx context.Context, modulePath, version string, zipReader *zip.Reader) (fr *FetchResult) {
+	fr = &FetchResult{
+		ModulePath:       modulePath,
+		RequestedVersion: version,
+		ResolvedVersion:  version,
+	}
+	defer func() {
+		if fr.Error != nil {
+			derrors.Wrap(&fr.Error, "FetchZip(%q, %q)", modulePath, version)
+			fr.Status = derrors.ToHTTPStatus(fr.Error)
+		}
+		if fr.Status == 0 {
+			fr.Status = http.StatusOK
+		}
+	}()
+	fetchFromZip(ctx, fr, zipReader)
+	return fr
+}
+
+// fetchFromZip processes a module zip that did not come from the proxy,
+// for fr.ModulePath at fr.ResolvedVersion, and fills in the rest of fr. The
+// module's go.mod file, if any, is read from the zip. The packages are loaded
+// with the default Options.
+func fetchFromZip(ctx context.Context, fr *FetchResult, zipReader *zip.Reader) {
+	versionType, err := version.ParseType(fr.ResolvedVersion)
+	if err != nil {
+		fr.Error = fmt.Errorf("%v: %w", err, derrors.BadModule)
+		return
+	}
+	var goMod *goModInfo
+	goModName := path.Join(moduleVersionDir(fr.ModulePath, fr.ResolvedVersion), "go.mod")
+	for _, f := range zipReader.File {
+		if f.Name != goModName {
+			continue
+		}
+		if f.UncompressedSize64 > MaxFileSize {
+			fr.Error = fmt.Errorf("go.mod file size %d exceeds max limit %d: %w", f.UncompressedSize64, MaxFileSize, derrors.BadModule)
+			return
+		}
+		goModBytes, err := readZipFile(f)
+		if err != nil {
+			fr.Error = err
+			return
+		}
+		goModPath := modfile.ModulePath(goModBytes)
+		if goModPath == "" {
+			fr.Error = fmt.Errorf("go.mod has no module path: %w", derrors.BadModule)
+			return
+		}
+		fr.GoModPath = goModPath
+		if goModPath != fr.ModulePath {
+			fr.Error = fmt.Errorf("module path=%s, go.mod path=%s: %w", fr.ModulePath, goModPath, derrors.AlternativeModule)
+			return
+		}
+		goMod, err = parseGoMod(goModBytes)
+		if err != nil {
+			log.Infof(ctx, "%s@%s: %v", fr.ModulePath, fr.ResolvedVersion, err)
+			goMod = nil
+		}
+		break
+	}
+	var deps []*internal.ModuleDependency
+	if goMod != nil {
+		deps = goMod.dependencies
+	}
+	mod, pvs, err := processZipFile(ctx, fr.ModulePath, versionType, fr.ResolvedVersion, time.Now(), zipReader, nil, deps, Options{})
+	if err != nil {
+		fr.Error = err
+		return
+	}
+	fr.Module = mod
+	fr.PackageVersionStates = pvs
+	if goMod != nil {
+		fr.Module.Dependencies = goMod.dependencies
+		fr.Module.GoModRetractions = goMod.retractions
+		fr.Module.GoModDeprecation = goMod.deprecation
+		fr.Module.GoVersion = goMod.goVersion
+	}
+	for _, state := range fr.PackageVersionStates {
+		if state.Status != http.StatusOK {
+			fr.Status = derrors.ToHTTPStatus(derrors.HasIncompletePackages)
+		}
+	}
+}
+
+// maxLocalModuleSize is the maximum total size of the files of a module read
+// from a local directory. It is the limit that the go command places on
+// module zips.
+//
+// It is a variable for testing.
+var maxLocalModuleSize int64 = modzip.MaxZipFile
+
+// zipLocalModule returns an in-memory zip of the module in localPath, with the
+// same layout as a module zip from the proxy. Like the go command, it omits
+// version control directories, nested modules and irregular files such as
+// symbolic links. Files larger than MaxFileSize, or more than
+// maxLocalModuleSize bytes of files in total, make the module a bad module.
+func zipLocalModule(modulePath, localPath string) (_ *zip.Reader, err error) {
+	defer derrors.Wrap(&err, "zipLocalModule(%q, %q)", modulePath, localPath)
+
+	var (
+		buf   bytes.Buffer
+		total int64
+	)
+	w := zip.NewWriter(&buf)
+	prefix := moduleVersionDir(modulePath, LocalVersion)
+	err = filepath.Walk(localPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if filePath == localPath {
+				return nil
+			}
+			if SkipLocalDir(filePath, info) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(localPath, filePath)
+		if err != nil {
+			return err
+		}
+		if info.Size() > MaxFileSize {
+			return fmt.Errorf("%s: file size %d exceeds max limit %d: %w", rel, info.Size(), MaxFileSize, derrors.BadModule)
+		}
+		fw, err := w.Create(path.Join(prefix, filepath.ToSlash(rel)))
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		// The file may have grown since it was listed, so the limits are
+		// enforced on the bytes that are copied.
+		n, err := io.Copy(fw, io.LimitReader(f, MaxFileSize+1))
+		if err != nil {
+			return err
+		}
+		if n > MaxFileSize {
+			return fmt.Errorf("%s: file size exceeds max limit %d: %w", rel, MaxFileSize, derrors.BadModule)
+		}
+		total += n
+		if total > maxLocalModuleSize {
+			return fmt.Errorf("module files exceed %d bytes: %w", maxLocalModuleSize, derrors.BadModule)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+}
+
+// SkipLocalDir reports whether the subdirectory dir of a local module, with
+// the given FileInfo, is not part of the module: either it is a version
+// control directory, or it contains a nested module.
+func SkipLocalDir(dir string, info os.FileInfo) bool {
+	switch info.Name() {
+	case ".bzr", ".git", ".hg", ".svn":
+		return true
+	}
+	if fi, err := os.Lstat(filepath.Join(dir, "go.mod")); err == nil && fi.Mode().IsRegular() {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+func TestFetchLocalModuleSizeLimits(t *testing.T) {
+	defer func(m int64) { maxLocalModuleSize = m }(maxLocalModuleSize)
+	maxLocalModuleSize = 100
+
+	for _, test := range []struct {
+		name string
+		// size is the size of big.txt.
+		size int64
+	}{
+		{"max file size", MaxFileSize + 1},
+		{"max module size", 101},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "fetchlocal")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module foo.com/bar\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			// Truncate makes a sparse file, which takes up no space on disk.
+			big := filepath.Join(dir, "big.txt")
+			if err := ioutil.WriteFile(big, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Truncate(big, test.size); err != nil {
+				t.Fatal(err)
+			}
+			got := FetchLocalModule(context.Background(), "", dir)
+			if !errors.Is(got.Error, derrors.BadModule) {
+				t.Errorf("got error %v, want %v", got.Error, derrors.BadModule)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// goModInfo is the information from a go.mod file that is stored with a
+// module version.
+type goModInfo struct {
+	dependencies []*internal.ModuleDependency
+	retractions  []*internal.Retraction
+	deprecation  string
+	goVersion    string
+}
+
+// parseGoMod parses the go.mod file with contents goMod.
+func parseGoMod(goMod []byte) (_ *goModInfo, err error) {
+	defer derrors.Wrap(&err, "parseGoMod")
+
+	// Parse rejects retract directives with invalid versions. ParseLax
+	// accepts them, but it ignores replace and exclude directives, so it is
+	// only used if Parse fails.
+	f, err := modfile.Parse("go.mod", goMod, nil)
+	if err != nil {
+		f, err = modfile.ParseLax("go.mod", goMod, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	info := &goModInfo{
+		dependencies: dependencies(f),
+		retractions:  retractions(f),
+	}
+	if f.Module != nil {
+		info.deprecation = f.Module.Deprecated
+	}
+	if f.Go != nil {
+		info.goVersion = goVersion(f.Go.Version)
+	}
+	return info, nil
+}
+
+// GoVersionRE matches the go versions that are stored with a module version:
+// the version of its go directive, which since Go 1.21 may have a patch
+// version, without any pre-release suffix.
+var GoVersionRE = regexp.MustCompile(`^[1-9][0-9]*\.(?:0|[1-9][0-9]*)(?:\.(?:0|[1-9][0-9]*))?$`)
+
+// goVersion returns the version of a go directive without its pre-release
+// suffix, like 1.21 for 1.21rc1, because go versions are compared
+// numerically in search. It returns the empty string if the result does not
+// match GoVersionRE.
+func goVersion(v string) string {
+	if i := strings.IndexFunc(v, unicode.IsLetter); i >= 0 {
+		v = v[:i]
+	}
+	if !GoVersionRE.MatchString(v) {
+		return ""
+	}
+	return v
+}
+
+// dependencies returns the require, replace and exclude directives of f.
+// Directives of each kind are returned in the order they appear in the file.
+func dependencies(f *modfile.File) []*internal.ModuleDependency {
+	var deps []*internal.ModuleDependency
+	for _, r := range f.Require {
+		deps = append(deps, &internal.ModuleDependency{
+			Kind:       internal.DependencyRequire,
+			ModulePath: r.Mod.Path,
+			Version:    r.Mod.Version,
+			Indirect:   r.Indirect,
+		})
+	}
+	for _, r := range f.Replace {
+		deps = append(deps, &internal.ModuleDependency{
+			Kind:               internal.DependencyReplace,
+			ModulePath:         r.Old.Path,
+			Version:            r.Old.Version,
+			ReplacementPath:    r.New.Path,
+			ReplacementVersion: r.New.Version,
+		})
+	}
+	for _, e := range f.Exclude {
+		deps = append(deps, &internal.ModuleDependency{
+			Kind:       internal.DependencyExclude,
+			ModulePath: e.Mod.Path,
+			Version:    e.Mod.Version,
+		})
+	}
+	return deps
+}
+
+// retractions returns the retract directives of f. ParseLax does not
+// validate retracted versions, so invalid versions and intervals whose low
+// version is greater than their high version are skipped.
+func retractions(f *modfile.File) []*internal.Retraction {
+	var rs []*internal.Retraction
+	for _, r := range f.Retract {
+		if !semver.IsValid(r.Low) || !semver.IsValid(r.High) || semver.Compare(r.Low, r.High) > 0 {
+			continue
+		}
+		rs = append(rs, &internal.Retraction{Low: r.Low, High: r.High, Rationale: r.Rationale})
+	}
+	return rs
+}
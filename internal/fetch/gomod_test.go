@@ -0,0 +1,189 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+)
+
+func TestParseGoModRetractionsAndDeprecation(t *testing.T) {
+	for _, tc := range []struct {
+		name, goMod     string
+		wantRetractions []*internal.Retraction
+		wantDeprecation string
+	}{
+		{
+			name:  "none",
+			goMod: "module example.com/m\n\ngo 1.14\n",
+		},
+		{
+			name: "single versions and intervals",
+			goMod: `module example.com/m
+
+retract v1.0.0
+retract [v1.1.0, v1.1.5] // Data race.
+
+// Bad releases.
+retract (
+	v1.2.0
+	// Panics on start.
+	[v1.3.0,v1.3.2]
+	v1.4.0 // Wrong license.
+	bad-version
+	[v1.6.0, v1.5.0]
+)
+`,
+			wantRetractions: []*internal.Retraction{
+				{Low: "v1.0.0", High: "v1.0.0"},
+				{Low: "v1.1.0", High: "v1.1.5", Rationale: "Data race."},
+				{Low: "v1.2.0", High: "v1.2.0", Rationale: "Bad releases."},
+				{Low: "v1.3.0", High: "v1.3.2", Rationale: "Panics on start."},
+				{Low: "v1.4.0", High: "v1.4.0", Rationale: "Wrong license."},
+			},
+		},
+		{
+			name: "newer go directive",
+			goMod: `module example.com/m
+
+go 1.22.1
+
+toolchain go1.22.4
+
+retract (
+	v1.0.0 // Published accidentally.
+	[v1.1.0, v1.1.3]
+)
+`,
+			wantRetractions: []*internal.Retraction{
+				{Low: "v1.0.0", High: "v1.0.0", Rationale: "Published accidentally."},
+				{Low: "v1.1.0", High: "v1.1.3"},
+			},
+		},
+		{
+			name: "deprecated",
+			goMod: `// Package m does things.
+//
+// Deprecated: use example.com/m/v2
+// instead.
+module example.com/m
+`,
+			wantDeprecation: "use example.com/m/v2\ninstead.",
+		},
+		{
+			name:            "deprecated suffix comment",
+			goMod:           "module example.com/m // Deprecated: unmaintained.\n",
+			wantDeprecation: "unmaintained.",
+		},
+		{
+			name:  "deprecated not at start of paragraph",
+			goMod: "// This is not Deprecated: at all.\nmodule example.com/m\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseGoMod([]byte(tc.goMod))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantRetractions, got.retractions); diff != "" {
+				t.Errorf("retractions mismatch (-want +got):\n%s", diff)
+			}
+			if got.deprecation != tc.wantDeprecation {
+				t.Errorf("deprecation = %q, want %q", got.deprecation, tc.wantDeprecation)
+			}
+		})
+	}
+}
+
+func TestParseGoModDependenciesWithRetractions(t *testing.T) {
+	// The retract directive must not prevent the replace and exclude
+	// directives from being read.
+	got, err := parseGoMod([]byte(`module example.com/m
+
+require example.com/a v1.0.0
+replace example.com/a => ../a
+exclude example.com/a v0.1.0
+retract v1.0.0
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*internal.ModuleDependency{
+		{Kind: internal.DependencyRequire, ModulePath: "example.com/a", Version: "v1.0.0"},
+		{Kind: internal.DependencyReplace, ModulePath: "example.com/a", ReplacementPath: "../a"},
+		{Kind: internal.DependencyExclude, ModulePath: "example.com/a", Version: "v0.1.0"},
+	}
+	if diff := cmp.Diff(want, got.dependencies); diff != "" {
+		t.Errorf("dependencies mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseGoModGoVersion(t *testing.T) {
+	for _, tc := range []struct {
+		goDirective, want string
+	}{
+		{"", ""},
+		{"go 1.14", "1.14"},
+		{"go 1.21.0", "1.21.0"},
+		{"go 1.21rc1 // Release candidate.", "1.21"},
+	} {
+		t.Run(tc.goDirective, func(t *testing.T) {
+			got, err := parseGoMod([]byte("module example.com/m\n\n" + tc.goDirective + "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.goVersion != tc.want {
+				t.Errorf("goVersion = %q, want %q", got.goVersion, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseGoModInvalidGoVersion(t *testing.T) {
+	// modfile rejects a go directive without a minor version, even in
+	// ParseLax. The fetch keeps the module and omits its go.mod information.
+	if _, err := parseGoMod([]byte("module example.com/m\n\ngo 1\n")); err == nil {
+		t.Error("got nil error, want non-nil")
+	}
+}
+
+func TestParseGoModThreePartGoVersion(t *testing.T) {
+	// A go directive with a patch version, and a toolchain directive, must
+	// not prevent the rest of the file from being read.
+	got, err := parseGoMod([]byte(`// Deprecated: use example.com/m/v2.
+module example.com/m
+
+go 1.21.0
+
+toolchain go1.21.3
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.2.0 // indirect
+)
+
+replace example.com/a => ../a
+
+exclude example.com/a v0.1.0
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &goModInfo{
+		dependencies: []*internal.ModuleDependency{
+			{Kind: internal.DependencyRequire, ModulePath: "example.com/a", Version: "v1.0.0"},
+			{Kind: internal.DependencyRequire, ModulePath: "example.com/b", Version: "v1.2.0", Indirect: true},
+			{Kind: internal.DependencyReplace, ModulePath: "example.com/a", ReplacementPath: "../a"},
+			{Kind: internal.DependencyExclude, ModulePath: "example.com/a", Version: "v0.1.0"},
+		},
+		deprecation: "use example.com/m/v2.",
+		goVersion:   "1.21.0",
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(goModInfo{})); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"context"
+
+	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/stdlib"
+	"golang.org/x/pkgsite/internal/version"
+)
+
+// An APIHistorySource provides the versions in which the exported
+// identifiers of stored packages were added.
+type APIHistorySource interface {
+	// GetSymbolHistory returns the first version in which each exported
+	// identifier of the package with packagePath appears, among the release
+	// versions of the module with modulePath that are no later than
+	// version. The result is keyed by the anchor ID of the identifier: "F"
+	// for a function or type, "T.M" for a method.
+	GetSymbolHistory(ctx context.Context, packagePath, modulePath, version string) (map[string]string, error)
+}
+
+// A packageHistory holds the versions in which the identifiers of a package
+// were added, as of a version of its module. Its fields are exported so that
+// it can be included in the key of the render cache.
+type packageHistory struct {
+	// First maps the anchor IDs of the identifiers in earlier versions of
+	// the package to the version in which they first appear.
+	First map[string]string
+	// Earliest is the earliest version in First. Identifiers that appear in
+	// it have been in the package for as long as its history is known, so
+	// no version is shown for them.
+	Earliest string
+	// Current is the version being fetched, in which identifiers that are
+	// not in First were added. It is empty if the version is not a release
+	// version.
+	Current string
+}
+
+// loadPackageHistory returns the history in src of the package with the given
+// path as of the given version of its module. It returns nil if there is no
+// earlier version of the package, or if src is nil. Errors are logged, and
+// treated as if there were no history.
+func loadPackageHistory(ctx context.Context, src APIHistorySource, packagePath, modulePath, resolvedVersion string) *packageHistory {
+	if src == nil {
+		return nil
+	}
+	first, err := src.GetSymbolHistory(ctx, packagePath, modulePath, resolvedVersion)
+	if err != nil {
+		log.Errorf(ctx, "loadPackageHistory(%q, %q, %q): %v", packagePath, modulePath, resolvedVersion, err)
+		return nil
+	}
+	if len(first) == 0 {
+		return nil
+	}
+	h := &packageHistory{First: first}
+	for _, v := range first {
+		if h.Earliest == "" || version.ForSorting(v) < version.ForSorting(h.Earliest) {
+			h.Earliest = v
+		}
+	}
+	if t, err := version.ParseType(resolvedVersion); err == nil && t == version.TypeRelease {
+		h.Current = resolvedVersion
+	}
+	if modulePath == stdlib.ModulePath {
+		// Show the Go release, as in "go1.15".
+		for id, v := range h.First {
+			h.First[id] = stdlibTag(v)
+		}
+		h.Earliest = stdlibTag(h.Earliest)
+		if h.Current != "" {
+			h.Current = stdlibTag(h.Current)
+		}
+	}
+	return h
+}
+
+// withCurrent returns a copy of h in which v stands for the current version,
+// wherever it appears. It returns h if h is nil or has no current version.
+func (h *packageHistory) withCurrent(v string) *packageHistory {
+	if h == nil || h.Current == "" {
+		return h
+	}
+	c := &packageHistory{First: map[string]string{}, Earliest: h.Earliest, Current: v}
+	for id, w := range h.First {
+		if w == h.Current {
+			w = v
+		}
+		c.First[id] = w
+	}
+	if c.Earliest == h.Current {
+		c.Earliest = v
+	}
+	return c
+}
+
+func stdlibTag(v string) string {
+	tag, err := stdlib.TagForVersion(v)
+	if err != nil {
+		return v
+	}
+	return tag
+}
+
+// sinceVersion returns the version in which the identifier with the given
+// anchor ID was added to the package, or the empty string if it is not known
+// or the identifier was always in the package.
+func (h *packageHistory) sinceVersion(id string) string {
+	if h == nil {
+		return ""
+	}
+	v, ok := h.First[id]
+	if !ok {
+		v = h.Current
+	}
+	if v == h.Earliest {
+		return ""
+	}
+	return v
+}
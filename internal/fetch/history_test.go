@@ -0,0 +1,117 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"golang.org/x/pkgsite/internal/proxy"
+	"golang.org/x/pkgsite/internal/stdlib"
+	"golang.org/x/pkgsite/internal/testing/testhelper"
+)
+
+// fakeAPIHistorySource is an APIHistorySource that serves fixed histories,
+// keyed by package path.
+type fakeAPIHistorySource map[string]map[string]string
+
+func (s fakeAPIHistorySource) GetSymbolHistory(ctx context.Context, packagePath, modulePath, version string) (map[string]string, error) {
+	h := map[string]string{}
+	for id, v := range s[packagePath] {
+		h[id] = v
+	}
+	return h, nil
+}
+
+func TestPackageHistorySinceVersion(t *testing.T) {
+	ctx := context.Background()
+	src := fakeAPIHistorySource{
+		"example.com/m/p": {"A": "v1.0.0", "B": "v1.2.0", "T.M": "v1.10.0"},
+		"errors":          {"New": "v1.0.0", "Is": "v1.13.0"},
+	}
+
+	for _, test := range []struct {
+		packagePath, modulePath, version string
+		want                             map[string]string
+	}{
+		{
+			"example.com/m/p", "example.com/m", "v1.11.0",
+			map[string]string{"A": "", "B": "v1.2.0", "T.M": "v1.10.0", "New": "v1.11.0"},
+		},
+		{
+			// Identifiers that are new in a pseudo-version are not annotated.
+			"example.com/m/p", "example.com/m", "v1.11.1-0.20200101000000-abcdefabcdef",
+			map[string]string{"B": "v1.2.0", "New": ""},
+		},
+		{
+			// A package without history is not annotated.
+			"example.com/m/q", "example.com/m", "v1.11.0",
+			map[string]string{"A": "", "New": ""},
+		},
+		{
+			"errors", stdlib.ModulePath, "v1.15.0",
+			map[string]string{"New": "", "Is": "go1.13", "Unwrap": "go1.15"},
+		},
+	} {
+		h := loadPackageHistory(ctx, src, test.packagePath, test.modulePath, test.version)
+		for id, want := range test.want {
+			if got := h.sinceVersion(id); got != want {
+				t.Errorf("%s@%s: sinceVersion(%q) = %q, want %q", test.packagePath, test.version, id, got, want)
+			}
+		}
+		// With a placeholder for the current version, the results are
+		// the same, except for the placeholder.
+		hc := h.withCurrent("CURRENT")
+		for id, want := range test.want {
+			if h != nil && h.Current != "" && want == h.Current {
+				want = "CURRENT"
+			}
+			if got := hc.sinceVersion(id); got != want {
+				t.Errorf("%s@%s: withCurrent: sinceVersion(%q) = %q, want %q", test.packagePath, test.version, id, got, want)
+			}
+		}
+	}
+}
+
+func TestExtractPackagesSinceVersion(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	const modulePath = "github.com/my/history"
+	proxyClient, teardownProxy := proxy.SetupTestProxy(t, []*proxy.TestModule{{
+		ModulePath: modulePath,
+		Version:    "v1.3.0",
+		Files: map[string]string{
+			"LICENSE": testhelper.BSD0License,
+			"p/p.go":  "// Package p is a package.\npackage p\n\n// F is old.\nfunc F() {}\n\n// G is newer.\nfunc G() {}\n\n// H is new.\nfunc H() {}\n",
+		},
+	}})
+	defer teardownProxy()
+
+	src := fakeAPIHistorySource{
+		modulePath + "/p": {"F": "v1.0.0", "G": "v1.2.0"},
+	}
+
+	r, cleanup, err := proxyClient.GetZip(ctx, modulePath, "v1.3.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	pkgs, _, err := extractPackagesFromZip(ctx, modulePath, "v1.3.0", r, nil, nil, nil, Options{APIHistory: src})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := pkgs[0].DocumentationHTML.String()
+	for _, want := range []string{
+		`<a href="#F">¶</a></h3>`,
+		`<a href="#G">¶</a> <span class="Documentation-sinceVersion">Added in v1.2.0</span></h3>`,
+		`<a href="#H">¶</a> <span class="Documentation-sinceVersion">Added in v1.3.0</span></h3>`,
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("documentation does not contain %q:\n%s", want, doc)
+		}
+	}
+}
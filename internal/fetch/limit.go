@@ -4,6 +4,8 @@
 
 package fetch
 
+import "runtime"
+
 // Limits for discovery worker.
 const (
 	maxPackagesPerModule = 10000
@@ -24,3 +26,22 @@ const (
 var MaxDocumentationHTML = 10 * megabyte
 
 const megabyte = 1000 * 1000
+
+// defaultPackageMemoryBudgetMB is the memory budget for loading packages
+// used when Options.Settings.PackageMemoryBudgetMB is not positive.
+const defaultPackageMemoryBudgetMB = 200
+
+// packageLimits returns the maximum number of packages of a module that are
+// loaded at the same time, and the budget in bytes for the total size of their
+// Go files. Settings that are not positive are replaced by defaults.
+func (o Options) packageLimits() (workers int, budget int64) {
+	workers = o.Settings.PackageWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	budgetMB := o.Settings.PackageMemoryBudgetMB
+	if budgetMB <= 0 {
+		budgetMB = defaultPackageMemoryBudgetMB
+	}
+	return workers, int64(budgetMB) * megabyte
+}
@@ -0,0 +1,208 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"context"
+	"go/ast"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/fetch/dochtml"
+	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/stdlib"
+)
+
+// A SymbolSource provides the exported identifiers of stored packages, so
+// that references to them in the documentation of the packages being fetched
+// can be linked.
+type SymbolSource interface {
+	// GetPackageSymbols returns the name and identifiers of each of the
+	// packages with the given paths in the given version of the module with
+	// modulePath. If version is empty, the latest version of each package
+	// is used. Packages that are not stored are omitted.
+	GetPackageSymbols(ctx context.Context, modulePath, version string, paths []string) ([]*internal.PackageSymbols, error)
+}
+
+// An importLinker determines the links from the documentation of the packages
+// of a module version to the packages they import. It is safe for concurrent
+// use.
+type importLinker struct {
+	modulePath string
+	version    string
+	// linkVersion is the version in the links to the packages of the
+	// module. It is version, except in the copies made by withLinkVersion.
+	linkVersion string
+	// requires maps the paths of the modules that the module requires to
+	// their versions. Replaced modules are left out, since their
+	// documentation is not that of the required version.
+	requires map[string]string
+	// symbols is the source of the identifiers of imported packages. If it
+	// is nil, only identifiers in declarations are linked to other
+	// packages.
+	symbols SymbolSource
+
+	// mu and related are shared with the copies made by withLinkVersion.
+	mu *sync.Mutex
+	// related holds the imported packages that have been looked up in
+	// symbols, by import path. The value is nil for packages that are not
+	// stored.
+	related map[string]*dochtml.RelatedPackage
+}
+
+func newImportLinker(modulePath, version string, deps []*internal.ModuleDependency, symbols SymbolSource) *importLinker {
+	l := &importLinker{
+		modulePath:  modulePath,
+		version:     version,
+		linkVersion: version,
+		requires:    map[string]string{},
+		symbols:     symbols,
+		mu:          &sync.Mutex{},
+		related:     map[string]*dochtml.RelatedPackage{},
+	}
+	replaced := map[string]bool{}
+	for _, d := range deps {
+		if d.Kind == internal.DependencyReplace {
+			replaced[d.ModulePath] = true
+		}
+	}
+	for _, d := range deps {
+		if d.Kind == internal.DependencyRequire && !replaced[d.ModulePath] {
+			l.requires[d.ModulePath] = d.Version
+		}
+	}
+	return l
+}
+
+// withLinkVersion returns a copy of l that uses v as the version in the links
+// to the packages of the module. The imported packages are still looked up
+// at the version of the module, and the copy shares the packages that l has
+// looked up.
+func (l *importLinker) withLinkVersion(v string) *importLinker {
+	c := *l
+	c.linkVersion = v
+	return &c
+}
+
+// moduleVersion returns the path and version of the module that contains the
+// package with the given import path, as the module being fetched depends on
+// it: the module itself, or a module required by its go.mod file. It returns
+// empty strings if the module is not known.
+func (l *importLinker) moduleVersion(importPath string) (modulePath, version string) {
+	if stdlib.Contains(importPath) {
+		if l.modulePath == stdlib.ModulePath {
+			return l.modulePath, l.version
+		}
+		return "", ""
+	}
+	// Use the longest module path that contains the package, since modules
+	// may be nested.
+	consider := func(m, v string) {
+		if (importPath == m || strings.HasPrefix(importPath, m+"/")) && len(m) > len(modulePath) {
+			modulePath, version = m, v
+		}
+	}
+	consider(l.modulePath, l.version)
+	for m, v := range l.requires {
+		consider(m, v)
+	}
+	return modulePath, version
+}
+
+// packageURL returns the URL of the documentation of the package with the
+// given import path, at the version of its module that the module being
+// fetched depends on: the link version for the packages of the module, or
+// the version required by its go.mod file. It returns the empty string if
+// the version is not known.
+func (l *importLinker) packageURL(importPath string) string {
+	if l.modulePath == stdlib.ModulePath || stdlib.Contains(importPath) {
+		return ""
+	}
+	modulePath, version := l.moduleVersion(importPath)
+	if modulePath == "" {
+		return ""
+	}
+	if modulePath == l.modulePath {
+		version = l.linkVersion
+	}
+	return "/" + modulePath + "@" + version + strings.TrimPrefix(importPath, modulePath)
+}
+
+// relatedPackages returns the packages among importPaths that are stored in
+// symbols, ordered by import path. Each package is looked up only once, at
+// the version returned by moduleVersion, or at its latest version if that is
+// not known. Errors are logged, and the packages are treated as not stored.
+func (l *importLinker) relatedPackages(ctx context.Context, importPaths []string) []*dochtml.RelatedPackage {
+	if l.symbols == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Group the packages that have not been looked up by module version, so
+	// that each module version is looked up once.
+	type modver struct{ path, version string }
+	var (
+		mvs     []modver
+		missing = map[modver][]string{}
+	)
+	for _, p := range importPaths {
+		if _, ok := l.related[p]; ok {
+			continue
+		}
+		m, v := l.moduleVersion(p)
+		mv := modver{m, v}
+		if missing[mv] == nil {
+			mvs = append(mvs, mv)
+		}
+		missing[mv] = append(missing[mv], p)
+	}
+	for _, mv := range mvs {
+		pss, err := l.symbols.GetPackageSymbols(ctx, mv.path, mv.version, missing[mv])
+		if err != nil {
+			log.Errorf(ctx, "importLinker.relatedPackages: %v", err)
+		}
+		for _, p := range missing[mv] {
+			l.related[p] = nil
+		}
+		for _, ps := range pss {
+			rp := &dochtml.RelatedPackage{ImportPath: ps.Path, Name: ps.Name}
+			for _, s := range ps.Symbols {
+				rp.IDs = append(rp.IDs, s.Anchor())
+			}
+			l.related[ps.Path] = rp
+		}
+	}
+	var rps []*dochtml.RelatedPackage
+	for _, p := range importPaths {
+		if rp := l.related[p]; rp != nil {
+			rps = append(rps, rp)
+		}
+	}
+	sort.Slice(rps, func(i, j int) bool { return rps[i].ImportPath < rps[j].ImportPath })
+	return rps
+}
+
+// fileImports returns the paths imported by files, sorted and without
+// duplicates.
+func fileImports(files []*ast.File) []string {
+	seen := map[string]bool{}
+	var paths []string
+	for _, f := range files {
+		for _, imp := range f.Imports {
+			p, err := strconv.Unquote(imp.Path.Value)
+			if err != nil || seen[p] {
+				continue
+			}
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,153 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/proxy"
+	"golang.org/x/pkgsite/internal/testing/testhelper"
+)
+
+func TestImportLinkerPackageURL(t *testing.T) {
+	links := newImportLinker("github.com/my/module", "v1.2.0", []*internal.ModuleDependency{
+		{Kind: internal.DependencyRequire, ModulePath: "example.com/dep", Version: "v0.3.0"},
+		{Kind: internal.DependencyRequire, ModulePath: "example.com/dep/nested", Version: "v1.0.0"},
+		{Kind: internal.DependencyRequire, ModulePath: "example.com/replaced", Version: "v1.0.0"},
+		{Kind: internal.DependencyReplace, ModulePath: "example.com/replaced", Version: "v1.0.0"},
+	}, nil)
+	for _, test := range []struct {
+		importPath, want string
+	}{
+		{"github.com/my/module/sub", "/github.com/my/module@v1.2.0/sub"},
+		{"example.com/dep", "/example.com/dep@v0.3.0"},
+		{"example.com/dep/pkg", "/example.com/dep@v0.3.0/pkg"},
+		{"example.com/dep/nested/pkg", "/example.com/dep/nested@v1.0.0/pkg"},
+		{"example.com/depot", ""},
+		{"example.com/replaced", ""},
+		{"example.com/unknown", ""},
+		{"io", ""},
+	} {
+		if got := links.packageURL(test.importPath); got != test.want {
+			t.Errorf("packageURL(%q) = %q, want %q", test.importPath, got, test.want)
+		}
+	}
+
+	// Only the links to the packages of the module use the link version.
+	placeholderLinks := links.withLinkVersion("VERSION")
+	for _, test := range []struct {
+		importPath, want string
+	}{
+		{"github.com/my/module/sub", "/github.com/my/module@VERSION/sub"},
+		{"example.com/dep/pkg", "/example.com/dep@v0.3.0/pkg"},
+	} {
+		if got := placeholderLinks.packageURL(test.importPath); got != test.want {
+			t.Errorf("withLinkVersion: packageURL(%q) = %q, want %q", test.importPath, got, test.want)
+		}
+	}
+}
+
+// fakeSymbolSource is a SymbolSource that serves a fixed set of packages at
+// every version.
+type fakeSymbolSource struct {
+	pkgs []*internal.PackageSymbols
+	// calls holds the module version of each call, as "path@version".
+	calls []string
+}
+
+func (s *fakeSymbolSource) GetPackageSymbols(ctx context.Context, modulePath, version string, paths []string) ([]*internal.PackageSymbols, error) {
+	s.calls = append(s.calls, modulePath+"@"+version)
+	var pss []*internal.PackageSymbols
+	for _, ps := range s.pkgs {
+		for _, p := range paths {
+			if ps.Path == p {
+				pss = append(pss, ps)
+			}
+		}
+	}
+	return pss, nil
+}
+
+func TestExtractPackagesLinksImports(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	const modulePath = "github.com/my/linked"
+	proxyClient, teardownProxy := proxy.SetupTestProxy(t, []*proxy.TestModule{{
+		ModulePath: modulePath,
+		Files: map[string]string{
+			"LICENSE": testhelper.BSD0License,
+			"a/a.go": `// Package a uses b.
+package a
+
+import "example.com/dep/b"
+
+// F returns a b.T. See also b.New and b.Missing.
+func F() b.T { return b.New() }
+
+// H wraps F.
+func H() b.T { return F() }
+`,
+			"c/c.go": `// Package c also uses b.
+package c
+
+import "example.com/dep/b"
+
+// G calls b.New.
+func G() { b.New() }
+`,
+		},
+	}})
+	defer teardownProxy()
+
+	symbols := &fakeSymbolSource{pkgs: []*internal.PackageSymbols{{
+		Path: "example.com/dep/b",
+		Name: "b",
+		Symbols: []*internal.Symbol{
+			{Name: "T", Kind: internal.SymbolKindType},
+			{Name: "New", Kind: internal.SymbolKindFunction},
+		},
+	}}}
+
+	r, cleanup, err := proxyClient.GetZip(ctx, modulePath, "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	deps := []*internal.ModuleDependency{
+		{Kind: internal.DependencyRequire, ModulePath: "example.com/dep", Version: "v0.5.0"},
+	}
+	pkgs, _, err := extractPackagesFromZip(ctx, modulePath, "v1.0.0", r, nil, nil, deps, Options{Symbols: symbols})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 2 {
+		t.Fatalf("got %d packages, want 2", len(pkgs))
+	}
+	doc := pkgs[0].DocumentationHTML.String()
+	for _, want := range []string{
+		// The declaration links to the required version.
+		`func F() <a href="/example.com/dep@v0.5.0/b">b</a>.<a href="/example.com/dep@v0.5.0/b#T">T</a></pre>`,
+		// So do identifiers in comments.
+		`See also <a href="/example.com/dep@v0.5.0/b">b</a>.<a href="/example.com/dep@v0.5.0/b#New">New</a>`,
+		// Identifiers that b doesn't declare are not linked.
+		"and b.Missing.",
+		// Nor are identifiers of the package itself.
+		"H wraps F.",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("documentation of %s does not contain %q:\n%s", pkgs[0].Path, want, doc)
+		}
+	}
+	// Both packages import b, but it is looked up only once, at the
+	// required version.
+	if want := []string{"example.com/dep@v0.5.0"}; !cmp.Equal(symbols.calls, want) {
+		t.Errorf("got calls to GetPackageSymbols for %v, want %v", symbols.calls, want)
+	}
+}
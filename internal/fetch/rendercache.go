@@ -0,0 +1,334 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/safehtml"
+	"github.com/google/safehtml/uncheckedconversions"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/experiment"
+	"golang.org/x/pkgsite/internal/fetch/dochtml"
+	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/source"
+)
+
+// RendererVersion identifies the packages produced by loadPackage. It must be
+// incremented whenever a change to this package changes the package that
+// loadPackage produces from the same source, so that packages rendered by
+// earlier versions are not reused.
+const RendererVersion = 3
+
+// A RenderCache stores the packages produced by loadPackage, keyed by a hash
+// of their source and a renderer version. Pseudo-versions and re-tagged
+// versions of a module often contain packages whose source is identical to
+// that of another version, and those packages are loaded from the cache
+// instead of being parsed and rendered again.
+type RenderCache interface {
+	// GetRenderedPackage returns the data stored for contentHash and
+	// rendererVersion. It returns an error wrapping derrors.NotFound if
+	// there is none.
+	GetRenderedPackage(ctx context.Context, contentHash string, rendererVersion int) ([]byte, error)
+	// InsertRenderedPackage stores data for contentHash and rendererVersion.
+	InsertRenderedPackage(ctx context.Context, contentHash string, rendererVersion int, data []byte) error
+}
+
+var (
+	keyRenderCacheHit  = tag.MustNewKey("fetch.render_cache.hit")
+	renderCacheResults = stats.Int64(
+		"go-discovery/fetch/render_cache_result_count",
+		"The result of looking up a package in the render cache.",
+		stats.UnitDimensionless,
+	)
+
+	// RenderCacheResultCount is a counter of render cache lookups, by
+	// whether they were a hit.
+	RenderCacheResultCount = &view.View{
+		Name:        "go-discovery/fetch/render_cache_result_count",
+		Measure:     renderCacheResults,
+		Aggregation: view.Count(),
+		Description: "render cache lookups, by whether they were a hit",
+		TagKeys:     []tag.Key{keyRenderCacheHit},
+	}
+)
+
+func recordRenderCacheResult(ctx context.Context, hit bool) {
+	stats.RecordWithTags(ctx, []tag.Mutator{
+		tag.Upsert(keyRenderCacheHit, strconv.FormatBool(hit)),
+	}, renderCacheResults.M(1))
+}
+
+// In packages in the render cache, commitPlaceholder stands for the commit in
+// source links, versionPlaceholder for the version in links to the other
+// packages of the module, and currentPlaceholder for the current version in
+// the history of the package, so that the packages can be reused for other
+// versions. They are replaced with the values for the version being fetched.
+const (
+	commitPlaceholder  = "pkgsite-render-cache-commit"
+	versionPlaceholder = "pkgsite-render-cache-version"
+	currentPlaceholder = "pkgsite-render-cache-current"
+)
+
+// loadPackageCached is like loadPackage, but uses cache, if it is not nil, to
+// avoid loading packages whose source has been loaded before.
+//
+// Only packages that loadPackage loads without error are stored in the cache.
+func loadPackageCached(ctx context.Context, cache RenderCache, zipGoFiles []*zip.File, innerPath, modulePath string, sourceInfo *source.Info, links *importLinker, history *packageHistory) (*internal.LegacyPackage, error) {
+	if cache == nil {
+		return loadPackage(ctx, zipGoFiles, innerPath, modulePath, sourceInfo, links, history)
+	}
+	var current string
+	if history != nil {
+		current = history.Current
+	}
+	if !isPlainValue(sourceInfo.Commit()) || !isPlainValue(links.version) || !isPlainValue(current) {
+		// The values can't be put in place of the placeholders without
+		// escaping them, so don't cache the package.
+		return loadPackage(ctx, zipGoFiles, innerPath, modulePath, sourceInfo, links, history)
+	}
+	files := map[string][]byte{}
+	for _, f := range zipGoFiles {
+		b, err := readZipFile(f)
+		if err != nil {
+			// Let loadPackage report the error.
+			return loadPackage(ctx, zipGoFiles, innerPath, modulePath, sourceInfo, links, history)
+		}
+		if bytes.Contains(b, []byte(commitPlaceholder)) || bytes.Contains(b, []byte(versionPlaceholder)) ||
+			bytes.Contains(b, []byte(currentPlaceholder)) {
+			// The placeholders would be replaced in the documentation
+			// of this package, so don't cache it.
+			return loadPackage(ctx, zipGoFiles, innerPath, modulePath, sourceInfo, links, history)
+		}
+		files[path.Base(f.Name)] = b
+	}
+	renderInfo := sourceInfo.WithCommit(commitPlaceholder)
+	renderLinks := links.withLinkVersion(versionPlaceholder)
+	renderHistory := history.withCurrent(currentPlaceholder)
+	fill := func(pkg *internal.LegacyPackage) {
+		fillPlaceholders(pkg, sourceInfo.Commit(), links.version, current)
+	}
+	hash, err := contentHash(ctx, files, innerPath, modulePath, renderInfo, renderLinks, renderHistory)
+	if err != nil {
+		log.Errorf(ctx, "render cache: %v", err)
+		return loadPackage(ctx, zipGoFiles, innerPath, modulePath, sourceInfo, links, history)
+	}
+
+	data, err := cache.GetRenderedPackage(ctx, hash, RendererVersion)
+	if err == nil {
+		pkg, err := decodeRenderedPackage(data)
+		if err == nil {
+			recordRenderCacheResult(ctx, true)
+			fill(pkg)
+			return pkg, nil
+		}
+		log.Errorf(ctx, "render cache: %s: %v", hash, err)
+	} else if !errors.Is(err, derrors.NotFound) {
+		log.Errorf(ctx, "render cache: %v", err)
+	}
+	recordRenderCacheResult(ctx, false)
+
+	pkg, err := loadPackage(ctx, zipGoFiles, innerPath, modulePath, renderInfo, renderLinks, renderHistory)
+	if err != nil || pkg == nil {
+		// Load the package again so that the error doesn't mention the
+		// placeholders.
+		return loadPackage(ctx, zipGoFiles, innerPath, modulePath, sourceInfo, links, history)
+	}
+	data, err = encodeRenderedPackage(pkg)
+	if err == nil {
+		err = cache.InsertRenderedPackage(ctx, hash, RendererVersion, data)
+	}
+	if err != nil {
+		log.Errorf(ctx, "render cache: %s: %v", hash, err)
+	}
+	fill(pkg)
+	return pkg, nil
+}
+
+// contentHash returns a hash of everything that determines the package that
+// loadPackage produces: the Go files of the package, which maps file names to
+// contents, its path, the source information with the commit replaced, the
+// links to the packages it imports with the version of the module replaced,
+// the history of its identifiers with the current version replaced, and the
+// settings that affect rendering.
+func contentHash(ctx context.Context, files map[string][]byte, innerPath, modulePath string, renderInfo *source.Info, links *importLinker, history *packageHistory) (_ string, err error) {
+	defer derrors.Wrap(&err, "contentHash(ctx, files, %q, %q, renderInfo)", innerPath, modulePath)
+
+	infoJSON, err := json.Marshal(renderInfo)
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// The documentation of each build context links to the packages that
+	// its files import, so include the links for the imports of all the
+	// files.
+	fset := token.NewFileSet()
+	var astFiles []*ast.File
+	for _, name := range names {
+		f, err := parser.ParseFile(fset, name, files[name], parser.ImportsOnly)
+		if err != nil {
+			return "", err
+		}
+		astFiles = append(astFiles, f)
+	}
+	imports := fileImports(astFiles)
+	importURLs := map[string]string{}
+	for _, p := range imports {
+		importURLs[p] = links.packageURL(p)
+	}
+	linksJSON, err := json.Marshal(struct {
+		URLs    map[string]string
+		Related []*dochtml.RelatedPackage
+		History *packageHistory
+	}{importURLs, links.relatedPackages(ctx, imports), history})
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%d\x00%t\x00", modulePath, innerPath, infoJSON, linksJSON,
+		MaxDocumentationHTML, experiment.IsActive(ctx, internal.ExperimentInsertPlaygroundLinks))
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\x00%d\x00", name, len(files[name]))
+		h.Write(files[name])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// isPlainValue reports whether s can be put in place of a placeholder in
+// documentation without escaping it: whether s has only characters that are
+// never escaped in HTML text, attribute values or URLs. Versions, tags and
+// commit hashes have only such characters, except for the "+" of
+// +incompatible versions, which html/template escapes.
+func isPlainValue(s string) bool {
+	for _, r := range s {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || strings.ContainsRune("./-_", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// fillPlaceholders replaces the placeholders in the documentation of pkg with
+// commit, version and the current version of its history, which must satisfy
+// isPlainValue.
+func fillPlaceholders(pkg *internal.LegacyPackage, commit, version, current string) {
+	r := strings.NewReplacer(commitPlaceholder, commit, versionPlaceholder, version, currentPlaceholder, current)
+	replace := func(h safehtml.HTML) safehtml.HTML {
+		// The placeholders and the values that replace them have only
+		// characters that are not escaped anywhere in HTML, so the
+		// result is as safe as h.
+		return uncheckedconversions.HTMLFromStringKnownToSatisfyTypeContract(r.Replace(h.String()))
+	}
+	pkg.DocumentationHTML = replace(pkg.DocumentationHTML)
+	for _, d := range pkg.OtherDocumentation {
+		d.HTML = replace(d.HTML)
+	}
+}
+
+// renderedPackage is the encoding of a package in the render cache.
+// safehtml.HTML values cannot be encoded directly, so they are stored as
+// strings.
+type renderedPackage struct {
+	Path               string
+	Name               string
+	Synopsis           string
+	Imports            []string
+	DocumentationHTML  string
+	GOOS               string
+	GOARCH             string
+	OtherDocumentation []renderedDocumentation
+	V1Path             string
+	Symbols            []*internal.Symbol
+	UsesCgo            bool
+	GoReleaseTags      []string
+}
+
+type renderedDocumentation struct {
+	GOOS     string
+	GOARCH   string
+	Synopsis string
+	HTML     string
+}
+
+func encodeRenderedPackage(pkg *internal.LegacyPackage) ([]byte, error) {
+	rp := renderedPackage{
+		Path:              pkg.Path,
+		Name:              pkg.Name,
+		Synopsis:          pkg.Synopsis,
+		Imports:           pkg.Imports,
+		DocumentationHTML: pkg.DocumentationHTML.String(),
+		GOOS:              pkg.GOOS,
+		GOARCH:            pkg.GOARCH,
+		V1Path:            pkg.V1Path,
+		Symbols:           pkg.Symbols,
+		UsesCgo:           pkg.UsesCgo,
+		GoReleaseTags:     pkg.GoReleaseTags,
+	}
+	for _, d := range pkg.OtherDocumentation {
+		rp.OtherDocumentation = append(rp.OtherDocumentation, renderedDocumentation{
+			GOOS:     d.GOOS,
+			GOARCH:   d.GOARCH,
+			Synopsis: d.Synopsis,
+			HTML:     d.HTML.String(),
+		})
+	}
+	return json.Marshal(rp)
+}
+
+// decodeRenderedPackage decodes a package encoded by encodeRenderedPackage.
+// The documentation in data is trusted to be the HTML that was produced by
+// dochtml.Render when the package was stored, so data must come from the
+// render cache.
+func decodeRenderedPackage(data []byte) (*internal.LegacyPackage, error) {
+	var rp renderedPackage
+	if err := json.Unmarshal(data, &rp); err != nil {
+		return nil, err
+	}
+	pkg := &internal.LegacyPackage{
+		Path:              rp.Path,
+		Name:              rp.Name,
+		Synopsis:          rp.Synopsis,
+		Imports:           rp.Imports,
+		DocumentationHTML: uncheckedconversions.HTMLFromStringKnownToSatisfyTypeContract(rp.DocumentationHTML),
+		GOOS:              rp.GOOS,
+		GOARCH:            rp.GOARCH,
+		V1Path:            rp.V1Path,
+		Symbols:           rp.Symbols,
+		UsesCgo:           rp.UsesCgo,
+		GoReleaseTags:     rp.GoReleaseTags,
+	}
+	for _, d := range rp.OtherDocumentation {
+		pkg.OtherDocumentation = append(pkg.OtherDocumentation, &internal.Documentation{
+			GOOS:     d.GOOS,
+			GOARCH:   d.GOARCH,
+			Synopsis: d.Synopsis,
+			HTML:     uncheckedconversions.HTMLFromStringKnownToSatisfyTypeContract(d.HTML),
+		})
+	}
+	return pkg, nil
+}
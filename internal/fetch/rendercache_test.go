@@ -0,0 +1,236 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/safehtml"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/proxy"
+	"golang.org/x/pkgsite/internal/source"
+	"golang.org/x/pkgsite/internal/testing/testhelper"
+)
+
+// fakeRenderCache is a RenderCache that stores packages in memory.
+type fakeRenderCache struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	hits int
+}
+
+func (c *fakeRenderCache) GetRenderedPackage(ctx context.Context, contentHash string, rendererVersion int) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.data[contentHash]
+	if !ok {
+		return nil, derrors.NotFound
+	}
+	c.hits++
+	return data, nil
+}
+
+func (c *fakeRenderCache) InsertRenderedPackage(ctx context.Context, contentHash string, rendererVersion int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[contentHash] = data
+	return nil
+}
+
+func TestLoadPackageCached(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	const (
+		modulePath = "github.com/example/dedup"
+		repoURL    = "https://" + modulePath
+	)
+	files := map[string]string{
+		"LICENSE": testhelper.BSD0License,
+		"a/a.go":  "// Package a is a package.\npackage a\n\n// F is a function.\nfunc F() {}",
+		"b/b.go":  "// Package b is another package.\npackage b\n\nimport _ \"C\"\n\n// G is a function.\nfunc G() {}",
+	}
+	changed := map[string]string{}
+	for name, contents := range files {
+		changed[name] = contents
+	}
+	changed["b/b.go"] += "\n\n// H is a new function.\nfunc H() {}"
+	proxyClient, teardownProxy := proxy.SetupTestProxy(t, []*proxy.TestModule{
+		{ModulePath: modulePath, Version: "v1.0.0", Files: files},
+		{ModulePath: modulePath, Version: "v1.0.1", Files: files},
+		{ModulePath: modulePath, Version: "v1.1.0", Files: changed},
+	})
+	defer teardownProxy()
+
+	extract := func(version string, cache RenderCache) []*internal.LegacyPackage {
+		t.Helper()
+		r, cleanup, err := proxyClient.GetZip(ctx, modulePath, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cleanup()
+		sourceInfo := source.NewGitHubInfo(repoURL, "", version)
+		pkgs, _, err := extractPackagesFromZip(ctx, modulePath, version, r, nil, sourceInfo, nil, Options{RenderCache: cache})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pkgs
+	}
+
+	// Load each version without a cache, for comparison.
+	want := map[string][]*internal.LegacyPackage{}
+	for _, v := range []string{"v1.0.0", "v1.0.1", "v1.1.0"} {
+		want[v] = extract(v, nil)
+	}
+
+	cache := &fakeRenderCache{data: map[string][]byte{}}
+	for _, test := range []struct {
+		version  string
+		wantHits int
+	}{
+		{"v1.0.0", 0},
+		// v1.0.1 is identical to v1.0.0.
+		{"v1.0.1", 2},
+		// Only package a is the same in v1.1.0.
+		{"v1.1.0", 3},
+		// Loading a version again reuses all of its packages.
+		{"v1.1.0", 5},
+	} {
+		got := extract(test.version, cache)
+		if diff := cmp.Diff(want[test.version], got, cmp.AllowUnexported(safehtml.HTML{})); diff != "" {
+			t.Errorf("%s: mismatch (-uncached +cached):\n%s", test.version, diff)
+		}
+		for _, pkg := range got {
+			if link := repoURL + "/blob/" + test.version + "/"; !strings.Contains(pkg.DocumentationHTML.String(), link) {
+				t.Errorf("%s: documentation of %s does not link to %s", test.version, pkg.Path, link)
+			}
+		}
+		if cache.hits != test.wantHits {
+			t.Errorf("%s: got %d cache hits, want %d", test.version, cache.hits, test.wantHits)
+		}
+	}
+}
+
+func TestLoadPackageCachedAcrossVersions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	// Packages whose documentation links to other packages of the module,
+	// and shows the versions in which identifiers were added, are reused
+	// for other versions with the same source.
+	const modulePath = "github.com/example/samesource"
+	files := map[string]string{
+		"LICENSE": testhelper.BSD0License,
+		"a/a.go": `// Package a uses b.
+package a
+
+import "github.com/example/samesource/b"
+
+// F returns a b.T.
+func F() b.T { return b.New() }
+`,
+		"b/b.go": `// Package b is a package.
+package b
+
+// T is a type.
+type T struct{}
+
+// New returns a T.
+func New() T { return T{} }
+`,
+	}
+	proxyClient, teardownProxy := proxy.SetupTestProxy(t, []*proxy.TestModule{
+		{ModulePath: modulePath, Version: "v1.0.0", Files: files},
+		{ModulePath: modulePath, Version: "v1.1.0", Files: files},
+	})
+	defer teardownProxy()
+
+	opts := Options{
+		Symbols: &fakeSymbolSource{pkgs: []*internal.PackageSymbols{{
+			Path: modulePath + "/b",
+			Name: "b",
+			Symbols: []*internal.Symbol{
+				{Name: "T", Kind: internal.SymbolKindType},
+				{Name: "New", Kind: internal.SymbolKindFunction},
+			},
+		}}},
+		APIHistory: fakeAPIHistorySource{modulePath + "/b": {"T": "v0.9.0"}},
+	}
+	extract := func(version string, cache RenderCache) []*internal.LegacyPackage {
+		t.Helper()
+		r, cleanup, err := proxyClient.GetZip(ctx, modulePath, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cleanup()
+		opts := opts
+		opts.RenderCache = cache
+		pkgs, _, err := extractPackagesFromZip(ctx, modulePath, version, r, nil, nil, nil, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pkgs
+	}
+
+	cache := &fakeRenderCache{data: map[string][]byte{}}
+	for _, test := range []struct {
+		version  string
+		wantHits int
+	}{
+		{"v1.0.0", 0},
+		{"v1.1.0", 2},
+	} {
+		want := extract(test.version, nil)
+		got := extract(test.version, cache)
+		if diff := cmp.Diff(want, got, cmp.AllowUnexported(safehtml.HTML{})); diff != "" {
+			t.Errorf("%s: mismatch (-uncached +cached):\n%s", test.version, diff)
+		}
+		if cache.hits != test.wantHits {
+			t.Errorf("%s: got %d cache hits, want %d", test.version, cache.hits, test.wantHits)
+		}
+		for _, pkg := range got {
+			doc := pkg.DocumentationHTML.String()
+			if strings.Contains(doc, "pkgsite-render-cache") {
+				t.Errorf("%s: documentation of %s contains a placeholder:\n%s", test.version, pkg.Path, doc)
+			}
+			var wantDoc string
+			switch pkg.Path {
+			case modulePath + "/a":
+				wantDoc = `<a href="/` + modulePath + `@` + test.version + `/b#T">T</a>`
+			case modulePath + "/b":
+				wantDoc = `<span class="Documentation-sinceVersion">Added in ` + test.version + `</span>`
+			}
+			if !strings.Contains(doc, wantDoc) {
+				t.Errorf("%s: documentation of %s does not contain %q:\n%s", test.version, pkg.Path, wantDoc, doc)
+			}
+		}
+	}
+}
+
+func TestIsPlainValue(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		want bool
+	}{
+		{"", true},
+		{"v1.2.3", true},
+		{"v0.0.0-20200101000000-abcdefabcdef", true},
+		{"go1.15", true},
+		{"sub/v1.0.0", true},
+		{"0123456789abcdef0123456789abcdef01234567", true},
+		{"v2.0.0+incompatible", false},
+		{`v1.0.0"><script>`, false},
+		{"v1.0.0&amp;", false},
+	} {
+		if got := isPlainValue(test.s); got != test.want {
+			t.Errorf("isPlainValue(%q) = %t, want %t", test.s, got, test.want)
+		}
+	}
+}
@@ -43,6 +43,11 @@ func cleanFetchResult(fr *FetchResult, detector *licenses.Detector) *FetchResult
 	if fr.Module.CommitTime.IsZero() {
 		fr.Module.CommitTime = testProxyCommitTime
 	}
+	if fr.Module.GoVersion == "" && fr.ModulePath != stdlib.ModulePath {
+		// The go version of the go.mod file that the test proxy serves for
+		// modules without one.
+		fr.Module.GoVersion = "1.12"
+	}
 
 	allLicenses := detector.AllLicenses()
 	if len(allLicenses) > 0 {
@@ -67,16 +72,19 @@ func cleanFetchResult(fr *FetchResult, detector *licenses.Detector) *FetchResult
 			}
 			dir.Package.Path = dir.Path
 			fr.Module.LegacyPackages = append(fr.Module.LegacyPackages, &internal.LegacyPackage{
-				Path:              dir.Path,
-				V1Path:            dir.V1Path,
-				Licenses:          dir.Licenses,
-				Name:              dir.Package.Name,
-				Synopsis:          dir.Package.Documentation.Synopsis,
-				DocumentationHTML: dir.Package.Documentation.HTML,
-				Imports:           dir.Package.Imports,
-				GOOS:              dir.Package.Documentation.GOOS,
-				GOARCH:            dir.Package.Documentation.GOARCH,
-				IsRedistributable: dir.IsRedistributable,
+				Path:               dir.Path,
+				V1Path:             dir.V1Path,
+				Licenses:           dir.Licenses,
+				Name:               dir.Package.Name,
+				Synopsis:           dir.Package.Documentation.Synopsis,
+				DocumentationHTML:  dir.Package.Documentation.HTML,
+				Imports:            dir.Package.Imports,
+				GOOS:               dir.Package.Documentation.GOOS,
+				GOARCH:             dir.Package.Documentation.GOARCH,
+				OtherDocumentation: dir.Package.OtherDocumentation,
+				IsRedistributable:  dir.IsRedistributable,
+				UsesCgo:            dir.Package.UsesCgo,
+				GoReleaseTags:      dir.Package.GoReleaseTags,
 			})
 			if shouldSetPVS {
 				fr.PackageVersionStates = append(
@@ -105,10 +113,12 @@ func licenseDetector(ctx context.Context, t *testing.T, modulePath, version stri
 			t.Fatal(err)
 		}
 	} else {
-		zipReader, err = proxyClient.GetZip(ctx, modulePath, version)
+		var cleanup func()
+		zipReader, cleanup, err = proxyClient.GetZip(ctx, modulePath, version)
 		if err != nil {
 			t.Fatal(err)
 		}
+		t.Cleanup(cleanup)
 	}
 	logf := func(format string, args ...interface{}) {
 		log.Infof(ctx, format, args...)
@@ -164,5 +174,12 @@ func validateDocumentationHTML(t *testing.T, got, want *internal.Module) {
 		if len(wantHTML.String()) != 0 && !strings.Contains(gotHTML.String(), wantHTML.String()) {
 			t.Errorf("documentation for got.Module.Directories[%d].DocumentationHTML does not contain wanted documentation substring:\n want (substring): %q\n got: %q\n", i, wantHTML, gotHTML)
 		}
+		for j, wantDoc := range want.Directories[i].Package.OtherDocumentation {
+			wantHTML := wantDoc.HTML
+			gotHTML := got.Directories[i].Package.OtherDocumentation[j].HTML
+			if len(wantHTML.String()) != 0 && !strings.Contains(gotHTML.String(), wantHTML.String()) {
+				t.Errorf("documentation for got.Module.Directories[%d].OtherDocumentation[%d] does not contain wanted documentation substring:\n want (substring): %q\n got: %q\n", i, j, wantHTML, gotHTML)
+			}
+		}
 	}
 }
@@ -0,0 +1,267 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/safehtml/template"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/postgres"
+	"golang.org/x/pkgsite/internal/stdlib"
+)
+
+// apiDiffSeparator separates the two versions in the URL path of an API diff
+// page, as in "/github.com/a/b@v1.3.0...v1.5.2".
+const apiDiffSeparator = "..."
+
+// APIDiffPage contains data for the API diff page.
+type APIDiffPage struct {
+	basePage
+	PackagePath string
+
+	// From and To are the versions being compared.
+	From, To *APIDiffVersion
+
+	// SameMajor reports whether From and To have the same major version, so
+	// that removed and changed identifiers are incompatible changes.
+	SameMajor bool
+
+	*APIDiff
+}
+
+// APIDiffVersion is one of the versions compared on the API diff page.
+type APIDiffVersion struct {
+	DisplayVersion string
+	// URL is the URL of the package documentation at this version.
+	URL string
+	// Recorded reports whether the exported identifiers of the package were
+	// recorded when this version was processed. Versions processed before
+	// they were recorded cannot be compared.
+	Recorded bool
+}
+
+// APIDiff describes the differences between the exported APIs of two versions
+// of a package.
+type APIDiff struct {
+	Added, Removed, Changed []*SymbolChange
+}
+
+// A SymbolChange describes an exported identifier that was added, removed or
+// changed between two versions of a package.
+type SymbolChange struct {
+	// ID is the identifier, as used for its anchor on the documentation
+	// page: "F" for a function or type, "T.M" for a method.
+	ID   string
+	Kind internal.SymbolKind
+
+	// OldSignature and NewSignature are the signatures of the identifier in
+	// the two versions. OldSignature is empty for added identifiers, and
+	// NewSignature is empty for removed ones.
+	OldSignature, NewSignature string
+
+	// Link is the URL of the documentation of the identifier in the newer
+	// version, or for removed identifiers, the older one.
+	Link string
+
+	// Incompatible reports whether the change can break code that uses the
+	// identifier even though the major version is the same.
+	Incompatible bool
+}
+
+// serveAPIDiff serves a page that compares the exported APIs of two versions
+// of a package. It expects paths of the form
+// "/<package-path>@<version>...<version>". The versions are read from ds.
+func (s *Server) serveAPIDiff(w http.ResponseWriter, r *http.Request, ds internal.DataSource) error {
+	pkgPath, fromVersion, toVersion, err := parseAPIDiffURLPath(r.URL.Path)
+	if err != nil {
+		return &serverError{
+			status: http.StatusBadRequest,
+			err:    err,
+		}
+	}
+	db, ok := ds.(*postgres.DB)
+	if !ok {
+		// The proxydatasource does not record the API of each version.
+		return proxydatasourceNotSupportedErr()
+	}
+	ctx := r.Context()
+	if err := validatePathAndVersion(ctx, ds, pkgPath, fromVersion); err != nil {
+		return err
+	}
+	from, fromSymbols, err := fetchAPIDiffVersion(ctx, db, pkgPath, fromVersion)
+	if err != nil {
+		return err
+	}
+	to, toSymbols, err := fetchAPIDiffVersion(ctx, db, pkgPath, toVersion)
+	if err != nil {
+		return err
+	}
+	major := semver.Major(fromVersion)
+	sameMajor := major == semver.Major(toVersion) && major != "v0"
+	page := &APIDiffPage{
+		basePage:    s.newBasePage(r, fmt.Sprintf("%s %s...%s", pkgPath, from.DisplayVersion, to.DisplayVersion)),
+		PackagePath: pkgPath,
+		From:        from,
+		To:          to,
+		SameMajor:   sameMajor,
+		APIDiff:     &APIDiff{},
+	}
+	if from.Recorded && to.Recorded {
+		page.APIDiff = diffAPI(fromSymbols, toSymbols, from.URL, to.URL, sameMajor)
+	}
+	s.servePage(ctx, w, "pkg_api_diff.tmpl", page)
+	return nil
+}
+
+// isAPIDiffURLPath reports whether urlPath is the path of an API diff page.
+func isAPIDiffURLPath(urlPath string) bool {
+	parts := strings.SplitN(urlPath, "@", 2)
+	return len(parts) == 2 && strings.Contains(parts[1], apiDiffSeparator) && !strings.Contains(parts[1], "/")
+}
+
+// parseAPIDiffURLPath parses the path of an API diff page into a package path
+// and two versions. Versions of standard library packages are given as Go
+// tags, like "go1.15", and are returned as semantic versions.
+func parseAPIDiffURLPath(urlPath string) (pkgPath, fromVersion, toVersion string, err error) {
+	defer derrors.Wrap(&err, "parseAPIDiffURLPath(%q)", urlPath)
+
+	parts := strings.SplitN(strings.Trim(urlPath, "/"), "@", 2)
+	if len(parts) != 2 {
+		return "", "", "", errors.New("missing versions")
+	}
+	pkgPath = parts[0]
+	if err := module.CheckImportPath(pkgPath); err != nil {
+		return "", "", "", fmt.Errorf("malformed path %q: %v", pkgPath, err)
+	}
+	versions := strings.Split(parts[1], apiDiffSeparator)
+	if len(versions) != 2 {
+		return "", "", "", fmt.Errorf("want two versions separated by %q", apiDiffSeparator)
+	}
+	for i, v := range versions {
+		if stdlib.Contains(pkgPath) {
+			v = stdlib.VersionForTag(v)
+		}
+		if !semver.IsValid(v) {
+			return "", "", "", fmt.Errorf("invalid version: %q", versions[i])
+		}
+		versions[i] = v
+	}
+	return pkgPath, versions[0], versions[1], nil
+}
+
+// fetchAPIDiffVersion returns the APIDiffVersion and the exported identifiers
+// of the package with pkgPath at the given version. The module of the package
+// is determined from the paths table. If the identifiers were not recorded,
+// the Recorded field of the APIDiffVersion is false.
+func fetchAPIDiffVersion(ctx context.Context, db *postgres.DB, pkgPath, version string) (_ *APIDiffVersion, _ []*internal.Symbol, err error) {
+	defer derrors.Wrap(&err, "fetchAPIDiffVersion(ctx, db, %q, %q)", pkgPath, version)
+
+	modulePath := internal.UnknownModulePath
+	if stdlib.Contains(pkgPath) {
+		modulePath = stdlib.ModulePath
+	}
+	modulePath, _, isPackage, err := db.GetPathInfo(ctx, pkgPath, modulePath, version)
+	if err != nil && !errors.Is(err, derrors.NotFound) {
+		return nil, nil, err
+	}
+	if err != nil || !isPackage {
+		return nil, nil, &serverError{
+			status: http.StatusNotFound,
+			epage: &errorPage{
+				messageTemplate: template.MakeTrustedTemplate(`
+					<h3 class="Error-message">Package {{.Path}}@{{.Version}} is not available.</h3>
+					<p class="Error-message">
+					  To view the versions of this package, <a href="/{{.Path}}?tab=versions">click here</a>.
+					</p>`),
+				MessageData: struct{ Path, Version string }{pkgPath, displayVersion(version, modulePath)},
+			},
+		}
+	}
+	v := &APIDiffVersion{
+		DisplayVersion: displayVersion(version, modulePath),
+		URL:            constructPackageURL(pkgPath, modulePath, linkVersion(version, modulePath)),
+	}
+	symbols, err := db.GetSymbols(ctx, pkgPath, modulePath, version)
+	if errors.Is(err, derrors.NotFound) {
+		return v, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	v.Recorded = true
+	return v, symbols, nil
+}
+
+// diffAPI compares the exported identifiers of two versions of a package,
+// whose documentation is at fromURL and toURL. If sameMajor is true, removed
+// and changed identifiers, and methods added to interface types, are marked
+// as incompatible.
+//
+// The fields of struct types and the methods of interface types are compared
+// one by one, so that changes to them are reported even though the synopsis
+// of their type is the same.
+//
+// Identifiers whose signature is not known in one of the versions, because
+// the package is not redistributable, are not reported as changed.
+func diffAPI(from, to []*internal.Symbol, fromURL, toURL string, sameMajor bool) *APIDiff {
+	old := map[string]*internal.Symbol{}
+	for _, s := range from {
+		old[s.Anchor()] = s
+	}
+	interfaces := map[string]bool{}
+	for _, s := range to {
+		if s.Kind == internal.SymbolKindType && strings.HasPrefix(s.Synopsis, "type "+s.Name+" interface") {
+			interfaces[s.Name] = true
+		}
+	}
+	d := &APIDiff{}
+	for _, s := range to {
+		id := s.Anchor()
+		o, ok := old[id]
+		delete(old, id)
+		switch {
+		case !ok:
+			d.Added = append(d.Added, &SymbolChange{
+				ID:           id,
+				Kind:         s.Kind,
+				NewSignature: s.Synopsis,
+				Link:         toURL + "#" + id,
+				// Code that implements the interface does not have the
+				// added method.
+				Incompatible: sameMajor && interfaces[s.ParentName],
+			})
+		case o.Synopsis != s.Synopsis && o.Synopsis != "" && s.Synopsis != "":
+			d.Changed = append(d.Changed, &SymbolChange{
+				ID:           id,
+				Kind:         s.Kind,
+				OldSignature: o.Synopsis,
+				NewSignature: s.Synopsis,
+				Link:         toURL + "#" + id,
+				Incompatible: sameMajor,
+			})
+		}
+	}
+	for _, s := range from {
+		if _, ok := old[s.Anchor()]; !ok {
+			continue
+		}
+		d.Removed = append(d.Removed, &SymbolChange{
+			ID:           s.Anchor(),
+			Kind:         s.Kind,
+			OldSignature: s.Synopsis,
+			Link:         fromURL + "#" + s.Anchor(),
+			Incompatible: sameMajor,
+		})
+	}
+	return d
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+)
+
+func TestParseAPIDiffURLPath(t *testing.T) {
+	for _, test := range []struct {
+		path, wantPkgPath, wantFrom, wantTo string
+		wantErr                             bool
+	}{
+		{"/github.com/a/b@v1.3.0...v1.5.2", "github.com/a/b", "v1.3.0", "v1.5.2", false},
+		{"/github.com/a/b/c@v1.3.0...v2.0.0-pre", "github.com/a/b/c", "v1.3.0", "v2.0.0-pre", false},
+		{"/net/http@go1.13...go1.15.2", "net/http", "v1.13.0", "v1.15.2", false},
+		{"/github.com/a/b@v1.3.0", "", "", "", true},
+		{"/github.com/a/b@v1.3.0...", "", "", "", true},
+		{"/github.com/a/b@v1.3.0...v1.4.0...v1.5.0", "", "", "", true},
+		{"/github.com/a/b@v1.3...latest", "", "", "", true},
+	} {
+		pkgPath, from, to, err := parseAPIDiffURLPath(test.path)
+		if (err != nil) != test.wantErr {
+			t.Errorf("parseAPIDiffURLPath(%q): got error %v, want error: %t", test.path, err, test.wantErr)
+			continue
+		}
+		if pkgPath != test.wantPkgPath || from != test.wantFrom || to != test.wantTo {
+			t.Errorf("parseAPIDiffURLPath(%q) = %q, %q, %q; want %q, %q, %q",
+				test.path, pkgPath, from, to, test.wantPkgPath, test.wantFrom, test.wantTo)
+		}
+	}
+}
+
+func TestIsAPIDiffURLPath(t *testing.T) {
+	for _, test := range []struct {
+		path string
+		want bool
+	}{
+		{"/github.com/a/b@v1.3.0...v1.5.2", true},
+		{"/github.com/a/b@v1.3.0", false},
+		{"/github.com/a/b@v1.3.0/c...d", false},
+		{"/github.com/a/b", false},
+	} {
+		if got := isAPIDiffURLPath(test.path); got != test.want {
+			t.Errorf("isAPIDiffURLPath(%q) = %t, want %t", test.path, got, test.want)
+		}
+	}
+}
+
+func TestDiffAPI(t *testing.T) {
+	from := []*internal.Symbol{
+		{Name: "Client", Kind: internal.SymbolKindType, Synopsis: "type Client struct"},
+		{Name: "Do", ParentName: "Client", Kind: internal.SymbolKindMethod, Synopsis: "func (*Client) Do()"},
+		{Name: "Get", Kind: internal.SymbolKindFunction, Synopsis: "func Get(url string)"},
+		{Name: "Old", Kind: internal.SymbolKindFunction, Synopsis: "func Old()"},
+		{Name: "Hidden", Kind: internal.SymbolKindFunction, Synopsis: ""},
+		{Name: "Options", Kind: internal.SymbolKindType, Synopsis: "type Options struct{ ... }"},
+		{Name: "Timeout", Kind: internal.SymbolKindField, ParentName: "Options", Synopsis: "Timeout int"},
+		{Name: "Doer", Kind: internal.SymbolKindType, Synopsis: "type Doer interface{ ... }"},
+		{Name: "Do", Kind: internal.SymbolKindMethod, ParentName: "Doer", Synopsis: "Do()"},
+	}
+	to := []*internal.Symbol{
+		{Name: "Client", Kind: internal.SymbolKindType, Synopsis: "type Client struct"},
+		{Name: "Do", ParentName: "Client", Kind: internal.SymbolKindMethod, Synopsis: "func (*Client) Do(ctx context.Context)"},
+		{Name: "Get", Kind: internal.SymbolKindFunction, Synopsis: "func Get(url string)"},
+		{Name: "New", Kind: internal.SymbolKindFunction, Synopsis: "func New()"},
+		{Name: "Hidden", Kind: internal.SymbolKindFunction, Synopsis: "func Hidden()"},
+		{Name: "Options", Kind: internal.SymbolKindType, Synopsis: "type Options struct{ ... }"},
+		{Name: "Timeout", Kind: internal.SymbolKindField, ParentName: "Options", Synopsis: "Timeout time.Duration"},
+		{Name: "Retries", Kind: internal.SymbolKindField, ParentName: "Options", Synopsis: "Retries int"},
+		{Name: "Doer", Kind: internal.SymbolKindType, Synopsis: "type Doer interface{ ... }"},
+		{Name: "Do", Kind: internal.SymbolKindMethod, ParentName: "Doer", Synopsis: "Do()"},
+		{Name: "Close", Kind: internal.SymbolKindMethod, ParentName: "Doer", Synopsis: "Close() error"},
+	}
+	const (
+		fromURL = "/example.com/m@v1.0.0"
+		toURL   = "/example.com/m@v1.1.0"
+	)
+	for _, sameMajor := range []bool{true, false} {
+		got := diffAPI(from, to, fromURL, toURL, sameMajor)
+		want := &APIDiff{
+			Added: []*SymbolChange{
+				{ID: "New", Kind: internal.SymbolKindFunction, NewSignature: "func New()", Link: toURL + "#New"},
+				{ID: "Options.Retries", Kind: internal.SymbolKindField, NewSignature: "Retries int", Link: toURL + "#Options.Retries"},
+				{ID: "Doer.Close", Kind: internal.SymbolKindMethod, NewSignature: "Close() error", Link: toURL + "#Doer.Close", Incompatible: sameMajor},
+			},
+			Removed: []*SymbolChange{
+				{ID: "Old", Kind: internal.SymbolKindFunction, OldSignature: "func Old()", Link: fromURL + "#Old", Incompatible: sameMajor},
+			},
+			Changed: []*SymbolChange{
+				{
+					ID:           "Client.Do",
+					Kind:         internal.SymbolKindMethod,
+					OldSignature: "func (*Client) Do()",
+					NewSignature: "func (*Client) Do(ctx context.Context)",
+					Link:         toURL + "#Client.Do",
+					Incompatible: sameMajor,
+				},
+				{
+					ID:           "Options.Timeout",
+					Kind:         internal.SymbolKindField,
+					OldSignature: "Timeout int",
+					NewSignature: "Timeout time.Duration",
+					Link:         toURL + "#Options.Timeout",
+					Incompatible: sameMajor,
+				},
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("sameMajor=%t: mismatch (-want +got):\n%s", sameMajor, diff)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/postgres"
+)
+
+// DependenciesDetails contains the go.mod directives of a module version that
+// refer to other modules.
+type DependenciesDetails struct {
+	ModulePath string
+
+	// Requires and IndirectRequires are the modules that the module
+	// requires. IndirectRequires are those marked "// indirect".
+	Requires         []*internal.ModuleDependency
+	IndirectRequires []*internal.ModuleDependency
+
+	Replaces []*internal.ModuleDependency
+	Excludes []*internal.ModuleDependency
+}
+
+// fetchDependenciesDetails fetches the go.mod directives of the module version
+// specified by modulePath and version and returns a DependenciesDetails.
+func fetchDependenciesDetails(ctx context.Context, db *postgres.DB, modulePath, version string) (*DependenciesDetails, error) {
+	deps, err := db.GetModuleDependencies(ctx, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	dd := &DependenciesDetails{ModulePath: modulePath}
+	for _, d := range deps {
+		switch {
+		case d.Kind == internal.DependencyRequire && d.Indirect:
+			dd.IndirectRequires = append(dd.IndirectRequires, d)
+		case d.Kind == internal.DependencyRequire:
+			dd.Requires = append(dd.Requires, d)
+		case d.Kind == internal.DependencyReplace:
+			dd.Replaces = append(dd.Replaces, d)
+		case d.Kind == internal.DependencyExclude:
+			dd.Excludes = append(dd.Excludes, d)
+		}
+	}
+	return dd, nil
+}
+
+// DependentsDetails contains the modules that require a given module.
+type DependentsDetails struct {
+	ModulePath string
+
+	// Dependents are the modules whose latest version requires ModulePath,
+	// along with the version of ModulePath that they require.
+	Dependents []*internal.ModuleDependent
+
+	// TotalIsExact is false if there were more than dependentsLimit
+	// dependents, and only the first dependentsLimit are shown.
+	TotalIsExact bool
+}
+
+// dependentsLimit is the maximum number of dependents shown on the dependents
+// tab.
+const dependentsLimit = 1000
+
+// fetchDependentsDetails fetches the modules that require modulePath and
+// returns a DependentsDetails.
+func fetchDependentsDetails(ctx context.Context, db *postgres.DB, modulePath string) (*DependentsDetails, error) {
+	// Ask for one more than the limit, to find out whether there are more.
+	dependents, err := db.GetModuleDependents(ctx, modulePath, dependentsLimit+1)
+	if err != nil {
+		return nil, err
+	}
+	totalIsExact := true
+	if len(dependents) > dependentsLimit {
+		dependents = dependents[:dependentsLimit]
+		totalIsExact = false
+	}
+	return &DependentsDetails{
+		ModulePath:   modulePath,
+		Dependents:   dependents,
+		TotalIsExact: totalIsExact,
+	}, nil
+}
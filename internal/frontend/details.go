@@ -42,7 +42,11 @@ type DetailsPage struct {
 // expects paths of the form "[/mod]/<module-path>[@<version>?tab=<tab>]".
 // stdlib module pages are handled at "/std", and requests to "/mod/std" will
 // be redirected to that path.
-func (s *Server) serveDetails(w http.ResponseWriter, r *http.Request) (err error) {
+//
+// The pages are served from ds. urlPrefix is prepended to the links between
+// them: it is empty, except for the modules uploaded for preview, whose pages
+// are under "/preview/<id>".
+func (s *Server) serveDetails(w http.ResponseWriter, r *http.Request, ds internal.DataSource, urlPrefix string) (err error) {
 	if r.Method != http.MethodGet {
 		return &serverError{status: http.StatusMethodNotAllowed}
 	}
@@ -61,6 +65,9 @@ func (s *Server) serveDetails(w http.ResponseWriter, r *http.Request) (err error
 		http.Redirect(w, r, "/std", http.StatusMovedPermanently)
 		return nil
 	}
+	if isAPIDiffURLPath(r.URL.Path) {
+		return s.serveAPIDiff(w, r, ds)
+	}
 
 	urlInfo, err := extractURLPathInfo(r.URL.Path)
 	if err != nil {
@@ -71,7 +78,7 @@ func (s *Server) serveDetails(w http.ResponseWriter, r *http.Request) (err error
 	}
 	ctx := r.Context()
 	// Validate the fullPath and requestedVersion that were parsed.
-	if err := validatePathAndVersion(ctx, s.ds, urlInfo.fullPath, urlInfo.requestedVersion); err != nil {
+	if err := validatePathAndVersion(ctx, ds, urlInfo.fullPath, urlInfo.requestedVersion); err != nil {
 		return err
 	}
 	var (
@@ -79,7 +86,7 @@ func (s *Server) serveDetails(w http.ResponseWriter, r *http.Request) (err error
 		resolvedVersion    = urlInfo.requestedVersion
 	)
 	if experiment.IsActive(ctx, internal.ExperimentUsePathInfo) {
-		resolvedModulePath, resolvedVersion, _, err = s.ds.GetPathInfo(ctx, urlInfo.fullPath, urlInfo.modulePath, urlInfo.requestedVersion)
+		resolvedModulePath, resolvedVersion, _, err = ds.GetPathInfo(ctx, urlInfo.fullPath, urlInfo.modulePath, urlInfo.requestedVersion)
 		if err != nil {
 			if !errors.Is(err, derrors.NotFound) {
 				return err
@@ -88,7 +95,7 @@ func (s *Server) serveDetails(w http.ResponseWriter, r *http.Request) (err error
 			if urlInfo.isModule {
 				pathType = "module"
 			}
-			return s.servePathNotFoundPage(w, r, urlInfo.fullPath, urlInfo.modulePath, urlInfo.requestedVersion, pathType)
+			return s.servePathNotFoundPage(w, r, ds, urlInfo.fullPath, urlInfo.modulePath, urlInfo.requestedVersion, pathType)
 		}
 	}
 	if isActivePathAtMaster(ctx) && urlInfo.requestedVersion == internal.MasterVersion {
@@ -104,12 +111,12 @@ func (s *Server) serveDetails(w http.ResponseWriter, r *http.Request) (err error
 	}
 	// Depending on what the request was for, return the module or package page.
 	if urlInfo.isModule || urlInfo.fullPath == stdlib.ModulePath {
-		return s.legacyServeModulePage(w, r, urlInfo.fullPath, urlInfo.requestedVersion, resolvedVersion)
+		return s.legacyServeModulePage(w, r, ds, urlPrefix, urlInfo.fullPath, urlInfo.requestedVersion, resolvedVersion)
 	}
 	if isActiveUseDirectories(ctx) {
-		return s.servePackagePageNew(w, r, urlInfo.fullPath, resolvedModulePath, urlInfo.requestedVersion, resolvedVersion)
+		return s.servePackagePageNew(w, r, ds, urlPrefix, urlInfo.fullPath, resolvedModulePath, urlInfo.requestedVersion, resolvedVersion)
 	}
-	return s.legacyServePackagePage(w, r, urlInfo.fullPath, resolvedModulePath, urlInfo.requestedVersion, resolvedVersion)
+	return s.legacyServePackagePage(w, r, ds, urlPrefix, urlInfo.fullPath, resolvedModulePath, urlInfo.requestedVersion, resolvedVersion)
 }
 
 type urlPathInfo struct {
@@ -373,7 +380,7 @@ func parseStdLibURLPath(urlPath string) (path, requestedVersion string, err erro
 	return path, requestedVersion, nil
 }
 
-func (s *Server) servePathNotFoundPage(w http.ResponseWriter, r *http.Request, fullPath, modulePath, requestedVersion, pathType string) (err error) {
+func (s *Server) servePathNotFoundPage(w http.ResponseWriter, r *http.Request, ds internal.DataSource, fullPath, modulePath, requestedVersion, pathType string) (err error) {
 	defer derrors.Wrap(&err, "servePathNotFoundPage(w, r, %q, %q)", fullPath, requestedVersion)
 
 	ctx := r.Context()
@@ -399,7 +406,7 @@ func (s *Server) servePathNotFoundPage(w http.ResponseWriter, r *http.Request, f
 	// If frontend fetch is not enabled and we couldn't find a path at the
 	// given version, but if there's one at the latest version we can provide a
 	// link to it.
-	if _, _, _, err := s.ds.GetPathInfo(ctx, fullPath, modulePath, internal.LatestVersion); err != nil {
+	if _, _, _, err := ds.GetPathInfo(ctx, fullPath, modulePath, internal.LatestVersion); err != nil {
 		if errors.Is(err, derrors.NotFound) {
 			return pathNotFoundError(ctx, pathType, fullPath, requestedVersion)
 		}
@@ -32,7 +32,7 @@ type Directory struct {
 	URL      string
 }
 
-func (s *Server) legacyServeDirectoryPage(ctx context.Context, w http.ResponseWriter, r *http.Request, dbDir *internal.LegacyDirectory, requestedVersion string) (err error) {
+func (s *Server) legacyServeDirectoryPage(ctx context.Context, w http.ResponseWriter, r *http.Request, ds internal.DataSource, urlPrefix string, dbDir *internal.LegacyDirectory, requestedVersion string) (err error) {
 	defer derrors.Wrap(&err, "legacyServeDirectoryPage for %s@%s", dbDir.Path, requestedVersion)
 	tab := r.FormValue("tab")
 	settings, ok := directoryTabLookup[tab]
@@ -40,19 +40,19 @@ func (s *Server) legacyServeDirectoryPage(ctx context.Context, w http.ResponseWr
 		tab = "subdirectories"
 		settings = directoryTabLookup[tab]
 	}
-	licenses, err := s.ds.LegacyGetModuleLicenses(ctx, dbDir.ModulePath, dbDir.Version)
+	licenses, err := ds.LegacyGetModuleLicenses(ctx, dbDir.ModulePath, dbDir.Version)
 	if err != nil {
 		return err
 	}
-	header, err := legacyCreateDirectory(dbDir, licensesToMetadatas(licenses), false)
+	header, err := legacyCreateDirectory(dbDir, licensesToMetadatas(licenses), false, urlPrefix)
 	if err != nil {
 		return err
 	}
 	if requestedVersion == internal.LatestVersion {
-		header.URL = constructDirectoryURL(dbDir.Path, dbDir.ModulePath, internal.LatestVersion)
+		header.URL = urlPrefix + constructDirectoryURL(dbDir.Path, dbDir.ModulePath, internal.LatestVersion)
 	}
 
-	details, err := constructDetailsForDirectory(r, tab, dbDir, licenses)
+	details, err := constructDetailsForDirectory(r, tab, dbDir, licenses, urlPrefix)
 	if err != nil {
 		return err
 	}
@@ -61,7 +61,7 @@ func (s *Server) legacyServeDirectoryPage(ctx context.Context, w http.ResponseWr
 		Title:          fmt.Sprintf("directory %s", dbDir.Path),
 		Settings:       settings,
 		Header:         header,
-		Breadcrumb:     breadcrumbPath(dbDir.Path, dbDir.ModulePath, linkVersion(dbDir.Version, dbDir.ModulePath)),
+		Breadcrumb:     breadcrumbPath(dbDir.Path, dbDir.ModulePath, linkVersion(dbDir.Version, dbDir.ModulePath), urlPrefix),
 		Details:        details,
 		CanShowDetails: true,
 		Tabs:           directoryTabSettings,
@@ -81,7 +81,7 @@ func (s *Server) legacyServeDirectoryPage(ctx context.Context, w http.ResponseWr
 // the module path. However, on the package and directory view's
 // "Subdirectories" tab, we do not want to include packages whose import paths
 // are the same as the dirPath.
-func fetchDirectoryDetails(ctx context.Context, ds internal.DataSource, dirPath string, mi *internal.ModuleInfo,
+func fetchDirectoryDetails(ctx context.Context, ds internal.DataSource, urlPrefix, dirPath string, mi *internal.ModuleInfo,
 	licmetas []*licenses.Metadata, includeDirPath bool) (_ *Directory, err error) {
 	defer derrors.Wrap(&err, "s.ds.fetchDirectoryDetails(%q, %q, %q, %v)", dirPath, mi.ModulePath, mi.Version, licmetas)
 
@@ -98,7 +98,7 @@ func fetchDirectoryDetails(ctx context.Context, ds internal.DataSource, dirPath
 			LegacyModuleInfo: internal.LegacyModuleInfo{ModuleInfo: *mi},
 			Path:             dirPath,
 			Packages:         pkgs,
-		}, licmetas, includeDirPath)
+		}, licmetas, includeDirPath, urlPrefix)
 	}
 
 	dbDir, err := ds.LegacyGetDirectory(ctx, dirPath, mi.ModulePath, mi.Version, internal.AllFields)
@@ -107,12 +107,12 @@ func fetchDirectoryDetails(ctx context.Context, ds internal.DataSource, dirPath
 			LegacyModuleInfo: internal.LegacyModuleInfo{ModuleInfo: *mi},
 			Path:             dirPath,
 			Packages:         nil,
-		}, licmetas, includeDirPath)
+		}, licmetas, includeDirPath, urlPrefix)
 	}
 	if err != nil {
 		return nil, err
 	}
-	return legacyCreateDirectory(dbDir, licmetas, includeDirPath)
+	return legacyCreateDirectory(dbDir, licmetas, includeDirPath, urlPrefix)
 }
 
 // legacyCreateDirectory constructs a *LegacyDirectory from the provided dbDir and licmetas.
@@ -124,7 +124,9 @@ func fetchDirectoryDetails(ctx context.Context, ds internal.DataSource, dirPath
 // the module path. However, on the package and directory view's
 // "Subdirectories" tab, we do not want to include packages whose import paths
 // are the same as the dirPath.
-func legacyCreateDirectory(dbDir *internal.LegacyDirectory, licmetas []*licenses.Metadata, includeDirPath bool) (_ *Directory, err error) {
+//
+// urlPrefix is prepended to the URLs of the directory and its packages.
+func legacyCreateDirectory(dbDir *internal.LegacyDirectory, licmetas []*licenses.Metadata, includeDirPath bool, urlPrefix string) (_ *Directory, err error) {
 	defer derrors.Wrap(&err, "legacyCreateDirectory(%q, %q, %t)", dbDir.Path, dbDir.Version, includeDirPath)
 
 	var packages []*Package
@@ -132,7 +134,7 @@ func legacyCreateDirectory(dbDir *internal.LegacyDirectory, licmetas []*licenses
 		if !includeDirPath && pkg.Path == dbDir.Path {
 			continue
 		}
-		newPkg, err := legacyCreatePackage(pkg, &dbDir.ModuleInfo, false)
+		newPkg, err := legacyCreatePackage(pkg, &dbDir.ModuleInfo, false, urlPrefix)
 		if err != nil {
 			return nil, err
 		}
@@ -145,14 +147,14 @@ func legacyCreateDirectory(dbDir *internal.LegacyDirectory, licmetas []*licenses
 		}
 		packages = append(packages, newPkg)
 	}
-	mod := createModule(&dbDir.ModuleInfo, licmetas, false)
+	mod := createModule(&dbDir.ModuleInfo, licmetas, false, urlPrefix)
 	sort.Slice(packages, func(i, j int) bool { return packages[i].Path < packages[j].Path })
 
 	return &Directory{
 		Module:   *mod,
 		Path:     dbDir.Path,
 		Packages: packages,
-		URL:      constructDirectoryURL(dbDir.Path, dbDir.ModulePath, linkVersion(dbDir.Version, dbDir.ModulePath)),
+		URL:      urlPrefix + constructDirectoryURL(dbDir.Path, dbDir.ModulePath, linkVersion(dbDir.Version, dbDir.ModulePath)),
 	}, nil
 }
 
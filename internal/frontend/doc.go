@@ -7,6 +7,8 @@ package frontend
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/google/safehtml"
@@ -20,24 +22,66 @@ type DocumentationDetails struct {
 	GOOS          string
 	GOARCH        string
 	Documentation safehtml.HTML
+
+	// BuildContexts links to the documentation for each build context in which
+	// the package has distinct documentation. It is empty if the documentation
+	// is the same for all build contexts.
+	BuildContexts []*BuildContextLink
+}
+
+// BuildContextLink is a link to the documentation of a package for a
+// build context.
+type BuildContextLink struct {
+	Name     string // GOOS/GOARCH
+	URL      string
+	Selected bool
 }
 
 // fetchDocumentationDetails returns a DocumentationDetails constructed from pkg.
-func fetchDocumentationDetails(pkg *internal.LegacyVersionedPackage) *DocumentationDetails {
-	return &DocumentationDetails{
-		GOOS:          pkg.GOOS,
-		GOARCH:        pkg.GOARCH,
-		Documentation: pkg.DocumentationHTML,
-	}
+func fetchDocumentationDetails(r *http.Request, pkg *internal.LegacyVersionedPackage) *DocumentationDetails {
+	docs := append([]*internal.Documentation{{
+		GOOS:   pkg.GOOS,
+		GOARCH: pkg.GOARCH,
+		HTML:   pkg.DocumentationHTML,
+	}}, pkg.OtherDocumentation...)
+	return documentationDetails(r, docs)
 }
 
-// fetchDocumentationDetails returnsNew a DocumentationDetails constructed from doc.
-func fetchDocumentationDetailsNew(doc *internal.Documentation) *DocumentationDetails {
-	return &DocumentationDetails{
-		GOOS:          doc.GOOS,
-		GOARCH:        doc.GOARCH,
-		Documentation: doc.HTML,
+// fetchDocumentationDetailsNew returns a DocumentationDetails constructed from pkg.
+func fetchDocumentationDetailsNew(r *http.Request, pkg *internal.PackageNew) *DocumentationDetails {
+	docs := append([]*internal.Documentation{pkg.Documentation}, pkg.OtherDocumentation...)
+	return documentationDetails(r, docs)
+}
+
+// documentationDetails returns a DocumentationDetails for the documentation in
+// docs that matches the GOOS and GOARCH query parameters of r. If there is no
+// match, the first element of docs, which is the default documentation, is
+// used.
+func documentationDetails(r *http.Request, docs []*internal.Documentation) *DocumentationDetails {
+	goos := r.FormValue("GOOS")
+	goarch := r.FormValue("GOARCH")
+	selected := docs[0]
+	for _, doc := range docs {
+		if doc.GOOS == goos && doc.GOARCH == goarch {
+			selected = doc
+			break
+		}
+	}
+	dd := &DocumentationDetails{
+		GOOS:          selected.GOOS,
+		GOARCH:        selected.GOARCH,
+		Documentation: selected.HTML,
+	}
+	if len(docs) > 1 {
+		for _, doc := range docs {
+			dd.BuildContexts = append(dd.BuildContexts, &BuildContextLink{
+				Name:     internal.BuildContext{GOOS: doc.GOOS, GOARCH: doc.GOARCH}.String(),
+				URL:      fmt.Sprintf("?tab=doc&GOOS=%s&GOARCH=%s", url.QueryEscape(doc.GOOS), url.QueryEscape(doc.GOARCH)),
+				Selected: doc == selected,
+			})
+		}
 	}
+	return dd
 }
 
 // fileSource returns the original filepath in the module zip where the given
@@ -6,8 +6,12 @@ package frontend
 
 import (
 	"fmt"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/safehtml"
+	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/stdlib"
 	"golang.org/x/pkgsite/internal/testing/sample"
 )
@@ -42,3 +46,61 @@ func TestFileSource(t *testing.T) {
 		})
 	}
 }
+
+func TestDocumentationDetails(t *testing.T) {
+	linuxDoc := &internal.Documentation{GOOS: "linux", GOARCH: "amd64", HTML: safehtml.HTMLEscaped("linux")}
+	windowsDoc := &internal.Documentation{GOOS: "windows", GOARCH: "amd64", HTML: safehtml.HTMLEscaped("windows")}
+	for _, tc := range []struct {
+		name, url string
+		docs      []*internal.Documentation
+		want      *DocumentationDetails
+	}{
+		{
+			name: "single build context",
+			url:  "/a.com/p?tab=doc",
+			docs: []*internal.Documentation{linuxDoc},
+			want: &DocumentationDetails{GOOS: "linux", GOARCH: "amd64", Documentation: linuxDoc.HTML},
+		},
+		{
+			name: "default build context",
+			url:  "/a.com/p?tab=doc",
+			docs: []*internal.Documentation{linuxDoc, windowsDoc},
+			want: &DocumentationDetails{
+				GOOS:          "linux",
+				GOARCH:        "amd64",
+				Documentation: linuxDoc.HTML,
+				BuildContexts: []*BuildContextLink{
+					{Name: "linux/amd64", URL: "?tab=doc&GOOS=linux&GOARCH=amd64", Selected: true},
+					{Name: "windows/amd64", URL: "?tab=doc&GOOS=windows&GOARCH=amd64"},
+				},
+			},
+		},
+		{
+			name: "selected build context",
+			url:  "/a.com/p?tab=doc&GOOS=windows&GOARCH=amd64",
+			docs: []*internal.Documentation{linuxDoc, windowsDoc},
+			want: &DocumentationDetails{
+				GOOS:          "windows",
+				GOARCH:        "amd64",
+				Documentation: windowsDoc.HTML,
+				BuildContexts: []*BuildContextLink{
+					{Name: "linux/amd64", URL: "?tab=doc&GOOS=linux&GOARCH=amd64"},
+					{Name: "windows/amd64", URL: "?tab=doc&GOOS=windows&GOARCH=amd64", Selected: true},
+				},
+			},
+		},
+		{
+			name: "unknown build context",
+			url:  "/a.com/p?tab=doc&GOOS=plan9&GOARCH=386",
+			docs: []*internal.Documentation{linuxDoc},
+			want: &DocumentationDetails{GOOS: "linux", GOARCH: "amd64", Documentation: linuxDoc.HTML},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := documentationDetails(httptest.NewRequest("GET", tc.url, nil), tc.docs)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(safehtml.HTML{})); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -28,6 +28,10 @@ type Package struct {
 	URL                string // relative to this site
 	LatestURL          string // link with latest-version placeholder, relative to this site
 	Licenses           []LicenseMetadata
+
+	// UsesCgo and GoReleaseTags are described in internal.PackageNew.
+	UsesCgo       bool
+	GoReleaseTags []string
 }
 
 // Module contains information for an individual module.
@@ -40,6 +44,15 @@ type Module struct {
 	URL               string // relative to this site
 	LatestURL         string // link with latest-version placeholder, relative to this site
 	Licenses          []LicenseMetadata
+
+	// Retracted, RetractionRationale and Deprecation are described in
+	// internal.ModuleInfo.
+	Retracted           bool
+	RetractionRationale string
+	Deprecation         string
+	// GoVersion is the version of the go directive in the module's go.mod
+	// file, if any.
+	GoVersion string
 }
 
 // legacyCreatePackage returns a *Package based on the fields of the specified
@@ -47,8 +60,9 @@ type Module struct {
 //
 // latestRequested indicates whether the user requested the latest
 // version of the package. If so, the returned Package.URL will have the
-// structure /<path> instead of /<path>@<version>.
-func legacyCreatePackage(pkg *internal.LegacyPackage, mi *internal.ModuleInfo, latestRequested bool) (_ *Package, err error) {
+// structure /<path> instead of /<path>@<version>. urlPrefix is prepended to
+// the URLs; see serveDetails.
+func legacyCreatePackage(pkg *internal.LegacyPackage, mi *internal.ModuleInfo, latestRequested bool, urlPrefix string) (_ *Package, err error) {
 	defer derrors.Wrap(&err, "legacyCreatePackage(%v, %v)", pkg, mi)
 
 	if pkg == nil || mi == nil {
@@ -62,7 +76,7 @@ func legacyCreatePackage(pkg *internal.LegacyPackage, mi *internal.ModuleInfo, l
 		}
 	}
 
-	m := createModule(mi, modLicenses, latestRequested)
+	m := createModule(mi, modLicenses, latestRequested, urlPrefix)
 	urlVersion := m.LinkVersion
 	if latestRequested {
 		urlVersion = internal.LatestVersion
@@ -73,8 +87,10 @@ func legacyCreatePackage(pkg *internal.LegacyPackage, mi *internal.ModuleInfo, l
 		IsRedistributable: pkg.IsRedistributable,
 		Licenses:          transformLicenseMetadata(pkg.Licenses),
 		Module:            *m,
-		URL:               constructPackageURL(pkg.Path, mi.ModulePath, urlVersion),
-		LatestURL:         constructPackageURL(pkg.Path, mi.ModulePath, middleware.LatestVersionPlaceholder),
+		URL:               urlPrefix + constructPackageURL(pkg.Path, mi.ModulePath, urlVersion),
+		LatestURL:         urlPrefix + constructPackageURL(pkg.Path, mi.ModulePath, middleware.LatestVersionPlaceholder),
+		UsesCgo:           pkg.UsesCgo,
+		GoReleaseTags:     pkg.GoReleaseTags,
 	}, nil
 }
 
@@ -83,8 +99,9 @@ func legacyCreatePackage(pkg *internal.LegacyPackage, mi *internal.ModuleInfo, l
 //
 // latestRequested indicates whether the user requested the latest
 // version of the package. If so, the returned Package.URL will have the
-// structure /<path> instead of /<path>@<version>.
-func createPackageNew(vdir *internal.VersionedDirectory, latestRequested bool) (_ *Package, err error) {
+// structure /<path> instead of /<path>@<version>. urlPrefix is prepended to
+// the URLs; see serveDetails.
+func createPackageNew(vdir *internal.VersionedDirectory, latestRequested bool, urlPrefix string) (_ *Package, err error) {
 	defer derrors.Wrap(&err, "createPackageNew(%v, %t)", vdir, latestRequested)
 
 	if vdir == nil || vdir.Package == nil {
@@ -98,7 +115,7 @@ func createPackageNew(vdir *internal.VersionedDirectory, latestRequested bool) (
 		}
 	}
 
-	m := createModule(&vdir.ModuleInfo, modLicenses, latestRequested)
+	m := createModule(&vdir.ModuleInfo, modLicenses, latestRequested, urlPrefix)
 	urlVersion := m.LinkVersion
 	if latestRequested {
 		urlVersion = internal.LatestVersion
@@ -109,8 +126,10 @@ func createPackageNew(vdir *internal.VersionedDirectory, latestRequested bool) (
 		IsRedistributable: vdir.DirectoryNew.IsRedistributable,
 		Licenses:          transformLicenseMetadata(vdir.Licenses),
 		Module:            *m,
-		URL:               constructPackageURL(vdir.Path, vdir.ModulePath, urlVersion),
-		LatestURL:         constructPackageURL(vdir.Path, vdir.ModulePath, middleware.LatestVersionPlaceholder),
+		URL:               urlPrefix + constructPackageURL(vdir.Path, vdir.ModulePath, urlVersion),
+		LatestURL:         urlPrefix + constructPackageURL(vdir.Path, vdir.ModulePath, middleware.LatestVersionPlaceholder),
+		UsesCgo:           vdir.Package.UsesCgo,
+		GoReleaseTags:     vdir.Package.GoReleaseTags,
 	}, nil
 }
 
@@ -119,21 +138,26 @@ func createPackageNew(vdir *internal.VersionedDirectory, latestRequested bool) (
 //
 // latestRequested indicates whether the user requested the latest
 // version of the package. If so, the returned Module.URL will have the
-// structure /<path> instead of /<path>@<version>.
-func createModule(mi *internal.ModuleInfo, licmetas []*licenses.Metadata, latestRequested bool) *Module {
+// structure /<path> instead of /<path>@<version>. urlPrefix is prepended to
+// the URLs; see serveDetails.
+func createModule(mi *internal.ModuleInfo, licmetas []*licenses.Metadata, latestRequested bool, urlPrefix string) *Module {
 	urlVersion := linkVersion(mi.Version, mi.ModulePath)
 	if latestRequested {
 		urlVersion = internal.LatestVersion
 	}
 	return &Module{
-		DisplayVersion:    displayVersion(mi.Version, mi.ModulePath),
-		LinkVersion:       linkVersion(mi.Version, mi.ModulePath),
-		ModulePath:        mi.ModulePath,
-		CommitTime:        elapsedTime(mi.CommitTime),
-		IsRedistributable: mi.IsRedistributable,
-		Licenses:          transformLicenseMetadata(licmetas),
-		URL:               constructModuleURL(mi.ModulePath, urlVersion),
-		LatestURL:         constructModuleURL(mi.ModulePath, middleware.LatestVersionPlaceholder),
+		DisplayVersion:      displayVersion(mi.Version, mi.ModulePath),
+		LinkVersion:         linkVersion(mi.Version, mi.ModulePath),
+		ModulePath:          mi.ModulePath,
+		CommitTime:          elapsedTime(mi.CommitTime),
+		IsRedistributable:   mi.IsRedistributable,
+		Licenses:            transformLicenseMetadata(licmetas),
+		URL:                 urlPrefix + constructModuleURL(mi.ModulePath, urlVersion),
+		LatestURL:           urlPrefix + constructModuleURL(mi.ModulePath, middleware.LatestVersionPlaceholder),
+		Retracted:           mi.Retracted,
+		RetractionRationale: mi.RetractionRationale,
+		Deprecation:         mi.Deprecation,
+		GoVersion:           mi.GoVersion,
 	}
 }
 
@@ -243,9 +267,10 @@ type link struct {
 // modPath is the package's module path. This will be a prefix of pkgPath, except
 // within the standard library.
 // version is the version for the module, or LatestVersion.
+// urlPrefix is prepended to the links; see serveDetails.
 //
 // See TestBreadcrumbPath for examples.
-func breadcrumbPath(pkgPath, modPath, requestedVersion string) breadcrumb {
+func breadcrumbPath(pkgPath, modPath, requestedVersion, urlPrefix string) breadcrumb {
 	if pkgPath == stdlib.ModulePath {
 		return breadcrumb{Current: "Standard library"}
 	}
@@ -271,7 +296,7 @@ func breadcrumbPath(pkgPath, modPath, requestedVersion string) breadcrumb {
 	// Make all the other parts into links.
 	b.Links = make([]link, len(dirs)-1)
 	for i := 1; i < len(dirs); i++ {
-		href := "/" + dirs[i]
+		href := urlPrefix + "/" + dirs[i]
 		if requestedVersion != internal.LatestVersion {
 			href += "@" + requestedVersion
 		}
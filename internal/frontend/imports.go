@@ -6,9 +6,11 @@ package frontend
 
 import (
 	"context"
+	"encoding/base64"
 	"strings"
 
 	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/log"
 	"golang.org/x/pkgsite/internal/postgres"
 	"golang.org/x/pkgsite/internal/stdlib"
 )
@@ -28,11 +30,14 @@ type ImportsDetails struct {
 	// StdLib is an array of packages representing the package's imports
 	// that are in the Go standard library.
 	StdLib []string
+
+	// URLPrefix is prepended to the links to InternalImports.
+	URLPrefix string
 }
 
 // fetchImportsDetails fetches imports for the package version specified by
 // pkgPath, modulePath and version from the database and returns a ImportsDetails.
-func fetchImportsDetails(ctx context.Context, ds internal.DataSource, pkgPath, modulePath, resolvedVersion string) (*ImportsDetails, error) {
+func fetchImportsDetails(ctx context.Context, ds internal.DataSource, urlPrefix, pkgPath, modulePath, resolvedVersion string) (*ImportsDetails, error) {
 	dsImports, err := ds.GetImports(ctx, pkgPath, modulePath, resolvedVersion)
 	if err != nil {
 		return nil, err
@@ -54,6 +59,7 @@ func fetchImportsDetails(ctx context.Context, ds internal.DataSource, pkgPath, m
 		ExternalImports: externalImports,
 		InternalImports: moduleImports,
 		StdLib:          std,
+		URLPrefix:       urlPrefix,
 	}, nil
 }
 
@@ -67,33 +73,81 @@ type ImportedByDetails struct {
 	// They are organized into a tree of sections by prefix.
 	ImportedBy []*Section
 
-	Total        int  // number of packages in ImportedBy
+	Total        int  // number of packages in ImportedBy and on the previous pages
 	TotalIsExact bool // if false, then there may be more than Total
+
+	// Pagination links to the other pages of importers, if there are more
+	// than fit on one page.
+	Pagination pagination
 }
 
-const importedByLimit = 20001
+// importedByPageSize is the maximum number of importers shown on a page of the
+// imported-by tab.
+const importedByPageSize = 20000
 
-// etchImportedByDetails fetches importers for the package version specified by
+// fetchImportedByDetails fetches importers for the package version specified by
 // path and version from the database and returns a ImportedByDetails.
-func fetchImportedByDetails(ctx context.Context, db *postgres.DB, pkgPath, modulePath string) (*ImportedByDetails, error) {
-	importedBy, err := db.GetImportedBy(ctx, pkgPath, modulePath, importedByLimit)
+//
+// The importers are read a page at a time, in order of their paths. The link
+// to the next page carries a cursor, the last path on the current page, so
+// that the next page is read without scanning the current one.
+func fetchImportedByDetails(ctx context.Context, db *postgres.DB, pkgPath, modulePath string, pageParams paginationParams) (*ImportedByDetails, error) {
+	if pageParams.limit > importedByPageSize {
+		pageParams.limit = importedByPageSize
+	}
+	var (
+		after string
+		skip  int
+	)
+	if pageParams.cursor != "" {
+		b, err := base64.RawURLEncoding.DecodeString(pageParams.cursor)
+		if err != nil {
+			log.Errorf(ctx, "fetchImportedByDetails: invalid cursor %q: %v", pageParams.cursor, err)
+		} else {
+			after = string(b)
+		}
+	}
+	if after == "" {
+		// Without a cursor, the pages before this one must be read and skipped.
+		skip = pageParams.offset()
+	}
+	// Ask for one more than the page size, to find out whether there is a next
+	// page.
+	importedBy, err := db.GetImportedBy(ctx, pkgPath, modulePath, after, skip+pageParams.limit+1)
 	if err != nil {
 		return nil, err
 	}
-	// If we reached the query limit, then we don't know the total.
-	// Say so, and show one less than the limit.
-	// For example, if the limit is 101 and we get 101 results, then we'll
+	if skip < len(importedBy) {
+		importedBy = importedBy[skip:]
+	} else {
+		importedBy = nil
+	}
+	// If there is a next page, then we don't know the total.
+	// Say so, and show only this page.
+	// For example, if the page size is 100 and we get 101 results, then we'll
 	// say there are more than 100, and show the first 100.
 	totalIsExact := true
-	if len(importedBy) == importedByLimit {
-		importedBy = importedBy[:len(importedBy)-1]
+	if len(importedBy) > pageParams.limit {
+		importedBy = importedBy[:pageParams.limit]
 		totalIsExact = false
 	}
+	total := pageParams.offset() + len(importedBy)
+	// Count the next page, if any, as a single result, so that the pagination
+	// links to it.
+	count := total
+	if !totalIsExact {
+		count++
+	}
+	pgs := newPagination(pageParams, len(importedBy), count)
+	if !totalIsExact {
+		pgs.setNextCursor(base64.RawURLEncoding.EncodeToString([]byte(importedBy[len(importedBy)-1])))
+	}
 	sections := Sections(importedBy, nextPrefixAccount)
 	return &ImportedByDetails{
 		ModulePath:   modulePath,
 		ImportedBy:   sections,
-		Total:        len(importedBy),
+		Total:        total,
 		TotalIsExact: totalIsExact,
+		Pagination:   pgs,
 	}, nil
 }
@@ -6,10 +6,12 @@ package frontend
 
 import (
 	"context"
+	"net/url"
 	"path"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/postgres"
 	"golang.org/x/pkgsite/internal/testing/sample"
@@ -57,7 +59,7 @@ func TestFetchImportsDetails(t *testing.T) {
 			}
 
 			pkg := firstVersionedPackage(module)
-			got, err := fetchImportsDetails(ctx, testDB, pkg.Path, pkg.ModulePath, pkg.Version)
+			got, err := fetchImportsDetails(ctx, testDB, "", pkg.Path, pkg.ModulePath, pkg.Version)
 			if err != nil {
 				t.Fatalf("fetchImportsDetails(ctx, db, %q, %q) = %v err = %v, want %v",
 					module.LegacyPackages[0].Path, module.Version, got, err, tc.wantDetails)
@@ -146,16 +148,71 @@ func TestFetchImportedByDetails(t *testing.T) {
 			otherVersion := newModule(path.Dir(tc.pkg.Path), tc.pkg)
 			otherVersion.Version = "v1.0.5"
 			vp := firstVersionedPackage(otherVersion)
-			got, err := fetchImportedByDetails(ctx, testDB, vp.Path, vp.ModulePath)
+			got, err := fetchImportedByDetails(ctx, testDB, vp.Path, vp.ModulePath, paginationParams{limit: importedByPageSize, page: 1})
 			if err != nil {
 				t.Fatalf("fetchImportedByDetails(ctx, db, %q) = %v err = %v, want %v",
 					tc.pkg.Path, got, err, tc.wantDetails)
 			}
 
 			tc.wantDetails.ModulePath = vp.LegacyModuleInfo.ModulePath
-			if diff := cmp.Diff(tc.wantDetails, got); diff != "" {
+			if diff := cmp.Diff(tc.wantDetails, got, cmpopts.IgnoreFields(ImportedByDetails{}, "Pagination")); diff != "" {
 				t.Errorf("fetchImportedByDetails(ctx, db, %q) mismatch (-want +got):\n%s", tc.pkg.Path, diff)
 			}
 		})
 	}
+
+	t.Run("paged", func(t *testing.T) {
+		baseURL, err := url.Parse("/" + pkg1.Path + "?tab=importedby")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fetch := func(page int, cursor string) *ImportedByDetails {
+			t.Helper()
+			got, err := fetchImportedByDetails(ctx, testDB, pkg1.Path, "path.to/foo",
+				paginationParams{baseURL: baseURL, limit: 1, page: page, cursor: cursor})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return got
+		}
+		first := fetch(1, "")
+		want := &ImportedByDetails{
+			ModulePath:   "path.to/foo",
+			ImportedBy:   []*Section{{Prefix: pkg2.Path, NumLines: 0}},
+			Total:        1,
+			TotalIsExact: false,
+		}
+		if diff := cmp.Diff(want, first, cmpopts.IgnoreFields(ImportedByDetails{}, "Pagination")); diff != "" {
+			t.Errorf("page 1 mismatch (-want +got):\n%s", diff)
+		}
+		if first.Pagination.NextPage != 2 {
+			t.Fatalf("page 1: got NextPage = %d, want 2", first.Pagination.NextPage)
+		}
+		next, err := url.Parse(first.Pagination.PageURL(2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cursor := next.Query().Get("cursor")
+		if cursor == "" {
+			t.Fatalf("page 1: no cursor in %q", next)
+		}
+
+		want = &ImportedByDetails{
+			ModulePath:   "path.to/foo",
+			ImportedBy:   []*Section{{Prefix: pkg3.Path, NumLines: 0}},
+			Total:        2,
+			TotalIsExact: true,
+		}
+		// The second page is the same whether it is read after the cursor or
+		// at its offset.
+		for _, c := range []string{cursor, ""} {
+			got := fetch(2, c)
+			if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(ImportedByDetails{}, "Pagination")); diff != "" {
+				t.Errorf("page 2 with cursor %q mismatch (-want +got):\n%s", c, diff)
+			}
+			if got.Pagination.NextPage != 0 {
+				t.Errorf("page 2 with cursor %q: got NextPage = %d, want 0", c, got.Pagination.NextPage)
+			}
+		}
+	})
 }
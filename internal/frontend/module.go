@@ -17,7 +17,7 @@ import (
 
 // legacyServeModulePage serves details pages for the module specified by modulePath
 // and version.
-func (s *Server) legacyServeModulePage(w http.ResponseWriter, r *http.Request, modulePath, requestedVersion, resolvedVersion string) error {
+func (s *Server) legacyServeModulePage(w http.ResponseWriter, r *http.Request, ds internal.DataSource, urlPrefix, modulePath, requestedVersion, resolvedVersion string) error {
 	// This function handles top level behavior related to the existence of the
 	// requested modulePath@version:
 	// TODO: fix
@@ -29,15 +29,15 @@ func (s *Server) legacyServeModulePage(w http.ResponseWriter, r *http.Request, m
 	//     b. We have valid versions for this module path, but `version` isn't
 	//        one of them. Serve a 404 but recommend the other versions.
 	ctx := r.Context()
-	mi, err := s.ds.LegacyGetModuleInfo(ctx, modulePath, resolvedVersion)
+	mi, err := ds.LegacyGetModuleInfo(ctx, modulePath, resolvedVersion)
 	if err == nil {
-		return s.legacyServeModulePageWithModule(ctx, w, r, mi, requestedVersion)
+		return s.legacyServeModulePageWithModule(ctx, w, r, ds, urlPrefix, mi, requestedVersion)
 	}
 	if !errors.Is(err, derrors.NotFound) {
 		return err
 	}
 	if requestedVersion != internal.LatestVersion {
-		_, err = s.ds.LegacyGetModuleInfo(ctx, modulePath, internal.LatestVersion)
+		_, err = ds.LegacyGetModuleInfo(ctx, modulePath, internal.LatestVersion)
 		if err == nil {
 			return pathFoundAtLatestError(ctx, "module", modulePath, displayVersion(requestedVersion, modulePath))
 		}
@@ -48,13 +48,13 @@ func (s *Server) legacyServeModulePage(w http.ResponseWriter, r *http.Request, m
 	return pathNotFoundError(ctx, "module", modulePath, requestedVersion)
 }
 
-func (s *Server) legacyServeModulePageWithModule(ctx context.Context, w http.ResponseWriter, r *http.Request, mi *internal.LegacyModuleInfo, requestedVersion string) error {
-	licenses, err := s.ds.LegacyGetModuleLicenses(ctx, mi.ModulePath, mi.Version)
+func (s *Server) legacyServeModulePageWithModule(ctx context.Context, w http.ResponseWriter, r *http.Request, ds internal.DataSource, urlPrefix string, mi *internal.LegacyModuleInfo, requestedVersion string) error {
+	licenses, err := ds.LegacyGetModuleLicenses(ctx, mi.ModulePath, mi.Version)
 	if err != nil {
 		return err
 	}
 
-	modHeader := createModule(&mi.ModuleInfo, licensesToMetadatas(licenses), requestedVersion == internal.LatestVersion)
+	modHeader := createModule(&mi.ModuleInfo, licensesToMetadatas(licenses), requestedVersion == internal.LatestVersion, urlPrefix)
 	tab := r.FormValue("tab")
 	settings, ok := moduleTabLookup[tab]
 	if !ok {
@@ -65,7 +65,7 @@ func (s *Server) legacyServeModulePageWithModule(ctx context.Context, w http.Res
 	var details interface{}
 	if canShowDetails {
 		var err error
-		details, err = fetchDetailsForModule(ctx, r, tab, s.ds, mi, licenses)
+		details, err = fetchDetailsForModule(ctx, r, tab, ds, urlPrefix, mi, licenses)
 		if err != nil {
 			return fmt.Errorf("error fetching page for %q: %v", tab, err)
 		}
@@ -75,7 +75,7 @@ func (s *Server) legacyServeModulePageWithModule(ctx context.Context, w http.Res
 		Title:          moduleTitle(mi.ModulePath),
 		Settings:       settings,
 		Header:         modHeader,
-		Breadcrumb:     breadcrumbPath(modHeader.ModulePath, modHeader.ModulePath, modHeader.LinkVersion),
+		Breadcrumb:     breadcrumbPath(modHeader.ModulePath, modHeader.ModulePath, modHeader.LinkVersion, urlPrefix),
 		Details:        details,
 		CanShowDetails: canShowDetails,
 		Tabs:           moduleTabSettings,
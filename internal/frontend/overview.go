@@ -40,8 +40,9 @@ type OverviewDetails struct {
 }
 
 // versionedLinks says whether the constructed URLs should have versions.
+// urlPrefix is prepended to the URL of the module page.
 // constructOverviewDetails uses the given version to construct an OverviewDetails.
-func constructOverviewDetails(ctx context.Context, mi *internal.ModuleInfo, readme *internal.Readme, isRedistributable bool, versionedLinks bool) (*OverviewDetails, error) {
+func constructOverviewDetails(ctx context.Context, mi *internal.ModuleInfo, readme *internal.Readme, isRedistributable bool, versionedLinks bool, urlPrefix string) (*OverviewDetails, error) {
 	var lv string
 	if versionedLinks {
 		lv = linkVersion(mi.Version, mi.ModulePath)
@@ -50,7 +51,7 @@ func constructOverviewDetails(ctx context.Context, mi *internal.ModuleInfo, read
 	}
 	overview := &OverviewDetails{
 		ModulePath:      mi.ModulePath,
-		ModuleURL:       constructModuleURL(mi.ModulePath, lv),
+		ModuleURL:       urlPrefix + constructModuleURL(mi.ModulePath, lv),
 		RepositoryURL:   mi.SourceInfo.RepoURL(),
 		Redistributable: isRedistributable,
 	}
@@ -66,9 +67,9 @@ func constructOverviewDetails(ctx context.Context, mi *internal.ModuleInfo, read
 }
 
 // fetchPackageOverviewDetails uses data for the given package to return an OverviewDetails.
-func fetchPackageOverviewDetails(ctx context.Context, pkg *internal.LegacyVersionedPackage, versionedLinks bool) (*OverviewDetails, error) {
+func fetchPackageOverviewDetails(ctx context.Context, pkg *internal.LegacyVersionedPackage, versionedLinks bool, urlPrefix string) (*OverviewDetails, error) {
 	od, err := constructOverviewDetails(ctx, &pkg.ModuleInfo, &internal.Readme{Filepath: pkg.LegacyReadmeFilePath, Contents: pkg.LegacyReadmeContents},
-		pkg.LegacyPackage.IsRedistributable, versionedLinks)
+		pkg.LegacyPackage.IsRedistributable, versionedLinks, urlPrefix)
 	if err != nil {
 		return nil, err
 	}
@@ -80,7 +81,7 @@ func fetchPackageOverviewDetails(ctx context.Context, pkg *internal.LegacyVersio
 }
 
 // fetchPackageOverviewDetailsNew uses data for the given versioned directory to return an OverviewDetails.
-func fetchPackageOverviewDetailsNew(ctx context.Context, vdir *internal.VersionedDirectory, versionedLinks bool) (*OverviewDetails, error) {
+func fetchPackageOverviewDetailsNew(ctx context.Context, vdir *internal.VersionedDirectory, versionedLinks bool, urlPrefix string) (*OverviewDetails, error) {
 	var lv string
 	if versionedLinks {
 		lv = linkVersion(vdir.Version, vdir.ModulePath)
@@ -89,7 +90,7 @@ func fetchPackageOverviewDetailsNew(ctx context.Context, vdir *internal.Versione
 	}
 	overview := &OverviewDetails{
 		ModulePath:       vdir.ModulePath,
-		ModuleURL:        constructModuleURL(vdir.ModulePath, lv),
+		ModuleURL:        urlPrefix + constructModuleURL(vdir.ModulePath, lv),
 		RepositoryURL:    vdir.SourceInfo.RepoURL(),
 		Redistributable:  vdir.DirectoryNew.IsRedistributable,
 		PackageSourceURL: vdir.SourceInfo.DirectoryURL(packageSubdir(vdir.Path, vdir.ModulePath)),
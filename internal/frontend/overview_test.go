@@ -48,7 +48,7 @@ func TestFetchOverviewDetails(t *testing.T) {
 	}
 
 	readme := &internal.Readme{Filepath: tc.module.LegacyReadmeFilePath, Contents: tc.module.LegacyReadmeContents}
-	got, err := constructOverviewDetails(ctx, &tc.module.ModuleInfo, readme, true, true)
+	got, err := constructOverviewDetails(ctx, &tc.module.ModuleInfo, readme, true, true, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -141,7 +141,7 @@ func TestConstructPackageOverviewDetailsNew(t *testing.T) {
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			got, err := fetchPackageOverviewDetailsNew(context.Background(), test.vdir, test.versionedLinks)
+			got, err := fetchPackageOverviewDetailsNew(context.Background(), test.vdir, test.versionedLinks, "")
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -26,7 +26,7 @@ func (s *Server) handlePackageDetailsRedirect(w http.ResponseWriter, r *http.Req
 
 // legacyServePackagePage serves details pages for the package with import path
 // pkgPath, in the module specified by modulePath and version.
-func (s *Server) legacyServePackagePage(w http.ResponseWriter, r *http.Request, pkgPath, modulePath, requestedVersion, resolvedVersion string) (err error) {
+func (s *Server) legacyServePackagePage(w http.ResponseWriter, r *http.Request, ds internal.DataSource, urlPrefix, pkgPath, modulePath, requestedVersion, resolvedVersion string) (err error) {
 	ctx := r.Context()
 
 	// This function handles top level behavior related to the existence of the
@@ -36,9 +36,9 @@ func (s *Server) legacyServePackagePage(w http.ResponseWriter, r *http.Request,
 	//   3. If there is another version that contains this package path: serve a
 	//      404 and suggest these versions.
 	//   4. Just serve a 404
-	pkg, err := s.ds.LegacyGetPackage(ctx, pkgPath, modulePath, resolvedVersion)
+	pkg, err := ds.LegacyGetPackage(ctx, pkgPath, modulePath, resolvedVersion)
 	if err == nil {
-		return s.legacyServePackagePageWithPackage(ctx, w, r, pkg, requestedVersion)
+		return s.legacyServePackagePageWithPackage(ctx, w, r, ds, urlPrefix, pkg, requestedVersion)
 	}
 	if !errors.Is(err, derrors.NotFound) {
 		return err
@@ -47,25 +47,25 @@ func (s *Server) legacyServePackagePage(w http.ResponseWriter, r *http.Request,
 		// If we've already checked the latest version, then we know that this path
 		// is not a package at any version, so just skip ahead and serve the
 		// directory page.
-		dbDir, err := s.ds.LegacyGetDirectory(ctx, pkgPath, modulePath, resolvedVersion, internal.AllFields)
+		dbDir, err := ds.LegacyGetDirectory(ctx, pkgPath, modulePath, resolvedVersion, internal.AllFields)
 		if err != nil {
 			if errors.Is(err, derrors.NotFound) {
 				return pathNotFoundError(ctx, "package", pkgPath, requestedVersion)
 			}
 			return err
 		}
-		return s.legacyServeDirectoryPage(ctx, w, r, dbDir, requestedVersion)
+		return s.legacyServeDirectoryPage(ctx, w, r, ds, urlPrefix, dbDir, requestedVersion)
 	}
-	dir, err := s.ds.LegacyGetDirectory(ctx, pkgPath, modulePath, resolvedVersion, internal.AllFields)
+	dir, err := ds.LegacyGetDirectory(ctx, pkgPath, modulePath, resolvedVersion, internal.AllFields)
 	if err == nil {
-		return s.legacyServeDirectoryPage(ctx, w, r, dir, requestedVersion)
+		return s.legacyServeDirectoryPage(ctx, w, r, ds, urlPrefix, dir, requestedVersion)
 	}
 	if !errors.Is(err, derrors.NotFound) {
 		// The only error we expect is NotFound, so serve an 500 here, otherwise
 		// whatever response we resolve below might be inconsistent or misleading.
 		return fmt.Errorf("checking for directory: %v", err)
 	}
-	_, err = s.ds.LegacyGetPackage(ctx, pkgPath, modulePath, internal.LatestVersion)
+	_, err = ds.LegacyGetPackage(ctx, pkgPath, modulePath, internal.LatestVersion)
 	if err == nil {
 		return pathFoundAtLatestError(ctx, "package", pkgPath, requestedVersion)
 	}
@@ -82,13 +82,13 @@ func (s *Server) legacyServePackagePage(w http.ResponseWriter, r *http.Request,
 	return pathNotFoundError(ctx, "package", pkgPath, requestedVersion)
 }
 
-func (s *Server) legacyServePackagePageWithPackage(ctx context.Context, w http.ResponseWriter, r *http.Request, pkg *internal.LegacyVersionedPackage, requestedVersion string) (err error) {
+func (s *Server) legacyServePackagePageWithPackage(ctx context.Context, w http.ResponseWriter, r *http.Request, ds internal.DataSource, urlPrefix string, pkg *internal.LegacyVersionedPackage, requestedVersion string) (err error) {
 	defer func() {
 		if _, ok := err.(*serverError); !ok {
 			derrors.Wrap(&err, "legacyServePackagePageWithPackage(w, r, %q, %q, %q)", pkg.Path, pkg.ModulePath, requestedVersion)
 		}
 	}()
-	pkgHeader, err := legacyCreatePackage(&pkg.LegacyPackage, &pkg.ModuleInfo, requestedVersion == internal.LatestVersion)
+	pkgHeader, err := legacyCreatePackage(&pkg.LegacyPackage, &pkg.ModuleInfo, requestedVersion == internal.LatestVersion, urlPrefix)
 	if err != nil {
 		return fmt.Errorf("creating package header for %s@%s: %v", pkg.Path, pkg.Version, err)
 	}
@@ -102,7 +102,7 @@ func (s *Server) legacyServePackagePageWithPackage(ctx context.Context, w http.R
 		} else {
 			tab = "overview"
 		}
-		http.Redirect(w, r, fmt.Sprintf(r.URL.Path+"?tab=%s", tab), http.StatusFound)
+		http.Redirect(w, r, fmt.Sprintf("%s%s?tab=%s", urlPrefix, r.URL.Path, tab), http.StatusFound)
 		return nil
 	}
 	canShowDetails := pkg.LegacyPackage.IsRedistributable || settings.AlwaysShowDetails
@@ -110,7 +110,7 @@ func (s *Server) legacyServePackagePageWithPackage(ctx context.Context, w http.R
 	var details interface{}
 	if canShowDetails {
 		var err error
-		details, err = fetchDetailsForPackage(ctx, r, tab, s.ds, pkg)
+		details, err = fetchDetailsForPackage(ctx, r, tab, ds, urlPrefix, pkg)
 		if err != nil {
 			return fmt.Errorf("fetching page for %q: %v", tab, err)
 		}
@@ -121,7 +121,7 @@ func (s *Server) legacyServePackagePageWithPackage(ctx context.Context, w http.R
 		Settings: settings,
 		Header:   pkgHeader,
 		Breadcrumb: breadcrumbPath(pkgHeader.Path, pkgHeader.Module.ModulePath,
-			pkgHeader.Module.LinkVersion),
+			pkgHeader.Module.LinkVersion, urlPrefix),
 		Details:        details,
 		CanShowDetails: canShowDetails,
 		Tabs:           packageTabSettings,
@@ -131,25 +131,25 @@ func (s *Server) legacyServePackagePageWithPackage(ctx context.Context, w http.R
 	return nil
 }
 
-func (s *Server) servePackagePageNew(w http.ResponseWriter, r *http.Request, fullPath, modulePath, requestedVersion, resolvedVersion string) (err error) {
+func (s *Server) servePackagePageNew(w http.ResponseWriter, r *http.Request, ds internal.DataSource, urlPrefix, fullPath, modulePath, requestedVersion, resolvedVersion string) (err error) {
 	defer func() {
 		if _, ok := err.(*serverError); !ok {
 			derrors.Wrap(&err, "servePackagePageNew(w, r, %q, %q, %q)", fullPath, modulePath, requestedVersion)
 		}
 	}()
 	ctx := r.Context()
-	vdir, err := s.ds.GetDirectoryNew(ctx, fullPath, modulePath, resolvedVersion)
+	vdir, err := ds.GetDirectoryNew(ctx, fullPath, modulePath, resolvedVersion)
 	if err != nil {
 		return err
 	}
 	if vdir.Package != nil {
-		return s.servePackagePageWithVersionedDirectory(ctx, w, r, vdir, requestedVersion)
+		return s.servePackagePageWithVersionedDirectory(ctx, w, r, ds, urlPrefix, vdir, requestedVersion)
 	}
-	dir, err := s.ds.LegacyGetDirectory(ctx, fullPath, modulePath, resolvedVersion, internal.AllFields)
+	dir, err := ds.LegacyGetDirectory(ctx, fullPath, modulePath, resolvedVersion, internal.AllFields)
 	if err != nil {
 		return err
 	}
-	return s.legacyServeDirectoryPage(ctx, w, r, dir, requestedVersion)
+	return s.legacyServeDirectoryPage(ctx, w, r, ds, urlPrefix, dir, requestedVersion)
 }
 
 // stdlibPathForShortcut returns a path in the stdlib that shortcut should redirect to,
@@ -175,8 +175,8 @@ func (s *Server) stdlibPathForShortcut(ctx context.Context, shortcut string) (pa
 }
 
 func (s *Server) servePackagePageWithVersionedDirectory(ctx context.Context,
-	w http.ResponseWriter, r *http.Request, vdir *internal.VersionedDirectory, requestedVersion string) error {
-	pkgHeader, err := createPackageNew(vdir, requestedVersion == internal.LatestVersion)
+	w http.ResponseWriter, r *http.Request, ds internal.DataSource, urlPrefix string, vdir *internal.VersionedDirectory, requestedVersion string) error {
+	pkgHeader, err := createPackageNew(vdir, requestedVersion == internal.LatestVersion, urlPrefix)
 	if err != nil {
 		return fmt.Errorf("creating package header for %s@%s: %v", vdir.Path, vdir.Version, err)
 	}
@@ -190,7 +190,7 @@ func (s *Server) servePackagePageWithVersionedDirectory(ctx context.Context,
 		} else {
 			tab = "overview"
 		}
-		http.Redirect(w, r, fmt.Sprintf(r.URL.Path+"?tab=%s", tab), http.StatusFound)
+		http.Redirect(w, r, fmt.Sprintf("%s%s?tab=%s", urlPrefix, r.URL.Path, tab), http.StatusFound)
 		return nil
 	}
 	canShowDetails := vdir.DirectoryNew.IsRedistributable || settings.AlwaysShowDetails
@@ -198,7 +198,7 @@ func (s *Server) servePackagePageWithVersionedDirectory(ctx context.Context,
 	var details interface{}
 	if canShowDetails {
 		var err error
-		details, err = fetchDetailsForVersionedDirectory(ctx, r, tab, s.ds, vdir)
+		details, err = fetchDetailsForVersionedDirectory(ctx, r, tab, ds, urlPrefix, vdir)
 		if err != nil {
 			return fmt.Errorf("fetching page for %q: %v", tab, err)
 		}
@@ -209,7 +209,7 @@ func (s *Server) servePackagePageWithVersionedDirectory(ctx context.Context,
 		Settings: settings,
 		Header:   pkgHeader,
 		Breadcrumb: breadcrumbPath(pkgHeader.Path, pkgHeader.Module.ModulePath,
-			pkgHeader.Module.LinkVersion),
+			pkgHeader.Module.LinkVersion, urlPrefix),
 		Details:        details,
 		CanShowDetails: canShowDetails,
 		Tabs:           packageTabSettings,
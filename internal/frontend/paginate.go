@@ -18,9 +18,15 @@ import (
 // Given a sequence of results with offsets 0, 1, 2 ... (typically from a
 // database query), we paginate it by dividing it into numbered pages
 // 1, 2, 3, .... Each page except possibly the last has the same number of results.
+//
+// The link to the next page may also carry an opaque cursor that marks the
+// position of the last result on the current page, so that the next page can
+// be read without skipping the results before it. Links to other pages use
+// only the page number.
 type pagination struct {
 	baseURL     *url.URL // URL common to all pages
 	limit       int      // the maximum number of results on a page
+	nextCursor  string   // cursor of the last result on the page, if NextPage is set
 	ResultCount int      // number of results on this page
 	TotalCount  int      // total number of results
 	Approximate bool     // whether or not the total count is approximate
@@ -32,10 +38,16 @@ type pagination struct {
 }
 
 // PageURL constructs a URL that displays the given page.
-// It adds a "page" query parameter to the base URL.
+// It adds a "page" query parameter to the base URL, and a "cursor" query
+// parameter if page is the next page and its cursor is known.
 func (p pagination) PageURL(page int) string {
 	newQuery := p.baseURL.Query()
 	newQuery.Set("page", strconv.Itoa(page))
+	if page == p.NextPage && p.nextCursor != "" {
+		newQuery.Set("cursor", p.nextCursor)
+	} else {
+		newQuery.Del("cursor")
+	}
 	p.baseURL.RawQuery = newQuery.Encode()
 	return p.baseURL.String()
 }
@@ -58,11 +70,19 @@ func newPagination(params paginationParams, resultCount, totalCount int) paginat
 	}
 }
 
+// setNextCursor sets the cursor to use for the next page, if there is one.
+func (p *pagination) setNextCursor(cursor string) {
+	if p.NextPage != 0 {
+		p.nextCursor = cursor
+	}
+}
+
 // paginationParams holds pagination parameters extracted from the request.
 type paginationParams struct {
 	baseURL *url.URL
-	page    int // the number of the page to display
-	limit   int // the maximum number of results to display on the page
+	page    int    // the number of the page to display
+	limit   int    // the maximum number of results to display on the page
+	cursor  string // if non-empty, the page starts after this position
 }
 
 // offset returns the offset of the first result on the page.
@@ -89,6 +109,7 @@ func newPaginationParams(r *http.Request, defaultLimit int) paginationParams {
 		baseURL: r.URL,
 		page:    positiveParam("page", 1),
 		limit:   positiveParam("limit", defaultLimit),
+		cursor:  r.FormValue("cursor"),
 	}
 }
 
@@ -5,6 +5,7 @@
 package frontend
 
 import (
+	"net/url"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -197,3 +198,31 @@ func TestPagesToDisplay(t *testing.T) {
 		})
 	}
 }
+
+func TestPageURL(t *testing.T) {
+	baseURL, err := url.Parse("/search?q=foo&page=2&cursor=old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgs := newPagination(paginationParams{baseURL: baseURL, page: 2, limit: 10}, 10, 47)
+	pgs.setNextCursor("new")
+	for _, tc := range []struct {
+		page int
+		want string
+	}{
+		{1, "/search?page=1&q=foo"},
+		{3, "/search?cursor=new&page=3&q=foo"},
+		{4, "/search?page=4&q=foo"},
+	} {
+		if got := pgs.PageURL(tc.page); got != tc.want {
+			t.Errorf("PageURL(%d) = %q, want %q", tc.page, got, tc.want)
+		}
+	}
+
+	// There is no next page, so the cursor is not used.
+	last := newPagination(paginationParams{baseURL: baseURL, page: 5, limit: 10}, 7, 47)
+	last.setNextCursor("new")
+	if got, want := last.PageURL(4), "/search?page=4&q=foo"; got != want {
+		t.Errorf("PageURL(4) = %q, want %q", got, want)
+	}
+}
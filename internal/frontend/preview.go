@@ -0,0 +1,307 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"archive/zip"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/safehtml/template"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/fetch"
+	"golang.org/x/pkgsite/internal/localdatasource"
+)
+
+const (
+	// previewExpiration is how long an uploaded module can be previewed.
+	previewExpiration = 30 * time.Minute
+
+	// maxPreviews is the maximum number of modules that are kept for
+	// preview at a time.
+	maxPreviews = 50
+
+	// maxPreviewBytes is the maximum total size, as estimated by moduleSize,
+	// of the modules that are kept for preview.
+	maxPreviewBytes = 200 * 1000 * 1000
+
+	// maxPreviewUploads is the maximum number of uploads that are processed
+	// at a time.
+	maxPreviewUploads = 2
+
+	// maxPreviewFieldSize is the maximum size of the module path and version
+	// fields of an upload.
+	maxPreviewFieldSize = 1000
+)
+
+var (
+	// errPreviewStoreFull is returned when the preview store cannot hold
+	// another module.
+	errPreviewStoreFull = errors.New("preview store is full")
+
+	// errTooManyUploads is returned when maxPreviewUploads uploads are
+	// already being processed.
+	errTooManyUploads = errors.New("too many preview uploads")
+)
+
+// A previewStore holds the modules that were uploaded for preview. Each is
+// served from its own datasource, which holds only that module.
+type previewStore struct {
+	// uploads holds a value for each upload that is being processed.
+	uploads chan struct{}
+
+	mu      sync.Mutex
+	entries map[string]*previewEntry
+	// size is the total size of the modules in entries.
+	size int64
+}
+
+type previewEntry struct {
+	ds internal.DataSource
+	// size is the size of the module, as estimated by moduleSize.
+	size    int64
+	expires time.Time
+}
+
+func newPreviewStore() *previewStore {
+	return &previewStore{
+		uploads: make(chan struct{}, maxPreviewUploads),
+		entries: make(map[string]*previewEntry),
+	}
+}
+
+// add adds e to the store and returns its ID. It fails if the store is full.
+func (ps *previewStore) add(e *previewEntry, now time.Time) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b[:])
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.removeExpired(now)
+	if len(ps.entries) >= maxPreviews || ps.size+e.size > maxPreviewBytes {
+		return "", errPreviewStoreFull
+	}
+	ps.entries[id] = e
+	ps.size += e.size
+	return id, nil
+}
+
+// get returns the entry with the given ID, or nil if there is none or it has
+// expired.
+func (ps *previewStore) get(id string, now time.Time) *previewEntry {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.removeExpired(now)
+	return ps.entries[id]
+}
+
+// removeExpired removes the expired entries. ps.mu must be held.
+func (ps *previewStore) removeExpired(now time.Time) {
+	for id, e := range ps.entries {
+		if now.After(e.expires) {
+			delete(ps.entries, id)
+			ps.size -= e.size
+		}
+	}
+}
+
+// moduleSize estimates the memory that m holds: the contents of its READMEs
+// and licenses, and the documentation of its packages, which its directories
+// share.
+func moduleSize(m *internal.Module) int64 {
+	n := len(m.LegacyReadmeContents)
+	for _, lic := range m.Licenses {
+		n += len(lic.Contents)
+	}
+	for _, pkg := range m.LegacyPackages {
+		n += len(pkg.DocumentationHTML.String())
+		for _, doc := range pkg.OtherDocumentation {
+			n += len(doc.HTML.String())
+		}
+	}
+	for _, dir := range m.Directories {
+		if dir.Readme != nil && dir.Path != m.ModulePath {
+			n += len(dir.Readme.Contents)
+		}
+	}
+	return int64(n)
+}
+
+// PreviewPage contains data for the preview upload page.
+type PreviewPage struct {
+	basePage
+	MaxFileSizeMB int
+	Expiration    string
+}
+
+// servePreview handles requests for previews of uploaded modules. A GET of
+// "/preview/" serves a form for uploading a module zip, which is POSTed to the
+// same path. The pages of an uploaded module are served at
+// "/preview/<id>/<path>", where <path> is a path that serveDetails accepts.
+func (s *Server) servePreview(w http.ResponseWriter, r *http.Request) error {
+	if r.URL.Path == "/preview/" {
+		switch r.Method {
+		case http.MethodGet:
+			s.servePage(r.Context(), w, "preview.tmpl", &PreviewPage{
+				basePage:      s.newBasePage(r, "Preview a Module - go.dev"),
+				MaxFileSizeMB: fetch.MaxFileSize / (1000 * 1000),
+				Expiration:    previewExpiration.String(),
+			})
+			return nil
+		case http.MethodPost:
+			return s.servePreviewUpload(w, r)
+		default:
+			return &serverError{status: http.StatusMethodNotAllowed}
+		}
+	}
+	if r.Method != http.MethodGet {
+		return &serverError{status: http.StatusMethodNotAllowed}
+	}
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/preview/"), "/", 2)
+	e := s.previews.get(parts[0], time.Now())
+	if e == nil || len(parts) < 2 {
+		return &serverError{
+			status: http.StatusNotFound,
+			epage: &errorPage{
+				messageTemplate: template.MakeTrustedTemplate(`
+					<h3 class="Error-message">This preview does not exist or has expired.</h3>
+					<p class="Error-message">To preview a module, <a href="/preview/">upload it again</a>.</p>`),
+			},
+		}
+	}
+	r2 := r.Clone(r.Context())
+	r2.URL.Path = "/" + parts[1]
+	return s.serveDetails(w, r2, e.ds, "/preview/"+parts[0])
+}
+
+// servePreviewUpload processes a module zip uploaded in a multipart form,
+// together with its module path and version, and redirects to the page of
+// the module. The module is kept in memory for previewExpiration.
+func (s *Server) servePreviewUpload(w http.ResponseWriter, r *http.Request) error {
+	badRequest := func(msg string) error {
+		return &serverError{
+			status: http.StatusBadRequest,
+			epage: &errorPage{
+				messageTemplate: template.MakeTrustedTemplate(`<h3 class="Error-message">{{.}}</h3>`),
+				MessageData:     msg,
+			},
+		}
+	}
+	// The limit on the size of a file in a module zip also limits the size of
+	// the zip, which includes the form fields.
+	if r.ContentLength > fetch.MaxFileSize {
+		return &serverError{status: http.StatusRequestEntityTooLarge}
+	}
+	select {
+	case s.previews.uploads <- struct{}{}:
+		defer func() { <-s.previews.uploads }()
+	default:
+		return &serverError{status: http.StatusServiceUnavailable, err: errTooManyUploads}
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, fetch.MaxFileSize)
+	f, err := ioutil.TempFile("", "pkgsite-preview-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	modulePath, version, zipSize, err := readPreviewUpload(r, f)
+	if err != nil {
+		return badRequest(fmt.Sprintf("Could not read the upload: %v.", err))
+	}
+	if err := module.CheckPath(modulePath); err != nil {
+		return badRequest(fmt.Sprintf("Invalid module path: %v.", err))
+	}
+	if !semver.IsValid(version) || semver.Canonical(version) != version {
+		return badRequest(fmt.Sprintf("%q is not a canonical semantic version.", version))
+	}
+	zipReader, err := zip.NewReader(f, zipSize)
+	if err != nil {
+		return badRequest(fmt.Sprintf("Invalid zip file: %v.", err))
+	}
+	fr := fetch.FetchZip(r.Context(), modulePath, version, zipReader)
+	if fr.Error != nil {
+		return badRequest(fmt.Sprintf("The module could not be processed: %v.", fr.Error))
+	}
+	ds := localdatasource.New()
+	ds.AddModule(fr.Module)
+	id, err := s.previews.add(&previewEntry{
+		ds:      ds,
+		size:    moduleSize(fr.Module),
+		expires: time.Now().Add(previewExpiration),
+	}, time.Now())
+	if errors.Is(err, errPreviewStoreFull) {
+		return &serverError{status: http.StatusServiceUnavailable, err: err}
+	}
+	if err != nil {
+		return err
+	}
+	http.Redirect(w, r, fmt.Sprintf("/preview/%s/%s@%s", id, modulePath, version), http.StatusSeeOther)
+	return nil
+}
+
+// readPreviewUpload reads the multipart form of a preview upload from the body
+// of r, and returns its module path and version. The module zip is written to
+// f rather than held in memory, and its size is returned.
+func readPreviewUpload(r *http.Request, f *os.File) (modulePath, version string, zipSize int64, err error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return "", "", 0, err
+	}
+	hasZip := false
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", "", 0, err
+		}
+		switch name := p.FormName(); name {
+		case "module", "version":
+			// Read one byte more than the limit, to detect longer values.
+			b, err := ioutil.ReadAll(io.LimitReader(p, maxPreviewFieldSize+1))
+			if err != nil {
+				return "", "", 0, err
+			}
+			if len(b) > maxPreviewFieldSize {
+				return "", "", 0, fmt.Errorf("the %s field is too long", name)
+			}
+			if name == "module" {
+				modulePath = strings.TrimSpace(string(b))
+			} else {
+				version = strings.TrimSpace(string(b))
+			}
+		case "zip":
+			if hasZip {
+				return "", "", 0, errors.New("more than one module zip")
+			}
+			hasZip = true
+			zipSize, err = io.Copy(f, p)
+			if err != nil {
+				return "", "", 0, err
+			}
+		}
+	}
+	if !hasZip {
+		return "", "", 0, errors.New("missing module zip")
+	}
+	return modulePath, version, zipSize, nil
+}
@@ -0,0 +1,167 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/safehtml/template"
+	"golang.org/x/pkgsite/internal/testing/testhelper"
+)
+
+// uploadRequest returns a request that uploads a module zip with the given
+// files, relative to the module root, for preview.
+func uploadRequest(t *testing.T, modulePath, version string, files map[string]string) *http.Request {
+	t.Helper()
+	var zbuf bytes.Buffer
+	zw := zip.NewWriter(&zbuf)
+	for name, contents := range files {
+		fw, err := zw.Create(modulePath + "@" + version + "/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("module", modulePath)
+	mw.WriteField("version", version)
+	fw, err := mw.CreateFormFile("zip", "module.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(zbuf.Bytes())
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("POST", "/preview/", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestPreview(t *testing.T) {
+	s, err := NewServer(ServerConfig{
+		StaticPath:     template.TrustedSourceFromConstant("../../content/static"),
+		ThirdPartyPath: "../../third_party",
+		EnablePreview:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	s.Install(mux.Handle, nil)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/preview/", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `enctype="multipart/form-data"`) {
+		t.Fatalf("upload form: got status %d; body:\n%s", w.Code, w.Body)
+	}
+
+	files := map[string]string{
+		"go.mod":  "module example.com/preview",
+		"LICENSE": testhelper.MITLicense,
+		"p/p.go":  "// Package p is being previewed.\npackage p\n\nimport (\n\t_ \"example.com/preview/q\"\n\t_ \"example.com/previewer/r\"\n)\n\n// F does nothing.\nfunc F() {}\n",
+		"q/q.go":  "// Package q is imported by p.\npackage q\n",
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, uploadRequest(t, "example.com/preview", "v1.2.0", files))
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("upload: got status %d, want %d; body:\n%s", w.Code, http.StatusSeeOther, w.Body)
+	}
+	loc := w.Header().Get("Location")
+	if !strings.HasPrefix(loc, "/preview/") || !strings.HasSuffix(loc, "/example.com/preview@v1.2.0") {
+		t.Fatalf("upload: got Location %q", loc)
+	}
+	prefix := strings.TrimSuffix(loc, "/example.com/preview@v1.2.0")
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", prefix+"/example.com/preview@v1.2.0/p?tab=doc", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("package page: got status %d, want %d; body:\n%s", w.Code, http.StatusOK, w.Body)
+	}
+	for _, want := range []string{
+		"Package p is being previewed.",
+		"does nothing.",
+		`href="` + prefix + `/example.com/preview@v1.2.0/p?tab=licenses"`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("package page does not contain %q:\n%s", want, w.Body)
+		}
+	}
+
+	// Only the links to the pages of the module are under the preview.
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", prefix+"/example.com/preview@v1.2.0/p?tab=imports", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("imports page: got status %d, want %d; body:\n%s", w.Code, http.StatusOK, w.Body)
+	}
+	for _, want := range []string{
+		`href="` + prefix + `/example.com/preview/q"`,
+		`href="/example.com/previewer/r"`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("imports page does not contain %q:\n%s", want, w.Body)
+		}
+	}
+
+	for _, test := range []struct {
+		name       string
+		req        *http.Request
+		wantStatus int
+	}{
+		{"unknown preview", httptest.NewRequest("GET", "/preview/0123/example.com/preview@v1.2.0", nil), http.StatusNotFound},
+		{"bad version", uploadRequest(t, "example.com/preview", "v1.2", files), http.StatusBadRequest},
+		{"wrong module path", uploadRequest(t, "example.com/other", "v1.2.0", files), http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, test.req)
+		if w.Code != test.wantStatus {
+			t.Errorf("%s: got status %d, want %d", test.name, w.Code, test.wantStatus)
+		}
+	}
+}
+
+func TestPreviewStoreLimits(t *testing.T) {
+	ps := newPreviewStore()
+	now := time.Now()
+	add := func(size int64, expires time.Time) error {
+		_, err := ps.add(&previewEntry{size: size, expires: expires}, now)
+		return err
+	}
+	if err := add(maxPreviewBytes-1, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := add(2, now.Add(time.Minute)); !errors.Is(err, errPreviewStoreFull) {
+		t.Fatalf("got %v, want %v", err, errPreviewStoreFull)
+	}
+	if err := add(1, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Expired entries no longer count toward the limits.
+	now = now.Add(2 * time.Minute)
+	for i := 0; i < maxPreviews; i++ {
+		if err := add(1, now.Add(time.Minute)); err != nil {
+			t.Fatalf("entry %d: %v", i, err)
+		}
+	}
+	if err := add(1, now.Add(time.Minute)); !errors.Is(err, errPreviewStoreFull) {
+		t.Fatalf("got %v, want %v", err, errPreviewStoreFull)
+	}
+}
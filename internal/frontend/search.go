@@ -6,21 +6,32 @@ package frontend
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
 	"net/http"
 	"path"
+	"strconv"
 	"strings"
 
+	"github.com/google/safehtml/template"
 	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/experiment"
+	"golang.org/x/pkgsite/internal/fetch"
 	"golang.org/x/pkgsite/internal/log"
 	"golang.org/x/pkgsite/internal/postgres"
+	"golang.org/x/pkgsite/internal/version"
 )
 
-const defaultSearchLimit = 10
+const (
+	defaultSearchLimit = 10
+
+	// maxSymbolResults is the maximum number of symbols shown above the
+	// package results on the first page of search results.
+	maxSymbolResults = 5
+)
 
 // SearchPage contains all of the data that the search template needs to
 // populate.
@@ -28,6 +39,16 @@ type SearchPage struct {
 	basePage
 	Pagination pagination
 	Results    []*SearchResult
+	Symbols    []*SymbolResult
+	Facets     []*SearchFacet
+	// FacetsApproximate reports whether the facet counts are estimates.
+	FacetsApproximate bool
+	// Suggestion is a corrected search query, shown as "Did you mean ...?" when
+	// the query has few exact matches.
+	Suggestion string
+	// Explanation, if non-nil, describes how the search was executed. It is
+	// set only if the explain query parameter is allowed and present.
+	Explanation *internal.SearchExplanation
 }
 
 // SearchResult contains data needed to display a single search result.
@@ -41,19 +62,88 @@ type SearchResult struct {
 	CommitTime     string
 	NumImportedBy  uint64
 	Approximate    bool
+
+	// IsModule reports whether the result is a module without a root
+	// package, which links to the module page instead of a package page.
+	IsModule bool
+
+	// ReadmeSection and ReadmeAnchor are the heading and heading anchor of
+	// the README section that best matches the query, if any.
+	ReadmeSection string
+	ReadmeAnchor  string
+
+	// OtherMajor holds the same package in other major versions of its
+	// module, newest first. Only PackagePath, ModulePath, DisplayVersion and
+	// IsModule are set.
+	OtherMajor []*SearchResult
+
+	// Explanation holds the factors of the result's score. It is set only
+	// when the search page is explained.
+	Explanation *internal.ScoreExplanation
+}
+
+// SymbolResult contains data needed to display a single symbol search result.
+type SymbolResult struct {
+	// Name is the name of the symbol, qualified by its package name and, for
+	// methods, its type name.
+	Name           string
+	Kind           internal.SymbolKind
+	Synopsis       string
+	PackagePath    string
+	DisplayVersion string
+	NumImportedBy  uint64
+	// Anchor is the id of the symbol's documentation on the package page.
+	Anchor string
+}
+
+// SearchFacet is a group of refinements of the search results, one for each
+// value of some property of the matching packages.
+type SearchFacet struct {
+	Name   string
+	Values []*SearchFacetValue
 }
 
-// fetchSearchPage fetches data matching the search query from the database and
-// returns a SearchPage.
-func fetchSearchPage(ctx context.Context, db *postgres.DB, query string, pageParams paginationParams) (*SearchPage, error) {
-	dbresults, err := db.Search(ctx, query, pageParams.limit, pageParams.offset())
+// SearchFacetValue is a single refinement of the search results.
+type SearchFacetValue struct {
+	Label string
+	Count uint64
+	// Refinement is the search filter that restricts the results to packages
+	// with this value, or the empty string if the filter is already applied.
+	Refinement string
+}
+
+// fetchSearchPage fetches data matching the search query and filters from the
+// database and returns a SearchPage. If explain is true, the page also
+// explains how the search was executed.
+func fetchSearchPage(ctx context.Context, db *postgres.DB, query string, filters internal.SearchFilters, pageParams paginationParams, explain bool) (*SearchPage, error) {
+	var (
+		dbresults   []*internal.SearchResult
+		dbfacets    *internal.SearchFacets
+		explanation *internal.SearchExplanation
+		err         error
+	)
+	// The cursor takes precedence over the page number, which is then used only
+	// to display the position of the page. An invalid cursor is ignored.
+	var cursor *internal.SearchCursor
+	if pageParams.cursor != "" {
+		cursor, err = internal.DecodeSearchCursor(pageParams.cursor)
+		if err != nil {
+			log.Errorf(ctx, "fetchSearchPage: %v", err)
+			cursor = nil
+		}
+	}
+	if explain {
+		dbresults, dbfacets, explanation, err = db.SearchWithExplanation(ctx, query, filters, pageParams.limit, pageParams.offset(), cursor)
+	} else {
+		dbresults, dbfacets, err = db.Search(ctx, query, filters, pageParams.limit, pageParams.offset(), cursor)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	var results []*SearchResult
-	for _, r := range dbresults {
-		results = append(results, &SearchResult{
+	for i, r := range dbresults {
+		sr := &SearchResult{
 			Name:           r.Name,
 			PackagePath:    r.PackagePath,
 			ModulePath:     r.ModulePath,
@@ -62,15 +152,32 @@ func fetchSearchPage(ctx context.Context, db *postgres.DB, query string, pagePar
 			Licenses:       r.Licenses,
 			CommitTime:     elapsedTime(r.CommitTime),
 			NumImportedBy:  r.NumImportedBy,
-		})
+			IsModule:       r.IsModule,
+			ReadmeSection:  r.ReadmeSection,
+			ReadmeAnchor:   r.ReadmeAnchor,
+		}
+		for _, o := range r.OtherMajor {
+			sr.OtherMajor = append(sr.OtherMajor, &SearchResult{
+				PackagePath:    o.PackagePath,
+				ModulePath:     o.ModulePath,
+				DisplayVersion: displayVersion(o.Version, o.ModulePath),
+				IsModule:       o.IsModule,
+			})
+		}
+		if explanation != nil {
+			sr.Explanation = explanation.Results[i]
+		}
+		results = append(results, sr)
 	}
 
 	var (
 		numResults  int
 		approximate bool
+		suggestion  string
 	)
 	if len(dbresults) > 0 {
 		numResults = int(dbresults[0].NumResults)
+		suggestion = dbresults[0].Suggestion
 		if dbresults[0].Approximate {
 			// 128 buckets corresponds to a standard error of 10%.
 			// http://algo.inria.fr/flajolet/Publications/FlFuGaMe07.pdf
@@ -79,12 +186,86 @@ func fetchSearchPage(ctx context.Context, db *postgres.DB, query string, pagePar
 		}
 	}
 
+	// Symbols are shown only on the first page of results.
+	var symbols []*SymbolResult
+	if pageParams.offset() == 0 {
+		dbsymbols, err := db.SymbolSearch(ctx, query, filters, maxSymbolResults)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range dbsymbols {
+			symbols = append(symbols, &SymbolResult{
+				Name:           r.PackageName + "." + r.Anchor(),
+				Kind:           r.Kind,
+				Synopsis:       r.Synopsis,
+				PackagePath:    r.PackagePath,
+				DisplayVersion: displayVersion(r.Version, r.ModulePath),
+				NumImportedBy:  r.NumImportedBy,
+				Anchor:         r.Anchor(),
+			})
+		}
+	}
+
 	pgs := newPagination(pageParams, len(results), numResults)
 	pgs.Approximate = approximate
-	return &SearchPage{
-		Results:    results,
-		Pagination: pgs,
-	}, nil
+	if n := len(dbresults); n > 0 && dbresults[n-1].Cursor != nil {
+		pgs.setNextCursor(dbresults[n-1].Cursor.Encode())
+	}
+	page := &SearchPage{
+		Results:     results,
+		Symbols:     symbols,
+		Pagination:  pgs,
+		Suggestion:  suggestion,
+		Explanation: explanation,
+	}
+	if dbfacets != nil {
+		page.Facets = searchFacets(dbfacets, filters)
+		page.FacetsApproximate = dbfacets.Approximate
+	}
+	return page, nil
+}
+
+// searchFacets converts the facet counts of a search into refinements of that
+// search. Facets with fewer than two values are omitted, since they cannot
+// narrow down the results.
+func searchFacets(f *internal.SearchFacets, filters internal.SearchFilters) []*SearchFacet {
+	var facets []*SearchFacet
+	add := func(name, op string, values []*internal.FacetValue, label func(string) string, applied func(string) bool) {
+		if len(values) < 2 {
+			return
+		}
+		facet := &SearchFacet{Name: name}
+		for _, v := range values {
+			fv := &SearchFacetValue{Label: label(v.Value), Count: v.Count}
+			if !applied(v.Value) {
+				fv.Refinement = op + ":" + v.Value
+			}
+			facet.Values = append(facet.Values, fv)
+		}
+		facets = append(facets, facet)
+	}
+	identity := func(s string) string { return s }
+	isModuleFilter := func(s string) bool { return s == filters.ModulePath }
+	add("License", filterLicense, f.Licenses, identity, func(s string) bool {
+		for _, l := range filters.Licenses {
+			if l == s {
+				return true
+			}
+		}
+		return false
+	})
+	add("Host", filterModule, f.Hosts, identity, isModuleFilter)
+	add("Module", filterModule, f.Modules, identity, isModuleFilter)
+	add("Major version", filterMajor, f.Majors, identity, func(s string) bool { return s == filters.MajorVersion })
+	add("go.mod", filterGoMod, f.GoMod, func(s string) string {
+		if s == "true" {
+			return "Has go.mod"
+		}
+		return "No go.mod"
+	}, func(s string) bool {
+		return filters.HasGoMod != nil && strconv.FormatBool(*filters.HasGoMod) == s
+	})
+	return facets
 }
 
 // approximateNumber returns an approximation of the estimate, calibrated by
@@ -120,19 +301,62 @@ func (s *Server) serveSearch(w http.ResponseWriter, r *http.Request) error {
 		return nil
 	}
 
-	if path := searchRequestRedirectPath(ctx, s.ds, query); path != "" {
-		http.Redirect(w, r, path, http.StatusFound)
-		return nil
+	text, filters, err := parseSearchQuery(query)
+	if err != nil {
+		return &serverError{
+			status: http.StatusBadRequest,
+			epage: &errorPage{
+				messageTemplate: template.MakeTrustedTemplate(`
+					<h3 class="Error-message">{{.}}</h3>
+					<p class="Error-message">
+					  See <a href="/search-help">search help</a> for the supported search filters.
+					</p>`),
+				MessageData: err.Error(),
+			},
+			err: err,
+		}
+	}
+	// Only redirect if the query is a bare path, without filters.
+	if text == query {
+		if path := searchRequestRedirectPath(ctx, s.ds, query); path != "" {
+			http.Redirect(w, r, path, http.StatusFound)
+			return nil
+		}
+	}
+	// The explain parameter is "json" to get the explanation as JSON, or any
+	// other non-empty value to show it on the search page.
+	explain := r.FormValue("explain")
+	if !s.devMode && !experiment.IsActive(ctx, internal.ExperimentSearchExplain) {
+		explain = ""
 	}
-	page, err := fetchSearchPage(ctx, db, query, newPaginationParams(r, defaultSearchLimit))
+	page, err := fetchSearchPage(ctx, db, text, filters, newPaginationParams(r, defaultSearchLimit), explain != "")
 	if err != nil {
 		return fmt.Errorf("fetchSearchPage(ctx, db, %q): %v", query, err)
 	}
+	if explain == "json" {
+		return serveJSON(w, page.Explanation)
+	}
+	if page.Suggestion != "" {
+		page.Suggestion = replaceSearchText(query, page.Suggestion)
+	}
 	page.basePage = s.newBasePage(r, query)
 	s.servePage(ctx, w, "search.tmpl", page)
 	return nil
 }
 
+// serveJSON writes v to w as JSON.
+func serveJSON(w http.ResponseWriter, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %v", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("w.Write: %v", err)
+	}
+	return nil
+}
+
 // searchRequestRedirectPath returns the path that a search request should be
 // redirected to, or the empty string if there is no such path. If the user
 // types an existing package path into the search bar, we will redirect the
@@ -186,3 +410,101 @@ func searchRequestRedirectPath(ctx context.Context, ds internal.DataSource, quer
 func searchQuery(r *http.Request) string {
 	return strings.TrimSpace(r.FormValue("q"))
 }
+
+// Search filter operators. A search query term of the form "operator:value"
+// restricts the search results instead of being matched against the search
+// documents. See search_help.tmpl.
+const (
+	filterLicense     = "license"
+	filterModule      = "module"
+	filterImports     = "imports"
+	filterGoMod       = "gomod"
+	filterVersionType = "version-type"
+	filterGo          = "go"
+	filterMajor       = "major"
+)
+
+// parseSearchQuery separates the filter operators in a search query from the
+// text to be searched for. It returns the remaining search text and the
+// filters, or an error if a filter has an invalid value or there is no text
+// to search for, since filters only restrict the results of a search.
+//
+// The license operator may be repeated, to match packages that have any of the
+// given licenses. For the other operators, the last occurrence wins.
+func parseSearchQuery(query string) (text string, filters internal.SearchFilters, err error) {
+	var words []string
+	for _, word := range strings.Fields(query) {
+		op, val, ok := splitFilter(word)
+		if !ok {
+			// Not a filter, so search for it.
+			words = append(words, word)
+			continue
+		}
+		if val == "" {
+			return "", internal.SearchFilters{}, fmt.Errorf("missing value for search filter %q", op)
+		}
+		switch op {
+		case filterLicense:
+			filters.Licenses = append(filters.Licenses, val)
+		case filterModule:
+			filters.ModulePath = strings.TrimSuffix(val, "/")
+		case filterImports:
+			filters.Imports = val
+		case filterGoMod:
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return "", internal.SearchFilters{}, fmt.Errorf("invalid value %q for search filter %q: want true or false", val, op)
+			}
+			filters.HasGoMod = &b
+		case filterVersionType:
+			switch vt := version.Type(val); vt {
+			case version.TypeRelease, version.TypePrerelease, version.TypePseudo:
+				filters.VersionType = vt
+			default:
+				return "", internal.SearchFilters{}, fmt.Errorf("invalid value %q for search filter %q: want %s, %s or %s",
+					val, op, version.TypeRelease, version.TypePrerelease, version.TypePseudo)
+			}
+		case filterGo:
+			if !fetch.GoVersionRE.MatchString(val) {
+				return "", internal.SearchFilters{}, fmt.Errorf("invalid value %q for search filter %q: want a Go version like 1.14 or 1.21.5", val, op)
+			}
+			filters.GoVersion = val
+		case filterMajor:
+			if n, err := strconv.Atoi(strings.TrimPrefix(val, "v")); err != nil || n < 0 || val != fmt.Sprintf("v%d", n) {
+				return "", internal.SearchFilters{}, fmt.Errorf("invalid value %q for search filter %q: want a major version like v2", val, op)
+			}
+			filters.MajorVersion = val
+		}
+	}
+	if len(words) == 0 {
+		return "", internal.SearchFilters{}, errors.New("search text is required: filters only restrict the results of a search")
+	}
+	return strings.Join(words, " "), filters, nil
+}
+
+// splitFilter splits a word of a search query of the form "operator:value"
+// into its operator and value. It reports whether the word is a search filter.
+func splitFilter(word string) (op, val string, ok bool) {
+	i := strings.IndexByte(word, ':')
+	if i < 0 {
+		return "", "", false
+	}
+	op, val = word[:i], word[i+1:]
+	switch op {
+	case filterLicense, filterModule, filterImports, filterGoMod, filterVersionType, filterGo, filterMajor:
+		return op, val, true
+	}
+	return "", "", false
+}
+
+// replaceSearchText returns query with the text to be searched for replaced
+// by text, keeping its filters.
+func replaceSearchText(query, text string) string {
+	words := []string{text}
+	for _, word := range strings.Fields(query) {
+		if _, _, ok := splitFilter(word); ok {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
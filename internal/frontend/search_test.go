@@ -139,7 +139,7 @@ func TestFetchSearchPage(t *testing.T) {
 				}
 			}
 
-			got, err := fetchSearchPage(ctx, testDB, tc.query, paginationParams{limit: 20, page: 1})
+			got, err := fetchSearchPage(ctx, testDB, tc.query, internal.SearchFilters{}, paginationParams{limit: 20, page: 1}, false)
 			if err != nil {
 				t.Fatalf("fetchSearchPage(db, %q): %v", tc.query, err)
 			}
@@ -148,6 +148,7 @@ func TestFetchSearchPage(t *testing.T) {
 				cmp.AllowUnexported(SearchPage{}, pagination{}),
 				cmpopts.IgnoreFields(licenses.Metadata{}, "FilePath"),
 				cmpopts.IgnoreFields(pagination{}, "Approximate"),
+				cmpopts.IgnoreFields(SearchPage{}, "FacetsApproximate"),
 			}
 			if diff := cmp.Diff(tc.wantSearchPage, got, opts...); diff != "" {
 				t.Errorf("fetchSearchPage(db, %q) mismatch (-want +got):\n%s", tc.query, diff)
@@ -179,6 +180,124 @@ func TestApproximateNumber(t *testing.T) {
 	}
 }
 
+func TestParseSearchQuery(t *testing.T) {
+	hasGoMod := true
+	for _, test := range []struct {
+		query       string
+		wantText    string
+		wantFilters internal.SearchFilters
+	}{
+		{"yaml", "yaml", internal.SearchFilters{}},
+		{"  yaml   parser ", "yaml parser", internal.SearchFilters{}},
+		{
+			"yaml license:MIT license:Apache-2.0",
+			"yaml",
+			internal.SearchFilters{Licenses: []string{"MIT", "Apache-2.0"}},
+		},
+		{
+			"module:github.com/ourorg/ client gomod:true",
+			"client",
+			internal.SearchFilters{ModulePath: "github.com/ourorg", HasGoMod: &hasGoMod},
+		},
+		{
+			"imports:golang.org/x/net/context version-type:release http",
+			"http",
+			internal.SearchFilters{Imports: "golang.org/x/net/context", VersionType: version.TypeRelease},
+		},
+		{"go:1.13 http", "http", internal.SearchFilters{GoVersion: "1.13"}},
+		{"go:1.21.5 http", "http", internal.SearchFilters{GoVersion: "1.21.5"}},
+		{"major:v2 yaml", "yaml", internal.SearchFilters{MajorVersion: "v2"}},
+		{"unknown:operator", "unknown:operator", internal.SearchFilters{}},
+	} {
+		gotText, gotFilters, err := parseSearchQuery(test.query)
+		if err != nil {
+			t.Fatalf("parseSearchQuery(%q): %v", test.query, err)
+		}
+		if gotText != test.wantText {
+			t.Errorf("parseSearchQuery(%q): text = %q, want %q", test.query, gotText, test.wantText)
+		}
+		if diff := cmp.Diff(test.wantFilters, gotFilters); diff != "" {
+			t.Errorf("parseSearchQuery(%q): filters mismatch (-want +got):\n%s", test.query, diff)
+		}
+	}
+
+	for _, query := range []string{
+		"yaml license:",
+		"yaml gomod:maybe",
+		"yaml version-type:latest",
+		"yaml go:1.21rc1",
+		"yaml go:go1.13",
+		"yaml major:2",
+		"yaml major:v02",
+		"license:MIT",
+		"license:MIT gomod:true",
+	} {
+		if _, _, err := parseSearchQuery(query); err == nil {
+			t.Errorf("parseSearchQuery(%q): got nil error, want error", query)
+		}
+	}
+}
+
+func TestReplaceSearchText(t *testing.T) {
+	for _, test := range []struct {
+		query, text, want string
+	}{
+		{"kubernets", "kubernetes", "kubernetes"},
+		{"yaml.v license:MIT gomod:true", "yaml", "yaml license:MIT gomod:true"},
+		{"module:github.com/ourorg clinet", "client", "client module:github.com/ourorg"},
+	} {
+		if got := replaceSearchText(test.query, test.text); got != test.want {
+			t.Errorf("replaceSearchText(%q, %q) = %q, want %q", test.query, test.text, got, test.want)
+		}
+	}
+}
+
+func TestSearchFacets(t *testing.T) {
+	hasGoMod := true
+	facets := &internal.SearchFacets{
+		Licenses: []*internal.FacetValue{{Value: "MIT", Count: 5}, {Value: "Apache-2.0", Count: 3}},
+		Hosts:    []*internal.FacetValue{{Value: "github.com", Count: 8}},
+		Modules:  []*internal.FacetValue{{Value: "github.com/a/b", Count: 6}, {Value: "github.com/c/d", Count: 2}},
+		Majors:   []*internal.FacetValue{{Value: "v1", Count: 5}, {Value: "v2", Count: 3}},
+		GoMod:    []*internal.FacetValue{{Value: "true", Count: 7}, {Value: "false", Count: 1}},
+	}
+	filters := internal.SearchFilters{Licenses: []string{"MIT"}, HasGoMod: &hasGoMod, MajorVersion: "v2"}
+	want := []*SearchFacet{
+		{
+			Name: "License",
+			Values: []*SearchFacetValue{
+				{Label: "MIT", Count: 5},
+				{Label: "Apache-2.0", Count: 3, Refinement: "license:Apache-2.0"},
+			},
+		},
+		{
+			Name: "Module",
+			Values: []*SearchFacetValue{
+				{Label: "github.com/a/b", Count: 6, Refinement: "module:github.com/a/b"},
+				{Label: "github.com/c/d", Count: 2, Refinement: "module:github.com/c/d"},
+			},
+		},
+		{
+			Name: "Major version",
+			Values: []*SearchFacetValue{
+				{Label: "v1", Count: 5, Refinement: "major:v1"},
+				{Label: "v2", Count: 3},
+			},
+		},
+		{
+			Name: "go.mod",
+			Values: []*SearchFacetValue{
+				{Label: "Has go.mod", Count: 7},
+				{Label: "No go.mod", Count: 1, Refinement: "gomod:false"},
+			},
+		},
+	}
+	got := searchFacets(facets, filters)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("searchFacets mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestSearchRequestRedirectPath(t *testing.T) {
 	t.Run("no experiments ", func(t *testing.T) {
 		testSearchRequestRedirectPath(t)
@@ -40,6 +40,9 @@ type Server struct {
 	devMode              bool
 	errorPage            []byte
 	appVersionLabel      string
+	// previews holds uploaded modules for preview. It is nil if previews are
+	// disabled.
+	previews *previewStore
 
 	mu        sync.Mutex // Protects all fields below
 	templates map[string]*template.Template
@@ -55,6 +58,9 @@ type ServerConfig struct {
 	ThirdPartyPath       string
 	DevMode              bool
 	AppVersionLabel      string
+	// EnablePreview enables the /preview/ endpoint, which renders uploaded
+	// module zips.
+	EnablePreview bool
 }
 
 // NewServer creates a new Server for the given database and template directory.
@@ -82,13 +88,18 @@ func NewServer(scfg ServerConfig) (_ *Server, err error) {
 		return nil, fmt.Errorf("s.renderErrorPage(http.StatusInternalServerError, nil): %v", err)
 	}
 	s.errorPage = errorPageBytes
+	if scfg.EnablePreview {
+		s.previews = newPreviewStore()
+	}
 	return s, nil
 }
 
 // Install registers server routes using the given handler registration func.
 func (s *Server) Install(handle func(string, http.Handler), redisClient *redis.Client) {
 	var (
-		detailHandler http.Handler = s.errorHandler(s.serveDetails)
+		detailHandler http.Handler = s.errorHandler(func(w http.ResponseWriter, r *http.Request) error {
+			return s.serveDetails(w, r, s.ds, "")
+		})
 		fetchHandler  http.Handler = s.errorHandler(s.serveFetch)
 		searchHandler http.Handler = s.errorHandler(s.serveSearch)
 	)
@@ -106,6 +117,9 @@ func (s *Server) Install(handle func(string, http.Handler), redisClient *redis.C
 	handle("/search", searchHandler)
 	handle("/search-help", s.staticPageHandler("search_help.tmpl", "Search Help - go.dev"))
 	handle("/license-policy", s.licensePolicyHandler())
+	if s.previews != nil {
+		handle("/preview/", s.errorHandler(s.servePreview))
+	}
 	handle("/about", http.RedirectHandler("https://go.dev/about", http.StatusFound))
 	handle("/", detailHandler)
 	handle("/autocomplete", http.HandlerFunc(s.handleAutoCompletion))
@@ -415,12 +429,16 @@ func parsePageTemplates(base template.TrustedSource) (map[string]*template.Templ
 		{tsc("search.tmpl")},
 		{tsc("search_help.tmpl")},
 		{tsc("license_policy.tmpl")},
+		{tsc("pkg_api_diff.tmpl")},
+		{tsc("preview.tmpl")},
 		{tsc("overview.tmpl"), tsc("details.tmpl")},
 		{tsc("subdirectories.tmpl"), tsc("details.tmpl")},
 		{tsc("pkg_doc.tmpl"), tsc("details.tmpl")},
 		{tsc("pkg_importedby.tmpl"), tsc("details.tmpl")},
 		{tsc("pkg_imports.tmpl"), tsc("details.tmpl")},
 		{tsc("licenses.tmpl"), tsc("details.tmpl")},
+		{tsc("module_dependencies.tmpl"), tsc("details.tmpl")},
+		{tsc("module_dependents.tmpl"), tsc("details.tmpl")},
 		{tsc("versions.tmpl"), tsc("details.tmpl")},
 		{tsc("not_implemented.tmpl"), tsc("details.tmpl")},
 	}
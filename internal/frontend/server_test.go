@@ -21,6 +21,7 @@ import (
 	"golang.org/x/net/html"
 	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/experiment"
+	"golang.org/x/pkgsite/internal/fetch"
 	"golang.org/x/pkgsite/internal/middleware"
 	"golang.org/x/pkgsite/internal/postgres"
 	"golang.org/x/pkgsite/internal/proxy"
@@ -742,6 +743,22 @@ func serverTestCases() ([]serverTestCase, []serverTestCase, []serverTestCase) {
 						attr("title", "v1.0.0"),
 						text("v1.0.0")))),
 		},
+		{
+			name:           "module at version dependencies tab",
+			urlPath:        fmt.Sprintf("/mod/%s@%s?tab=dependencies", sample.ModulePath, sample.VersionString),
+			wantStatusCode: http.StatusOK,
+			want: in("",
+				pagecheck.ModuleHeader(mod, versioned),
+				in(".EmptyContent-message", text(`This module does not have any dependencies`))),
+		},
+		{
+			name:           "module dependents tab",
+			urlPath:        fmt.Sprintf("/mod/%s@%s?tab=dependents", sample.ModulePath, sample.VersionString),
+			wantStatusCode: http.StatusOK,
+			want: in("",
+				pagecheck.ModuleHeader(mod, versioned),
+				in(".EmptyContent-message", text(`No known dependents for this module`))),
+		},
 		{
 			name:           "module at version licenses tab",
 			urlPath:        fmt.Sprintf("/mod/%s@%s?tab=licenses", sample.ModulePath, sample.VersionString),
@@ -992,7 +1009,7 @@ func newTestServer(t *testing.T, proxyModules []*proxy.TestModule, experimentNam
 
 	q := queue.NewInMemory(ctx, 1, experimentNames,
 		func(ctx context.Context, mpath, version string) (int, error) {
-			return FetchAndUpdateState(ctx, mpath, version, proxyClient, sourceClient, testDB)
+			return FetchAndUpdateState(ctx, mpath, version, proxyClient, sourceClient, fetch.Options{}, testDB)
 		})
 
 	s, err := NewServer(ServerConfig{
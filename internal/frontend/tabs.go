@@ -103,6 +103,18 @@ var (
 			DisplayName:       "Versions",
 			TemplateName:      "versions.tmpl",
 		},
+		{
+			Name:              "dependencies",
+			AlwaysShowDetails: true,
+			DisplayName:       "Dependencies",
+			TemplateName:      "module_dependencies.tmpl",
+		},
+		{
+			Name:              "dependents",
+			AlwaysShowDetails: true,
+			DisplayName:       "Dependents",
+			TemplateName:      "module_dependents.tmpl",
+		},
 		{
 			Name:         "licenses",
 			DisplayName:  "Licenses",
@@ -142,27 +154,27 @@ func init() {
 
 // fetchDetailsForPackage returns tab details by delegating to the correct detail
 // handler.
-func fetchDetailsForPackage(ctx context.Context, r *http.Request, tab string, ds internal.DataSource, pkg *internal.LegacyVersionedPackage) (interface{}, error) {
+func fetchDetailsForPackage(ctx context.Context, r *http.Request, tab string, ds internal.DataSource, urlPrefix string, pkg *internal.LegacyVersionedPackage) (interface{}, error) {
 	switch tab {
 	case "doc":
-		return fetchDocumentationDetails(pkg), nil
+		return fetchDocumentationDetails(r, pkg), nil
 	case "versions":
-		return fetchPackageVersionsDetails(ctx, ds, pkg.Path, pkg.V1Path, pkg.ModulePath)
+		return fetchPackageVersionsDetails(ctx, ds, urlPrefix, pkg.Path, pkg.V1Path, pkg.ModulePath)
 	case "subdirectories":
-		return fetchDirectoryDetails(ctx, ds, pkg.Path, &pkg.ModuleInfo, pkg.Licenses, false)
+		return fetchDirectoryDetails(ctx, ds, urlPrefix, pkg.Path, &pkg.ModuleInfo, pkg.Licenses, false)
 	case "imports":
-		return fetchImportsDetails(ctx, ds, pkg.Path, pkg.ModulePath, pkg.Version)
+		return fetchImportsDetails(ctx, ds, urlPrefix, pkg.Path, pkg.ModulePath, pkg.Version)
 	case "importedby":
 		db, ok := ds.(*postgres.DB)
 		if !ok {
 			// The proxydatasource does not support the imported by page.
 			return nil, proxydatasourceNotSupportedErr()
 		}
-		return fetchImportedByDetails(ctx, db, pkg.Path, pkg.ModulePath)
+		return fetchImportedByDetails(ctx, db, pkg.Path, pkg.ModulePath, newPaginationParams(r, importedByPageSize))
 	case "licenses":
 		return fetchPackageLicensesDetails(ctx, ds, pkg.Path, pkg.ModulePath, pkg.Version)
 	case "overview":
-		return fetchPackageOverviewDetails(ctx, pkg, urlIsVersioned(r.URL))
+		return fetchPackageOverviewDetails(ctx, pkg, urlIsVersioned(r.URL), urlPrefix)
 	}
 	return nil, fmt.Errorf("BUG: unable to fetch details: unknown tab %q", tab)
 }
@@ -170,27 +182,27 @@ func fetchDetailsForPackage(ctx context.Context, r *http.Request, tab string, ds
 // fetchDetailsForVersionedDirectory returns tab details by delegating to the correct detail
 // handler.
 func fetchDetailsForVersionedDirectory(ctx context.Context, r *http.Request, tab string,
-	ds internal.DataSource, vdir *internal.VersionedDirectory) (interface{}, error) {
+	ds internal.DataSource, urlPrefix string, vdir *internal.VersionedDirectory) (interface{}, error) {
 	switch tab {
 	case "doc":
-		return fetchDocumentationDetailsNew(vdir.Package.Documentation), nil
+		return fetchDocumentationDetailsNew(r, vdir.Package), nil
 	case "versions":
-		return fetchPackageVersionsDetails(ctx, ds, vdir.Path, vdir.V1Path, vdir.ModulePath)
+		return fetchPackageVersionsDetails(ctx, ds, urlPrefix, vdir.Path, vdir.V1Path, vdir.ModulePath)
 	case "subdirectories":
-		return fetchDirectoryDetails(ctx, ds, vdir.Path, &vdir.ModuleInfo, vdir.Licenses, false)
+		return fetchDirectoryDetails(ctx, ds, urlPrefix, vdir.Path, &vdir.ModuleInfo, vdir.Licenses, false)
 	case "imports":
-		return fetchImportsDetails(ctx, ds, vdir.Path, vdir.ModulePath, vdir.Version)
+		return fetchImportsDetails(ctx, ds, urlPrefix, vdir.Path, vdir.ModulePath, vdir.Version)
 	case "importedby":
 		db, ok := ds.(*postgres.DB)
 		if !ok {
 			// The proxydatasource does not support the imported by page.
 			return nil, proxydatasourceNotSupportedErr()
 		}
-		return fetchImportedByDetails(ctx, db, vdir.Path, vdir.ModulePath)
+		return fetchImportedByDetails(ctx, db, vdir.Path, vdir.ModulePath, newPaginationParams(r, importedByPageSize))
 	case "licenses":
 		return fetchPackageLicensesDetails(ctx, ds, vdir.Path, vdir.ModulePath, vdir.Version)
 	case "overview":
-		return fetchPackageOverviewDetailsNew(ctx, vdir, urlIsVersioned(r.URL))
+		return fetchPackageOverviewDetailsNew(ctx, vdir, urlIsVersioned(r.URL), urlPrefix)
 	}
 	return nil, fmt.Errorf("BUG: unable to fetch details: unknown tab %q", tab)
 }
@@ -201,35 +213,49 @@ func urlIsVersioned(url *url.URL) bool {
 
 // fetchDetailsForModule returns tab details by delegating to the correct detail
 // handler.
-func fetchDetailsForModule(ctx context.Context, r *http.Request, tab string, ds internal.DataSource, mi *internal.LegacyModuleInfo, licenses []*licenses.License) (interface{}, error) {
+func fetchDetailsForModule(ctx context.Context, r *http.Request, tab string, ds internal.DataSource, urlPrefix string, mi *internal.LegacyModuleInfo, licenses []*licenses.License) (interface{}, error) {
 	switch tab {
 	case "packages":
-		return fetchDirectoryDetails(ctx, ds, mi.ModulePath, &mi.ModuleInfo, licensesToMetadatas(licenses), true)
+		return fetchDirectoryDetails(ctx, ds, urlPrefix, mi.ModulePath, &mi.ModuleInfo, licensesToMetadatas(licenses), true)
 	case "licenses":
 		return &LicensesDetails{Licenses: transformLicenses(mi.ModulePath, mi.Version, licenses)}, nil
 	case "versions":
-		return fetchModuleVersionsDetails(ctx, ds, &mi.ModuleInfo)
+		return fetchModuleVersionsDetails(ctx, ds, urlPrefix, &mi.ModuleInfo)
+	case "dependencies":
+		db, ok := ds.(*postgres.DB)
+		if !ok {
+			// The proxydatasource does not support the dependencies page.
+			return nil, proxydatasourceNotSupportedErr()
+		}
+		return fetchDependenciesDetails(ctx, db, mi.ModulePath, mi.Version)
+	case "dependents":
+		db, ok := ds.(*postgres.DB)
+		if !ok {
+			// The proxydatasource does not support the dependents page.
+			return nil, proxydatasourceNotSupportedErr()
+		}
+		return fetchDependentsDetails(ctx, db, mi.ModulePath)
 	case "overview":
 		readme := &internal.Readme{Filepath: mi.LegacyReadmeFilePath, Contents: mi.LegacyReadmeContents}
-		return constructOverviewDetails(ctx, &mi.ModuleInfo, readme, mi.IsRedistributable, urlIsVersioned(r.URL))
+		return constructOverviewDetails(ctx, &mi.ModuleInfo, readme, mi.IsRedistributable, urlIsVersioned(r.URL), urlPrefix)
 	}
 	return nil, fmt.Errorf("BUG: unable to fetch details: unknown tab %q", tab)
 }
 
 // constructDetailsForDirectory returns tab details by delegating to the correct
 // detail handler.
-func constructDetailsForDirectory(r *http.Request, tab string, dir *internal.LegacyDirectory, licenses []*licenses.License) (interface{}, error) {
+func constructDetailsForDirectory(r *http.Request, tab string, dir *internal.LegacyDirectory, licenses []*licenses.License, urlPrefix string) (interface{}, error) {
 	switch tab {
 	case "overview":
 		readme := &internal.Readme{Filepath: dir.LegacyReadmeFilePath, Contents: dir.LegacyReadmeContents}
-		return constructOverviewDetails(r.Context(), &dir.ModuleInfo, readme, dir.LegacyModuleInfo.IsRedistributable, urlIsVersioned(r.URL))
+		return constructOverviewDetails(r.Context(), &dir.ModuleInfo, readme, dir.LegacyModuleInfo.IsRedistributable, urlIsVersioned(r.URL), urlPrefix)
 	case "subdirectories":
 		// Ideally we would just use fetchDirectoryDetails here so that it
 		// follows the same code path as fetchDetailsForModule and
 		// fetchDetailsForPackage. However, since we already have the directory
 		// and licenses info, it doesn't make sense to call
 		// postgres.GetDirectory again.
-		return legacyCreateDirectory(dir, licensesToMetadatas(licenses), false)
+		return legacyCreateDirectory(dir, licensesToMetadatas(licenses), false, urlPrefix)
 	case "licenses":
 		return &LicensesDetails{Licenses: transformLicenses(dir.ModulePath, dir.Version, licenses)}, nil
 	}
@@ -59,11 +59,19 @@ type VersionSummary struct {
 	CommitTime     string
 	// Link to this version, for use in the anchor href.
 	Link string
+	// Retracted reports whether the version is retracted, for the reason in
+	// RetractionRationale.
+	Retracted           bool
+	RetractionRationale string
+	// CompareLink is a link to the changes in the API of the package between
+	// this version and the latest one in its list. It is empty for the latest
+	// version and on module pages.
+	CompareLink string
 }
 
 // fetchModuleVersionsDetails builds a version hierarchy for module versions
 // with the same series path as the given version.
-func fetchModuleVersionsDetails(ctx context.Context, ds internal.DataSource, mi *internal.ModuleInfo) (*VersionsDetails, error) {
+func fetchModuleVersionsDetails(ctx context.Context, ds internal.DataSource, urlPrefix string, mi *internal.ModuleInfo) (*VersionsDetails, error) {
 	versions, err := ds.GetTaggedVersionsForModule(ctx, mi.ModulePath)
 	if err != nil {
 		return nil, err
@@ -77,14 +85,14 @@ func fetchModuleVersionsDetails(ctx context.Context, ds internal.DataSource, mi
 		}
 	}
 	linkify := func(m *internal.ModuleInfo) string {
-		return constructModuleURL(m.ModulePath, linkVersion(m.Version, m.ModulePath))
+		return urlPrefix + constructModuleURL(m.ModulePath, linkVersion(m.Version, m.ModulePath))
 	}
-	return buildVersionDetails(mi.ModulePath, versions, linkify), nil
+	return buildVersionDetails(mi.ModulePath, versions, linkify, nil), nil
 }
 
 // fetchPackageVersionsDetails builds a version hierarchy for all module
 // versions containing a package path with v1 import path matching the given v1 path.
-func fetchPackageVersionsDetails(ctx context.Context, ds internal.DataSource, pkgPath, v1Path, modulePath string) (*VersionsDetails, error) {
+func fetchPackageVersionsDetails(ctx context.Context, ds internal.DataSource, urlPrefix, pkgPath, v1Path, modulePath string) (*VersionsDetails, error) {
 	versions, err := ds.GetTaggedVersionsForPackageSeries(ctx, pkgPath)
 	if err != nil {
 		return nil, err
@@ -98,18 +106,22 @@ func fetchPackageVersionsDetails(ctx context.Context, ds internal.DataSource, pk
 		}
 	}
 
-	linkify := func(mi *internal.ModuleInfo) string {
-		// Here we have only version information, but need to construct the full
-		// import path of the package corresponding to this version.
-		var versionPath string
+	// Here we have only version information, but need to construct the full
+	// import path of the package corresponding to this version.
+	versionPath := func(mi *internal.ModuleInfo) string {
 		if mi.ModulePath == stdlib.ModulePath {
-			versionPath = pkgPath
-		} else {
-			versionPath = pathInVersion(v1Path, mi)
+			return pkgPath
 		}
-		return constructPackageURL(versionPath, mi.ModulePath, linkVersion(mi.Version, mi.ModulePath))
+		return pathInVersion(v1Path, mi)
+	}
+	linkify := func(mi *internal.ModuleInfo) string {
+		return urlPrefix + constructPackageURL(versionPath(mi), mi.ModulePath, linkVersion(mi.Version, mi.ModulePath))
+	}
+	compareLinkify := func(from, to *internal.ModuleInfo) string {
+		return fmt.Sprintf("%s/%s@%s%s%s", urlPrefix, versionPath(from),
+			linkVersion(from.Version, from.ModulePath), apiDiffSeparator, linkVersion(to.Version, to.ModulePath))
 	}
-	return buildVersionDetails(modulePath, versions, linkify), nil
+	return buildVersionDetails(modulePath, versions, linkify, compareLinkify), nil
 }
 
 // pathInVersion constructs the full import path of the package corresponding
@@ -136,7 +148,10 @@ func pathInVersion(v1Path string, mi *internal.ModuleInfo) string {
 // versions tab, organizing major versions into those that have the same module
 // path as the package version under consideration, and those that don't.  The
 // given versions MUST be sorted first by module path and then by semver.
-func buildVersionDetails(currentModulePath string, modInfos []*internal.ModuleInfo, linkify func(v *internal.ModuleInfo) string) *VersionsDetails {
+//
+// If compareLinkify is non-nil, it is used to link each version to a comparison
+// with the latest version in its list.
+func buildVersionDetails(currentModulePath string, modInfos []*internal.ModuleInfo, linkify func(v *internal.ModuleInfo) string, compareLinkify func(from, to *internal.ModuleInfo) string) *VersionsDetails {
 
 	// lists organizes versions by VersionListKey. Note that major version isn't
 	// sufficient as a key: there are packages contained in the same major
@@ -147,6 +162,8 @@ func buildVersionDetails(currentModulePath string, modInfos []*internal.ModuleIn
 	// seenLists tracks the order in which we encounter entries of each version
 	// list. We want to preserve this order.
 	var seenLists []VersionListKey
+	// latest holds the first version encountered in each version list.
+	latest := make(map[VersionListKey]*internal.ModuleInfo)
 	for _, mi := range modInfos {
 		// Try to resolve the most appropriate major version for this version. If
 		// we detect a +incompatible version (when the path version does not match
@@ -178,13 +195,18 @@ func buildVersionDetails(currentModulePath string, modInfos []*internal.ModuleIn
 			ttversion = fmtVersion // tooltips will show the Go tag
 		}
 		vs := &VersionSummary{
-			TooltipVersion: ttversion,
-			Link:           linkify(mi),
-			CommitTime:     elapsedTime(mi.CommitTime),
-			DisplayVersion: fmtVersion,
+			TooltipVersion:      ttversion,
+			Link:                linkify(mi),
+			CommitTime:          elapsedTime(mi.CommitTime),
+			DisplayVersion:      fmtVersion,
+			Retracted:           mi.Retracted,
+			RetractionRationale: mi.RetractionRationale,
 		}
 		if _, ok := lists[key]; !ok {
 			seenLists = append(seenLists, key)
+			latest[key] = mi
+		} else if compareLinkify != nil {
+			vs.CompareLink = compareLinkify(mi, latest[key])
 		}
 		lists[key] = append(lists[key], vs)
 	}
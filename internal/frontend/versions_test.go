@@ -115,6 +115,31 @@ func TestFetchModuleVersionDetails(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "retracted versions",
+			info: info1,
+			modules: []*internal.Module{
+				sampleModule(modulePath1, "v1.2.1", version.TypeRelease),
+				sampleModule(modulePath1, "v1.2.3", version.TypeRelease),
+				func() *internal.Module {
+					m := sampleModule(modulePath1, "v1.3.0", version.TypeRelease)
+					m.GoModRetractions = []*internal.Retraction{
+						{Low: "v1.2.2", High: "v1.2.9", Rationale: "Broken build."},
+					}
+					return m
+				}(),
+			},
+			wantDetails: &VersionsDetails{
+				ThisModule: []*VersionList{
+					func() *VersionList {
+						vl := makeList("test.com/module", "v1", []string{"v1.3.0", "v1.2.3", "v1.2.1"})
+						vl.Versions[1].Retracted = true
+						vl.Versions[1].RetractionRationale = "Broken build."
+						return vl
+					}(),
+				},
+			},
+		},
 		{
 			name: "want only pseudo",
 			info: info2,
@@ -141,7 +166,7 @@ func TestFetchModuleVersionDetails(t *testing.T) {
 				}
 			}
 
-			got, err := fetchModuleVersionsDetails(ctx, testDB, tc.info)
+			got, err := fetchModuleVersionsDetails(ctx, testDB, "", tc.info)
 			if err != nil {
 				t.Fatalf("fetchModuleVersionsDetails(ctx, db, %v): %v", tc.info, err)
 			}
@@ -174,11 +199,15 @@ func TestFetchPackageVersionsDetails(t *testing.T) {
 		LegacyPackage:    *sample.LegacyPackage("std", "net/http"),
 	}
 	makeList := func(pkgPath, modulePath, major string, versions []string) *VersionList {
+		vs := versionSummaries(pkgPath, versions, func(path, version string) string {
+			return constructPackageURL(pkgPath, modulePath, version)
+		})
+		for i := 1; i < len(vs); i++ {
+			vs[i].CompareLink = "/" + pkgPath + "@" + versions[i] + "..." + versions[0]
+		}
 		return &VersionList{
 			VersionListKey: VersionListKey{ModulePath: modulePath, Major: major},
-			Versions: versionSummaries(pkgPath, versions, func(path, version string) string {
-				return constructPackageURL(pkgPath, modulePath, version)
-			}),
+			Versions:       vs,
 		}
 	}
 
@@ -269,7 +298,7 @@ func TestFetchPackageVersionsDetails(t *testing.T) {
 				}
 			}
 
-			got, err := fetchPackageVersionsDetails(ctx, testDB, tc.pkg.Path, tc.pkg.V1Path, tc.pkg.ModulePath)
+			got, err := fetchPackageVersionsDetails(ctx, testDB, "", tc.pkg.Path, tc.pkg.V1Path, tc.pkg.ModulePath)
 			if err != nil {
 				t.Fatalf("fetchPackageVersionsDetails(ctx, db, %v): %v", tc.pkg, err)
 			}
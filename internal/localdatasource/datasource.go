@@ -0,0 +1,346 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package localdatasource implements an internal.DataSource backed by modules
+// in directories on the local filesystem, for previewing documentation the way
+// "godoc -http" does, or by modules that have already been processed in
+// memory.
+package localdatasource
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/datasource"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/fetch"
+	"golang.org/x/pkgsite/internal/log"
+)
+
+var _ internal.DataSource = (*DataSource)(nil)
+
+// checkInterval is the minimum time between checks of a module directory for
+// changes. It is a variable for testing.
+var checkInterval = time.Second
+
+// DataSource implements the internal.DataSource interface, by reading modules
+// from local directories and caching the results in memory. Modules are read
+// when they are first requested, and read again when any of their files
+// change.
+//
+// Each module has a single version: fetch.LocalVersion for modules read from
+// directories, and the version of the module for modules added with
+// AddModule. Requests for any other version, except for the latest version,
+// fail with derrors.NotFound.
+type DataSource struct {
+	*datasource.ModuleDataSource
+
+	// gopaths are GOPATH roots in which packages that are not in a module
+	// directory are looked up.
+	gopaths []string
+
+	// mu guards the maps below.
+	mu sync.Mutex
+	// dirs maps module paths to the directories containing them.
+	dirs map[string]string
+	// modules maps module paths to the modules added with AddModule.
+	modules map[string]*internal.Module
+	// loaded maps module paths in dirs to the result of reading them.
+	loaded map[string]*loadedModule
+}
+
+// loadedModule holds the result of a call to fetch.FetchLocalModule.
+type loadedModule struct {
+	// mu guards the fields below. It is held while the module is read, so
+	// that a module is read only once at a time without blocking requests
+	// for other modules.
+	mu     sync.Mutex
+	module *internal.Module
+	err    error
+	// stamp summarizes the files of the module when it was read.
+	stamp dirStamp
+	// checked is the last time that the module directory was compared to
+	// stamp.
+	checked time.Time
+}
+
+// New returns a new local datasource, which looks up packages that are not in
+// a module added with AddModuleDir in the given GOPATH roots.
+func New(gopaths ...string) *DataSource {
+	ds := &DataSource{
+		gopaths: gopaths,
+		dirs:    make(map[string]string),
+		modules: make(map[string]*internal.Module),
+		loaded:  make(map[string]*loadedModule),
+	}
+	ds.ModuleDataSource = datasource.NewModuleDataSource(ds.getModule, ds.getModuleForPath)
+	return ds
+}
+
+// AddModuleDir adds the module in the directory dir, which must contain a
+// go.mod file, to the datasource.
+func (ds *DataSource) AddModuleDir(dir string) (err error) {
+	defer derrors.Wrap(&err, "AddModuleDir(%q)", dir)
+
+	modulePath, err := readModulePath(dir)
+	if err != nil {
+		return err
+	}
+	if modulePath == "" {
+		return fmt.Errorf("no go.mod file: %w", derrors.NotFound)
+	}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.dirs[modulePath] = dir
+	return nil
+}
+
+// AddModule adds a module that has already been processed, such as one that
+// was uploaded for preview, to the datasource. It is served as is.
+func (ds *DataSource) AddModule(m *internal.Module) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.modules[m.ModulePath] = m
+}
+
+// readModulePath returns the module path in the go.mod file in dir, or the
+// empty string if there is no go.mod file.
+func readModulePath(dir string) (string, error) {
+	goMod, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	modulePath := modfile.ModulePath(goMod)
+	if modulePath == "" {
+		return "", fmt.Errorf("%s: go.mod has no module path: %w", dir, derrors.BadModule)
+	}
+	return modulePath, nil
+}
+
+// GetPseudoVersionsForModule returns nil: local modules have no
+// pseudo-versions.
+func (ds *DataSource) GetPseudoVersionsForModule(ctx context.Context, modulePath string) ([]*internal.ModuleInfo, error) {
+	return nil, nil
+}
+
+// GetPseudoVersionsForPackageSeries returns nil: local modules have no
+// pseudo-versions.
+func (ds *DataSource) GetPseudoVersionsForPackageSeries(ctx context.Context, pkgPath string) ([]*internal.ModuleInfo, error) {
+	return nil, nil
+}
+
+// GetTaggedVersionsForModule returns the single version of the module with
+// modulePath.
+func (ds *DataSource) GetTaggedVersionsForModule(ctx context.Context, modulePath string) (_ []*internal.ModuleInfo, err error) {
+	defer derrors.Wrap(&err, "GetTaggedVersionsForModule(%q)", modulePath)
+
+	m, err := ds.getModule(ctx, modulePath, internal.LatestVersion)
+	if err != nil {
+		return nil, err
+	}
+	return []*internal.ModuleInfo{&m.ModuleInfo}, nil
+}
+
+// GetTaggedVersionsForPackageSeries returns the single version of the module
+// containing pkgPath.
+func (ds *DataSource) GetTaggedVersionsForPackageSeries(ctx context.Context, pkgPath string) (_ []*internal.ModuleInfo, err error) {
+	defer derrors.Wrap(&err, "GetTaggedVersionsForPackageSeries(%q)", pkgPath)
+
+	m, err := ds.getModuleForPath(ctx, pkgPath, internal.UnknownModulePath, internal.LatestVersion)
+	if err != nil {
+		return nil, err
+	}
+	return []*internal.ModuleInfo{&m.ModuleInfo}, nil
+}
+
+// getModuleForPath returns the module with modulePath, or if it is
+// internal.UnknownModulePath, the module containing the package or directory
+// with the given path.
+func (ds *DataSource) getModuleForPath(ctx context.Context, fullPath, modulePath, version string) (*internal.Module, error) {
+	if modulePath == internal.UnknownModulePath {
+		var err error
+		modulePath, err = ds.findModule(fullPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ds.getModule(ctx, modulePath, version)
+}
+
+// findModule returns the path of the module containing fullPath. It prefers
+// the modules added with AddModule and AddModuleDir, using the longest module
+// path that contains fullPath, and otherwise looks for fullPath in the GOPATH
+// roots.
+//
+// In a GOPATH root, the module is the one in the nearest directory with a
+// go.mod file that contains fullPath. If there is no such directory, the
+// directory of fullPath is treated as a module with path fullPath, provided
+// that it contains Go files.
+func (ds *DataSource) findModule(fullPath string) (_ string, err error) {
+	defer derrors.Wrap(&err, "findModule(%q)", fullPath)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	var modulePath string
+	longest := func(mp string) {
+		if (fullPath == mp || strings.HasPrefix(fullPath, mp+"/")) && len(mp) > len(modulePath) {
+			modulePath = mp
+		}
+	}
+	for mp := range ds.dirs {
+		longest(mp)
+	}
+	for mp := range ds.modules {
+		longest(mp)
+	}
+	if modulePath != "" {
+		return modulePath, nil
+	}
+	for _, root := range ds.gopaths {
+		src := filepath.Join(root, "src")
+		dir := filepath.Join(src, filepath.FromSlash(fullPath))
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			continue
+		}
+		for d, p := dir, fullPath; p != "." && p != "/"; d, p = filepath.Dir(d), path.Dir(p) {
+			mp, err := readModulePath(d)
+			if err != nil {
+				return "", err
+			}
+			if mp != "" {
+				ds.dirs[mp] = d
+				return mp, nil
+			}
+		}
+		if hasGoFiles(dir) {
+			ds.dirs[fullPath] = dir
+			return fullPath, nil
+		}
+	}
+	return "", fmt.Errorf("unable to find module: %w", derrors.NotFound)
+}
+
+// hasGoFiles reports whether dir contains any .go files.
+func hasGoFiles(dir string) bool {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), ".go") {
+			return true
+		}
+	}
+	return false
+}
+
+// getModule returns the module with modulePath. Modules in directories are
+// read again if their files have changed since they were last read. Only
+// the module being read is locked while it is read.
+func (ds *DataSource) getModule(ctx context.Context, modulePath, version string) (_ *internal.Module, err error) {
+	defer derrors.Wrap(&err, "getModule(%q, %q)", modulePath, version)
+
+	m, dir, lm, err := ds.lookupModule(modulePath, version)
+	if err != nil || m != nil {
+		return m, err
+	}
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	if !lm.checked.IsZero() && time.Since(lm.checked) < checkInterval {
+		return lm.module, lm.err
+	}
+	stamp, err := stampDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !lm.checked.IsZero() {
+		if lm.stamp == stamp {
+			lm.checked = time.Now()
+			return lm.module, lm.err
+		}
+		log.Infof(ctx, "reloading %s from %s", modulePath, dir)
+	}
+	res := fetch.FetchLocalModule(ctx, modulePath, dir)
+	lm.module = res.Module
+	lm.err = res.Error
+	lm.stamp = stamp
+	lm.checked = time.Now()
+	return lm.module, lm.err
+}
+
+// lookupModule returns the module with modulePath if it was added with
+// AddModule. Otherwise it returns the directory of the module and its
+// loadedModule, which is created if the module has not been read yet.
+func (ds *DataSource) lookupModule(modulePath, version string) (_ *internal.Module, dir string, lm *loadedModule, err error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if m, ok := ds.modules[modulePath]; ok {
+		if version != internal.LatestVersion && version != m.Version {
+			return nil, "", nil, fmt.Errorf("only version %s is available: %w", m.Version, derrors.NotFound)
+		}
+		return m, "", nil, nil
+	}
+	if version != internal.LatestVersion && version != fetch.LocalVersion {
+		return nil, "", nil, fmt.Errorf("only version %s is available: %w", fetch.LocalVersion, derrors.NotFound)
+	}
+	dir, ok := ds.dirs[modulePath]
+	if !ok {
+		return nil, "", nil, fmt.Errorf("unknown module: %w", derrors.NotFound)
+	}
+	lm = ds.loaded[modulePath]
+	if lm == nil {
+		lm = &loadedModule{}
+		ds.loaded[modulePath] = lm
+	}
+	return nil, dir, lm, nil
+}
+
+// A dirStamp summarizes the files in a module directory, so that changes to
+// them can be detected.
+type dirStamp struct {
+	numFiles  int
+	totalSize int64
+	// modTime is the latest modification time, in nanoseconds since the
+	// Unix epoch.
+	modTime int64
+}
+
+// stampDir returns the dirStamp for the module in dir.
+func stampDir(dir string) (_ dirStamp, err error) {
+	defer derrors.Wrap(&err, "stampDir(%q)", dir)
+
+	var s dirStamp
+	err = filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if filePath != dir && fetch.SkipLocalDir(filePath, info) {
+				return filepath.SkipDir
+			}
+		} else {
+			s.numFiles++
+			s.totalSize += info.Size()
+		}
+		// Directory modification times change when files are added,
+		// removed or renamed.
+		if t := info.ModTime().UnixNano(); t > s.modTime {
+			s.modTime = t
+		}
+		return nil
+	})
+	return s, err
+}
@@ -0,0 +1,180 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package localdatasource
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/fetch"
+	"golang.org/x/pkgsite/internal/testing/sample"
+	"golang.org/x/pkgsite/internal/testing/testhelper"
+)
+
+// writeFiles writes the given files, keyed by slash-separated paths relative
+// to dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, contents := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func setup(t *testing.T) (context.Context, *DataSource, string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "localdatasource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, filepath.Join(dir, "bar"), map[string]string{
+		"go.mod":     "module foo.com/bar",
+		"LICENSE":    testhelper.MITLicense,
+		"baz/baz.go": "// Package baz provides a helpful constant.\npackage baz\n\nconst OK = 200\n",
+		// Nested modules are not part of the module.
+		"nested/go.mod":    "module foo.com/bar/nested",
+		"nested/nested.go": "package nested\n",
+	})
+	writeFiles(t, filepath.Join(dir, "gopath", "src", "old.org"), map[string]string{
+		"LICENSE":    testhelper.MITLicense,
+		"pkg/pkg.go": "// Package pkg is in a GOPATH.\npackage pkg\n",
+	})
+	ds := New(filepath.Join(dir, "gopath"))
+	if err := ds.AddModuleDir(filepath.Join(dir, "bar")); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	return ctx, ds, dir, func() {
+		cancel()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestGetPackage(t *testing.T) {
+	ctx, ds, _, teardown := setup(t)
+	defer teardown()
+
+	for _, test := range []struct {
+		pkgPath, modulePath, version string
+		wantModulePath, wantSynopsis string
+		wantErr                      error
+	}{
+		{"foo.com/bar/baz", internal.UnknownModulePath, internal.LatestVersion, "foo.com/bar", "Package baz provides a helpful constant.", nil},
+		{"foo.com/bar/baz", "foo.com/bar", fetch.LocalVersion, "foo.com/bar", "Package baz provides a helpful constant.", nil},
+		{"old.org/pkg", internal.UnknownModulePath, internal.LatestVersion, "old.org/pkg", "Package pkg is in a GOPATH.", nil},
+		{"foo.com/bar/baz", "foo.com/bar", "v1.2.0", "", "", derrors.NotFound},
+		{"foo.com/bar/nested", internal.UnknownModulePath, internal.LatestVersion, "", "", derrors.NotFound},
+		{"foo.com/other", internal.UnknownModulePath, internal.LatestVersion, "", "", derrors.NotFound},
+	} {
+		got, err := ds.LegacyGetPackage(ctx, test.pkgPath, test.modulePath, test.version)
+		if test.wantErr != nil {
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("LegacyGetPackage(%q, %q, %q): got error %v, want %v", test.pkgPath, test.modulePath, test.version, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ModulePath != test.wantModulePath || got.Version != fetch.LocalVersion || got.Synopsis != test.wantSynopsis {
+			t.Errorf("LegacyGetPackage(%q, %q, %q) = %s@%s %q, want %s@%s %q", test.pkgPath, test.modulePath, test.version,
+				got.ModulePath, got.Version, got.Synopsis, test.wantModulePath, fetch.LocalVersion, test.wantSynopsis)
+		}
+	}
+}
+
+func TestGetPathInfo(t *testing.T) {
+	ctx, ds, _, teardown := setup(t)
+	defer teardown()
+
+	for _, test := range []struct {
+		path           string
+		wantModulePath string
+		wantIsPackage  bool
+	}{
+		{"foo.com/bar", "foo.com/bar", false},
+		{"foo.com/bar/baz", "foo.com/bar", true},
+		{"old.org/pkg", "old.org/pkg", true},
+	} {
+		modulePath, version, isPackage, err := ds.GetPathInfo(ctx, test.path, internal.UnknownModulePath, internal.LatestVersion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if modulePath != test.wantModulePath || version != fetch.LocalVersion || isPackage != test.wantIsPackage {
+			t.Errorf("GetPathInfo(%q) = %q, %q, %t; want %q, %q, %t", test.path, modulePath, version, isPackage,
+				test.wantModulePath, fetch.LocalVersion, test.wantIsPackage)
+		}
+	}
+}
+
+func TestReloadOnChange(t *testing.T) {
+	defer func(d time.Duration) { checkInterval = d }(checkInterval)
+	checkInterval = 0
+
+	ctx, ds, dir, teardown := setup(t)
+	defer teardown()
+
+	synopsis := func() string {
+		t.Helper()
+		vp, err := ds.LegacyGetPackage(ctx, "foo.com/bar/baz", internal.UnknownModulePath, internal.LatestVersion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return vp.Synopsis
+	}
+	if got, want := synopsis(), "Package baz provides a helpful constant."; got != want {
+		t.Fatalf("got synopsis %q, want %q", got, want)
+	}
+	// Make sure the modification time changes even on filesystems with a
+	// coarse resolution.
+	time.Sleep(10 * time.Millisecond)
+	writeFiles(t, filepath.Join(dir, "bar"), map[string]string{
+		"baz/baz.go": "// Package baz has changed.\npackage baz\n",
+	})
+	if got, want := synopsis(), "Package baz has changed."; got != want {
+		t.Errorf("after change: got synopsis %q, want %q", got, want)
+	}
+}
+
+func TestAddModule(t *testing.T) {
+	ctx, ds, _, teardown := setup(t)
+	defer teardown()
+
+	ds.AddModule(sample.Module("foo.com/bar/v2", "v2.1.0", "baz"))
+	for _, test := range []struct {
+		version     string
+		wantVersion string
+		wantErr     error
+	}{
+		{internal.LatestVersion, "v2.1.0", nil},
+		{"v2.1.0", "v2.1.0", nil},
+		{"v2.0.0", "", derrors.NotFound},
+	} {
+		got, err := ds.LegacyGetPackage(ctx, "foo.com/bar/v2/baz", internal.UnknownModulePath, test.version)
+		if !errors.Is(err, test.wantErr) {
+			t.Errorf("LegacyGetPackage(%q): got error %v, want %v", test.version, err, test.wantErr)
+			continue
+		}
+		if err == nil && got.Version != test.wantVersion {
+			t.Errorf("LegacyGetPackage(%q): got version %q, want %q", test.version, got.Version, test.wantVersion)
+		}
+	}
+	// Modules in directories are still found.
+	if _, err := ds.LegacyGetPackage(ctx, "foo.com/bar/baz", internal.UnknownModulePath, internal.LatestVersion); err != nil {
+		t.Error(err)
+	}
+}
@@ -43,14 +43,14 @@ func BenchmarkSearch(b *testing.B) {
 		b.Fatal(err)
 	}
 	db := New(ddb)
-	searchers := map[string]func(context.Context, string, int, int) ([]*internal.SearchResult, error){
+	searchers := map[string]func(context.Context, string, internal.SearchFilters, int, int, *internal.SearchCursor) ([]*internal.SearchResult, *internal.SearchFacets, error){
 		"db.Search": db.Search,
 	}
 	for name, search := range searchers {
 		for _, query := range testQueries {
 			b.Run(name+":"+query, func(b *testing.B) {
 				for i := 0; i < b.N; i++ {
-					if _, err := search(ctx, query, 10, 0); err != nil {
+					if _, _, err := search(ctx, query, internal.SearchFilters{}, 10, 0, nil); err != nil {
 						b.Fatal(err)
 					}
 				}
@@ -0,0 +1,75 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// GetSearchBoosts reads all the search boosts from the database, ordered by
+// prefix.
+func (db *DB) GetSearchBoosts(ctx context.Context) (_ []*internal.SearchBoost, err error) {
+	defer derrors.Wrap(&err, "DB.GetSearchBoosts(ctx)")
+
+	query := `
+		SELECT prefix, multiplier, created_by, reason
+		FROM search_boosts
+		ORDER BY prefix`
+	var boosts []*internal.SearchBoost
+	collect := func(rows *sql.Rows) error {
+		var b internal.SearchBoost
+		if err := rows.Scan(&b.Prefix, &b.Multiplier, &b.CreatedBy, &b.Reason); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		boosts = append(boosts, &b)
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect); err != nil {
+		return nil, err
+	}
+	return boosts, nil
+}
+
+// SetSearchBoost inserts b into the search_boosts table, replacing the boost
+// with the same prefix, if any. The new multiplier applies to searches
+// immediately.
+func (db *DB) SetSearchBoost(ctx context.Context, b *internal.SearchBoost) (err error) {
+	defer derrors.Wrap(&err, "DB.SetSearchBoost(ctx, %+v)", b)
+
+	if b.Prefix == "" || b.CreatedBy == "" || b.Reason == "" {
+		return fmt.Errorf("prefix, user and reason must be non-empty: %w", derrors.InvalidArgument)
+	}
+	if b.Multiplier <= 0 {
+		return fmt.Errorf("multiplier must be positive: %w", derrors.InvalidArgument)
+	}
+	_, err = db.db.Exec(ctx, `
+		INSERT INTO search_boosts (prefix, multiplier, created_by, reason)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (prefix)
+		DO UPDATE SET
+			multiplier = excluded.multiplier,
+			created_by = excluded.created_by,
+			reason = excluded.reason,
+			updated_at = CURRENT_TIMESTAMP`,
+		b.Prefix, b.Multiplier, b.CreatedBy, b.Reason)
+	return err
+}
+
+// DeleteSearchBoost deletes the search boost with the given prefix. It
+// returns an error wrapping derrors.NotFound if there is no such boost.
+func (db *DB) DeleteSearchBoost(ctx context.Context, prefix string) (err error) {
+	defer derrors.Wrap(&err, "DB.DeleteSearchBoost(ctx, %q)", prefix)
+
+	res, err := db.db.Exec(ctx, `DELETE FROM search_boosts WHERE prefix = $1`, prefix)
+	if err != nil {
+		return err
+	}
+	return notFoundIfNoRows(res)
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestSearchBoosts(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	corp := &internal.SearchBoost{Prefix: "corp.example.com/", Multiplier: 2, CreatedBy: "someone", Reason: "internal"}
+	fork := &internal.SearchBoost{Prefix: "github.com/fork/", Multiplier: 0.5, CreatedBy: "someone", Reason: "deprecated fork"}
+	for _, b := range []*internal.SearchBoost{corp, fork} {
+		if err := testDB.SetSearchBoost(ctx, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Setting a boost again replaces it.
+	corp.Multiplier = 3
+	if err := testDB.SetSearchBoost(ctx, corp); err != nil {
+		t.Fatal(err)
+	}
+	got, err := testDB.GetSearchBoosts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*internal.SearchBoost{corp, fork}, got); diff != "" {
+		t.Errorf("GetSearchBoosts mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := testDB.SetSearchBoost(ctx, &internal.SearchBoost{Prefix: "a", Multiplier: 0, CreatedBy: "someone", Reason: "zero"}); !errors.Is(err, derrors.InvalidArgument) {
+		t.Errorf("SetSearchBoost with zero multiplier: got error %v, want InvalidArgument", err)
+	}
+	if err := testDB.DeleteSearchBoost(ctx, fork.Prefix); err != nil {
+		t.Fatal(err)
+	}
+	if err := testDB.DeleteSearchBoost(ctx, fork.Prefix); !errors.Is(err, derrors.NotFound) {
+		t.Errorf("DeleteSearchBoost of deleted boost: got error %v, want NotFound", err)
+	}
+}
+
+func TestSearchWithBoosts(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	insertModules(ctx, t,
+		sample.Module("github.com/popular/foo", "v1.0.0", ""),
+		sample.Module("github.com/fork/foo", "v1.0.0", ""),
+		sample.Module("corp.example.com/foo", "v1.0.0", ""))
+	if _, err := testDB.db.Exec(ctx, `
+		UPDATE search_documents
+		SET imported_by_count = CASE WHEN module_path = 'github.com/popular/foo' THEN 100 ELSE 10 END`); err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []*internal.SearchBoost{
+		{Prefix: "corp.example.com/", Multiplier: 10, CreatedBy: "someone", Reason: "internal"},
+		{Prefix: "github.com/fork/", Multiplier: 0.5, CreatedBy: "someone", Reason: "deprecated fork"},
+	} {
+		if err := testDB.SetSearchBoost(ctx, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Each searcher ranks the boosted package first and the demoted one last.
+	runSearcherTests(ctx, t, []searcherTest{{
+		q:    "foo",
+		want: []string{"corp.example.com/foo", "github.com/popular/foo", "github.com/fork/foo"},
+	}})
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/database"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// insertDependencies inserts the go.mod directives of m into the
+// module_dependencies table, replacing any that are already there.
+func insertDependencies(ctx context.Context, db *database.DB, m *internal.Module, moduleID int) (err error) {
+	defer derrors.Wrap(&err, "insertDependencies(ctx, %q, %q)", m.ModulePath, m.Version)
+
+	if _, err := db.Exec(ctx, `DELETE FROM module_dependencies WHERE module_id = $1`, moduleID); err != nil {
+		return err
+	}
+	var values []interface{}
+	for _, d := range m.Dependencies {
+		values = append(values, moduleID, d.Kind, d.ModulePath, d.Version, d.Indirect,
+			d.ReplacementPath, d.ReplacementVersion)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	cols := []string{
+		"module_id",
+		"kind",
+		"dependency_path",
+		"dependency_version",
+		"indirect",
+		"replacement_path",
+		"replacement_version",
+	}
+	// A go.mod file may repeat a directive; keep the first.
+	return db.BulkInsert(ctx, "module_dependencies", cols, values, database.OnConflictDoNothing)
+}
+
+// GetModuleDependencies returns the go.mod directives of the given module
+// version: first its requirements, then its replacements, then its
+// exclusions, each ordered by module path and version.
+func (db *DB) GetModuleDependencies(ctx context.Context, modulePath, version string) (_ []*internal.ModuleDependency, err error) {
+	defer derrors.Wrap(&err, "DB.GetModuleDependencies(ctx, %q, %q)", modulePath, version)
+
+	query := `
+		SELECT
+			d.kind,
+			d.dependency_path,
+			d.dependency_version,
+			d.indirect,
+			d.replacement_path,
+			d.replacement_version
+		FROM module_dependencies d
+		INNER JOIN modules m
+		ON d.module_id = m.id
+		WHERE
+			m.module_path = $1
+			AND m.version = $2
+		ORDER BY d.kind, d.dependency_path, d.dependency_version`
+	var deps []*internal.ModuleDependency
+	collect := func(rows *sql.Rows) error {
+		var d internal.ModuleDependency
+		if err := rows.Scan(&d.Kind, &d.ModulePath, &d.Version, &d.Indirect,
+			&d.ReplacementPath, &d.ReplacementVersion); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		deps = append(deps, &d)
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, modulePath, version); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+// GetModuleDependents returns the modules whose latest version requires some
+// version of the module with the given path, ordered by module path. At most
+// limit dependents are returned.
+//
+// Only the latest version of each dependent is considered, so a module that
+// has stopped requiring modulePath is not a dependent even if its older
+// versions require it. Requirements of modulePath's other major versions are
+// not included, since they have different module paths.
+func (db *DB) GetModuleDependents(ctx context.Context, modulePath string, limit int) (_ []*internal.ModuleDependent, err error) {
+	defer derrors.Wrap(&err, "DB.GetModuleDependents(ctx, %q, %d)", modulePath, limit)
+
+	query := `
+		WITH latest AS (
+			SELECT DISTINCT ON (m.module_path)
+				m.id,
+				m.module_path,
+				m.version
+			FROM modules m
+			WHERE m.module_path IN (
+				SELECT m2.module_path
+				FROM module_dependencies d
+				INNER JOIN modules m2
+				ON d.module_id = m2.id
+				WHERE
+					d.kind = 'require'
+					AND d.dependency_path = $1
+			)
+			ORDER BY
+				m.module_path,
+				-- Order the versions by release then prerelease.
+				m.version_type = 'release' DESC,
+				m.sort_version DESC
+		)
+		SELECT l.module_path, l.version, d.dependency_version
+		FROM latest l
+		INNER JOIN module_dependencies d
+		ON d.module_id = l.id
+		WHERE
+			d.kind = 'require'
+			AND d.dependency_path = $1
+		ORDER BY l.module_path
+		LIMIT $2`
+	var dependents []*internal.ModuleDependent
+	collect := func(rows *sql.Rows) error {
+		var d internal.ModuleDependent
+		if err := rows.Scan(&d.ModulePath, &d.Version, &d.RequiredVersion); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		dependents = append(dependents, &d)
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, modulePath, limit); err != nil {
+		return nil, err
+	}
+	return dependents, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestModuleDependencies(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	require := func(path, version string) *internal.ModuleDependency {
+		return &internal.ModuleDependency{Kind: internal.DependencyRequire, ModulePath: path, Version: version}
+	}
+	insert := func(modulePath, version string, deps ...*internal.ModuleDependency) {
+		t.Helper()
+		m := sample.Module(modulePath, version, "")
+		m.Dependencies = deps
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replace := &internal.ModuleDependency{
+		Kind:               internal.DependencyReplace,
+		ModulePath:         "example.com/lib",
+		ReplacementPath:    "example.com/fork",
+		ReplacementVersion: "v1.0.1",
+	}
+	exclude := &internal.ModuleDependency{Kind: internal.DependencyExclude, ModulePath: "example.com/lib", Version: "v1.0.0"}
+	insert("example.com/app", "v1.0.0", exclude, replace, require("example.com/lib", "v1.2.0"))
+	// Old versions of example.com/tool required example.com/lib, but the
+	// latest one doesn't.
+	insert("example.com/tool", "v1.0.0", require("example.com/lib", "v1.0.0"))
+	insert("example.com/tool", "v1.1.0", require("example.com/other", "v0.1.0"))
+	insert("example.com/cli", "v0.1.0", require("example.com/lib", "v1.0.0"))
+	insert("example.com/cli", "v0.2.0", require("example.com/lib", "v1.1.0"))
+	// example.com/lib/v2 is a different module.
+	insert("example.com/next", "v1.0.0", require("example.com/lib/v2", "v2.0.0"))
+
+	gotDeps, err := testDB.GetModuleDependencies(ctx, "example.com/app", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDeps := []*internal.ModuleDependency{require("example.com/lib", "v1.2.0"), replace, exclude}
+	if diff := cmp.Diff(wantDeps, gotDeps); diff != "" {
+		t.Errorf("GetModuleDependencies mismatch (-want +got):\n%s", diff)
+	}
+
+	gotDependents, err := testDB.GetModuleDependents(ctx, "example.com/lib", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDependents := []*internal.ModuleDependent{
+		{ModulePath: "example.com/app", Version: "v1.0.0", RequiredVersion: "v1.2.0"},
+		{ModulePath: "example.com/cli", Version: "v0.2.0", RequiredVersion: "v1.1.0"},
+	}
+	if diff := cmp.Diff(wantDependents, gotDependents); diff != "" {
+		t.Errorf("GetModuleDependents mismatch (-want +got):\n%s", diff)
+	}
+
+	gotDependents, err = testDB.GetModuleDependents(ctx, "example.com/lib", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(wantDependents[:1], gotDependents); diff != "" {
+		t.Errorf("GetModuleDependents with limit mismatch (-want +got):\n%s", diff)
+	}
+}
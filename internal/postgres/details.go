@@ -98,7 +98,9 @@ func getPackageVersions(ctx context.Context, db *DB, pkgPath string, versionType
 		SELECT
 			p.module_path,
 			p.version,
-			m.commit_time
+			m.commit_time,
+			m.retracted,
+			m.retraction_rationale
 		FROM
 			packages p
 		INNER JOIN
@@ -133,7 +135,8 @@ func getPackageVersions(ctx context.Context, db *DB, pkgPath string, versionType
 	var versionHistory []*internal.ModuleInfo
 	for rows.Next() {
 		var mi internal.ModuleInfo
-		if err := rows.Scan(&mi.ModulePath, &mi.Version, &mi.CommitTime); err != nil {
+		if err := rows.Scan(&mi.ModulePath, &mi.Version, &mi.CommitTime,
+			&mi.Retracted, &mi.RetractionRationale); err != nil {
 			return nil, fmt.Errorf("row.Scan(): %v", err)
 		}
 		versionHistory = append(versionHistory, &mi)
@@ -176,7 +179,7 @@ func getModuleVersions(ctx context.Context, db *DB, modulePath string, versionTy
 
 	baseQuery := `
 	SELECT
-		module_path, version, commit_time
+		module_path, version, commit_time, retracted, retraction_rationale
     FROM
 		modules
 	WHERE
@@ -195,7 +198,8 @@ func getModuleVersions(ctx context.Context, db *DB, modulePath string, versionTy
 	var vinfos []*internal.ModuleInfo
 	collect := func(rows *sql.Rows) error {
 		var mi internal.ModuleInfo
-		if err := rows.Scan(&mi.ModulePath, &mi.Version, &mi.CommitTime); err != nil {
+		if err := rows.Scan(&mi.ModulePath, &mi.Version, &mi.CommitTime,
+			&mi.Retracted, &mi.RetractionRationale); err != nil {
 			return err
 		}
 		vinfos = append(vinfos, &mi)
@@ -263,14 +267,16 @@ func (db *DB) GetImports(ctx context.Context, pkgPath, modulePath, version strin
 	return imports, nil
 }
 
-// GetImportedBy fetches and returns all of the packages that import the
-// package with path.
+// GetImportedBy fetches and returns the packages that import the package with
+// path, in order of their paths. If after is non-empty, only the packages
+// whose paths sort after it are returned, so that the importers can be read a
+// page at a time without scanning the earlier pages.
 // The returned error may be checked with derrors.IsInvalidArgument to
 // determine if it resulted from an invalid package path or version.
 //
-// Instead of supporting pagination, this query runs with a limit.
-func (db *DB) GetImportedBy(ctx context.Context, pkgPath, modulePath string, limit int) (paths []string, err error) {
-	defer derrors.Wrap(&err, "GetImportedBy(ctx, %q, %q)", pkgPath, modulePath)
+// The query runs with a limit.
+func (db *DB) GetImportedBy(ctx context.Context, pkgPath, modulePath, after string, limit int) (paths []string, err error) {
+	defer derrors.Wrap(&err, "GetImportedBy(ctx, %q, %q, %q)", pkgPath, modulePath, after)
 	if pkgPath == "" {
 		return nil, fmt.Errorf("pkgPath cannot be empty: %w", derrors.InvalidArgument)
 	}
@@ -283,9 +289,11 @@ func (db *DB) GetImportedBy(ctx context.Context, pkgPath, modulePath string, lim
 			to_path = $1
 		AND
 			from_module_path <> $2
+		AND
+			from_path > $3
 		ORDER BY
 			from_path
-		LIMIT $3`
+		LIMIT $4`
 
 	var importedby []string
 	collect := func(rows *sql.Rows) error {
@@ -296,7 +304,7 @@ func (db *DB) GetImportedBy(ctx context.Context, pkgPath, modulePath string, lim
 		importedby = append(importedby, fromPath)
 		return nil
 	}
-	if err := db.db.RunQuery(ctx, query, collect, pkgPath, modulePath, limit); err != nil {
+	if err := db.db.RunQuery(ctx, query, collect, pkgPath, modulePath, after, limit); err != nil {
 		return nil, err
 	}
 	return importedby, nil
@@ -315,7 +323,11 @@ func (db *DB) GetModuleInfo(ctx context.Context, modulePath, version string) (_
 			version_type,
 			source_info,
 			redistributable,
-			has_go_mod
+			has_go_mod,
+			retracted,
+			retraction_rationale,
+			deprecation,
+			go_version
 		FROM
 			modules
 		WHERE
@@ -325,7 +337,8 @@ func (db *DB) GetModuleInfo(ctx context.Context, modulePath, version string) (_
 	var mi internal.ModuleInfo
 	row := db.db.QueryRow(ctx, query, modulePath, version)
 	if err := row.Scan(&mi.ModulePath, &mi.Version, &mi.CommitTime, &mi.VersionType,
-		jsonbScanner{&mi.SourceInfo}, &mi.IsRedistributable, &mi.HasGoMod); err != nil {
+		jsonbScanner{&mi.SourceInfo}, &mi.IsRedistributable, &mi.HasGoMod,
+		&mi.Retracted, &mi.RetractionRationale, &mi.Deprecation, &mi.GoVersion); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, derrors.NotFound
 		}
@@ -349,7 +362,11 @@ func (db *DB) LegacyGetModuleInfo(ctx context.Context, modulePath string, versio
 			version_type,
 			source_info,
 			redistributable,
-			has_go_mod
+			has_go_mod,
+			retracted,
+			retraction_rationale,
+			deprecation,
+			go_version
 		FROM
 			modules`
 
@@ -360,7 +377,9 @@ func (db *DB) LegacyGetModuleInfo(ctx context.Context, modulePath string, versio
 			ORDER BY
 				-- Order the versions by release then prerelease.
 				-- The default version should be the first release
-				-- version available, if one exists.
+				-- version available, if one exists. Retracted versions
+				-- are used only if there are no others.
+				retracted,
 				version_type = 'release' DESC,
 				sort_version DESC
 			LIMIT 1;`
@@ -374,7 +393,8 @@ func (db *DB) LegacyGetModuleInfo(ctx context.Context, modulePath string, versio
 	row := db.db.QueryRow(ctx, query, args...)
 	if err := row.Scan(&mi.ModulePath, &mi.Version, &mi.CommitTime,
 		database.NullIsEmpty(&mi.LegacyReadmeFilePath), database.NullIsEmpty(&mi.LegacyReadmeContents), &mi.VersionType,
-		jsonbScanner{&mi.SourceInfo}, &mi.IsRedistributable, &mi.HasGoMod); err != nil {
+		jsonbScanner{&mi.SourceInfo}, &mi.IsRedistributable, &mi.HasGoMod,
+		&mi.Retracted, &mi.RetractionRationale, &mi.Deprecation, &mi.GoVersion); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("module version %s@%s: %w", modulePath, version, derrors.NotFound)
 		}
@@ -237,13 +237,28 @@ func TestPostgres_GetImportsAndImportedBy(t *testing.T) {
 				testGetImports(ctx, t, tc.path, tc.modulePath, tc.version, tc.wantImports)
 			})
 
-			gotImportedBy, err := testDB.GetImportedBy(ctx, tc.path, tc.modulePath, 100)
+			gotImportedBy, err := testDB.GetImportedBy(ctx, tc.path, tc.modulePath, "", 100)
 			if err != nil {
 				t.Fatal(err)
 			}
 			if diff := cmp.Diff(tc.wantImportedBy, gotImportedBy); diff != "" {
 				t.Errorf("testDB.GetImportedBy(%q, %q) mismatch (-want +got):\n%s", tc.path, tc.modulePath, diff)
 			}
+			if len(tc.wantImportedBy) > 0 {
+				// Read the importers after the first one.
+				after := tc.wantImportedBy[0]
+				gotImportedBy, err := testDB.GetImportedBy(ctx, tc.path, tc.modulePath, after, 100)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var want []string
+				if len(tc.wantImportedBy) > 1 {
+					want = tc.wantImportedBy[1:]
+				}
+				if diff := cmp.Diff(want, gotImportedBy); diff != "" {
+					t.Errorf("testDB.GetImportedBy(%q, %q, %q) mismatch (-want +got):\n%s", tc.path, tc.modulePath, after, diff)
+				}
+			}
 		})
 	}
 }
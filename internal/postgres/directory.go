@@ -30,6 +30,10 @@ func (db *DB) GetDirectoryNew(ctx context.Context, path, modulePath, version str
 			m.redistributable,
 			m.has_go_mod,
 			m.source_info,
+			m.retracted,
+			m.retraction_rationale,
+			m.deprecation,
+			m.go_version,
 			p.id,
 			p.path,
 			p.name,
@@ -37,15 +41,11 @@ func (db *DB) GetDirectoryNew(ctx context.Context, path, modulePath, version str
 			p.redistributable,
 			p.license_types,
 			p.license_paths,
-			d.goos,
-			d.goarch,
-			d.synopsis,
-			d.html
+			p.uses_cgo,
+			p.go_release_tags
 		FROM modules m
 		INNER JOIN paths p
 		ON p.module_id = m.id
-		LEFT JOIN documentation d
-		ON d.path_id = p.id
 		WHERE
 			p.path = $1
 			AND m.module_path = $2
@@ -53,8 +53,6 @@ func (db *DB) GetDirectoryNew(ctx context.Context, path, modulePath, version str
 	var (
 		mi                         internal.ModuleInfo
 		dir                        internal.DirectoryNew
-		doc                        internal.Documentation
-		docHTML                    string
 		pkg                        internal.PackageNew
 		licenseTypes, licensePaths []string
 		pathID                     int
@@ -68,6 +66,10 @@ func (db *DB) GetDirectoryNew(ctx context.Context, path, modulePath, version str
 		&mi.IsRedistributable,
 		&mi.HasGoMod,
 		jsonbScanner{&mi.SourceInfo},
+		&mi.Retracted,
+		&mi.RetractionRationale,
+		&mi.Deprecation,
+		&mi.GoVersion,
 		&pathID,
 		&dir.Path,
 		database.NullIsEmpty(&pkg.Name),
@@ -75,10 +77,8 @@ func (db *DB) GetDirectoryNew(ctx context.Context, path, modulePath, version str
 		&dir.IsRedistributable,
 		pq.Array(&licenseTypes),
 		pq.Array(&licensePaths),
-		database.NullIsEmpty(&doc.GOOS),
-		database.NullIsEmpty(&doc.GOARCH),
-		database.NullIsEmpty(&doc.Synopsis),
-		database.NullIsEmpty(&docHTML),
+		&pkg.UsesCgo,
+		pq.Array(&pkg.GoReleaseTags),
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("directory %s@%s: %w", path, version, derrors.NotFound)
@@ -94,8 +94,18 @@ func (db *DB) GetDirectoryNew(ctx context.Context, path, modulePath, version str
 	if pkg.Name != "" {
 		dir.Package = &pkg
 		pkg.Path = dir.Path
-		doc.HTML = convertDocumentation(docHTML)
-		pkg.Documentation = &doc
+		docs, err := db.getDocumentation(ctx, path, modulePath, version)
+		if err != nil {
+			return nil, err
+		}
+		if len(docs) == 0 {
+			pkg.Documentation = &internal.Documentation{}
+		} else {
+			pkg.Documentation = docs[0]
+			if len(docs) > 1 {
+				pkg.OtherDocumentation = docs[1:]
+			}
+		}
 		collect := func(rows *sql.Rows) error {
 			var path string
 			if err := rows.Scan(&path); err != nil {
@@ -229,7 +239,11 @@ func (db *DB) LegacyGetDirectory(ctx context.Context, dirPath, modulePath, versi
 			&mi.VersionType,
 			jsonbScanner{&mi.SourceInfo},
 			&mi.IsRedistributable,
-			&mi.HasGoMod)
+			&mi.HasGoMod,
+			&mi.Retracted,
+			&mi.RetractionRationale,
+			&mi.Deprecation,
+			&mi.GoVersion)
 		if err := rows.Scan(scanArgs...); err != nil {
 			return fmt.Errorf("row.Scan(): %v", err)
 		}
@@ -285,14 +299,20 @@ func directoryColumns(fields internal.FieldSet) string {
 			m.version_type,
 			m.source_info,
 			m.redistributable,
-			m.has_go_mod`
+			m.has_go_mod,
+			m.retracted,
+			m.retraction_rationale,
+			m.deprecation,
+			m.go_version`
 }
 
 const orderByLatest = `
 			ORDER BY
 				-- Order the versions by release then prerelease.
 				-- The default version should be the first release
-				-- version available, if one exists.
+				-- version available, if one exists. Retracted versions
+				-- are used only if there are no others.
+				retracted,
 				version_type = 'release' DESC,
 				sort_version DESC,
 				module_path DESC`
@@ -304,6 +304,26 @@ func TestGetDirectoryNew(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	// Add a module with a package that has different documentation on windows.
+	windowsDoc := &internal.Documentation{
+		Synopsis: "This is a package synopsis for windows.",
+		HTML:     sample.DocumentationHTML,
+		GOOS:     "windows",
+		GOARCH:   "amd64",
+	}
+	m = sample.Module("a.com/w", "v1.0.0", "p")
+	findDirectory(m, "a.com/w/p").Package.OtherDocumentation = []*internal.Documentation{windowsDoc}
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	m = sample.Module("a.com/c", "v1.0.0", "p")
+	m.GoVersion = "1.14"
+	findDirectory(m, "a.com/c/p").Package.UsesCgo = true
+	findDirectory(m, "a.com/c/p").Package.GoReleaseTags = []string{"go1.13", "go1.14"}
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+
 	newVdir := func(path, modulePath, version string, readme *internal.Readme, pkg *internal.PackageNew) *internal.VersionedDirectory {
 		return &internal.VersionedDirectory{
 			ModuleInfo: *sample.ModuleInfo(modulePath, version),
@@ -415,6 +435,31 @@ func TestGetDirectoryNew(t *testing.T) {
 				},
 				newPackage("p", "a.com/m/dir/p")),
 		},
+		{
+			name:       "package with documentation for other build contexts",
+			dirPath:    "a.com/w/p",
+			modulePath: "a.com/w",
+			version:    "v1.0.0",
+			want: func() *internal.VersionedDirectory {
+				pkg := newPackage("p", "a.com/w/p")
+				pkg.OtherDocumentation = []*internal.Documentation{windowsDoc}
+				return newVdir("a.com/w/p", "a.com/w", "v1.0.0", nil, pkg)
+			}(),
+		},
+		{
+			name:       "package with toolchain requirements",
+			dirPath:    "a.com/c/p",
+			modulePath: "a.com/c",
+			version:    "v1.0.0",
+			want: func() *internal.VersionedDirectory {
+				pkg := newPackage("p", "a.com/c/p")
+				pkg.UsesCgo = true
+				pkg.GoReleaseTags = []string{"go1.13", "go1.14"}
+				vdir := newVdir("a.com/c/p", "a.com/c", "v1.0.0", nil, pkg)
+				vdir.GoVersion = "1.14"
+				return vdir
+			}(),
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			got, err := testDB.GetDirectoryNew(ctx, tc.dirPath, tc.modulePath, tc.version)
@@ -0,0 +1,57 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// getDocumentation returns the documentation for the package with the given
+// path in the given module version, one entry for each distinct build context.
+// The documentation is ordered by the position of its build context in
+// internal.BuildContexts, so the first entry is the default documentation for
+// the package.
+func (db *DB) getDocumentation(ctx context.Context, path, modulePath, version string) (_ []*internal.Documentation, err error) {
+	defer derrors.Wrap(&err, "getDocumentation(ctx, %q, %q, %q)", path, modulePath, version)
+
+	query := `
+		SELECT d.goos, d.goarch, d.synopsis, d.html
+		FROM documentation d
+		INNER JOIN paths p
+		ON d.path_id = p.id
+		INNER JOIN modules m
+		ON p.module_id = m.id
+		WHERE
+			p.path = $1
+			AND m.module_path = $2
+			AND m.version = $3`
+	var docs []*internal.Documentation
+	collect := func(rows *sql.Rows) error {
+		var (
+			doc     internal.Documentation
+			docHTML string
+		)
+		if err := rows.Scan(&doc.GOOS, &doc.GOARCH, &doc.Synopsis, &docHTML); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		doc.HTML = convertDocumentation(docHTML)
+		docs = append(docs, &doc)
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, path, modulePath, version); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		return internal.BuildContextIndex(docs[i].GOOS, docs[i].GOARCH) <
+			internal.BuildContextIndex(docs[j].GOOS, docs[j].GOARCH)
+	})
+	return docs, nil
+}
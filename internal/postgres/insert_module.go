@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"hash/fnv"
 	"io"
+	"net/http"
 	"runtime"
 	"sort"
 	"strings"
@@ -87,11 +88,22 @@ func (db *DB) saveModule(ctx context.Context, m *internal.Module) (err error) {
 		}
 
 		logMemory(ctx, "after insertLicenses")
+
+		if err := insertDependencies(ctx, tx, m, moduleID); err != nil {
+			return err
+		}
+		logMemory(ctx, "after insertDependencies")
+
 		if err := insertPackages(ctx, tx, m); err != nil {
 			return err
 		}
 		logMemory(ctx, "after insertPackages")
 
+		if err := insertSymbolHistory(ctx, tx, m); err != nil {
+			return err
+		}
+		logMemory(ctx, "after insertSymbolHistory")
+
 		if err := insertDirectories(ctx, tx, m, moduleID); err != nil {
 			return err
 		}
@@ -107,6 +119,10 @@ func (db *DB) saveModule(ctx context.Context, m *internal.Module) (err error) {
 			return err
 		}
 
+		if err := updateRetractions(ctx, tx, m.ModulePath); err != nil {
+			return err
+		}
+
 		// We only insert into imports_unique and search_documents if this is
 		// the latest version of the module.
 		isLatest, err := isLatestVersion(ctx, tx, m.ModulePath, m.Version)
@@ -151,7 +167,10 @@ func (db *DB) saveModule(ctx context.Context, m *internal.Module) (err error) {
 			return err
 		}
 		// Insert the module's packages into search_documents.
-		return UpsertSearchDocuments(ctx, tx, m)
+		if err := UpsertSearchDocuments(ctx, tx, m); err != nil {
+			return err
+		}
+		return insertSymbols(ctx, tx, m)
 	})
 }
 
@@ -163,6 +182,13 @@ func insertModule(ctx context.Context, db *database.DB, m *internal.Module) (_ i
 	if err != nil {
 		return 0, err
 	}
+	var retractionsJSON []byte
+	if len(m.GoModRetractions) > 0 {
+		retractionsJSON, err = json.Marshal(m.GoModRetractions)
+		if err != nil {
+			return 0, err
+		}
+	}
 	var moduleID int
 	err = db.QueryRow(ctx,
 		`INSERT INTO modules(
@@ -176,15 +202,21 @@ func insertModule(ctx context.Context, db *database.DB, m *internal.Module) (_ i
 			series_path,
 			source_info,
 			redistributable,
-			has_go_mod)
-		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10, $11)
+			has_go_mod,
+			go_mod_retractions,
+			go_mod_deprecation,
+			go_version)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10, $11, $12, $13, $14)
 		ON CONFLICT
 			(module_path, version)
 		DO UPDATE SET
 			readme_file_path=excluded.readme_file_path,
 			readme_contents=excluded.readme_contents,
 			source_info=excluded.source_info,
-			redistributable=excluded.redistributable
+			redistributable=excluded.redistributable,
+			go_mod_retractions=excluded.go_mod_retractions,
+			go_mod_deprecation=excluded.go_mod_deprecation,
+			go_version=excluded.go_version
 		RETURNING id`,
 		m.ModulePath,
 		m.Version,
@@ -197,6 +229,9 @@ func insertModule(ctx context.Context, db *database.DB, m *internal.Module) (_ i
 		sourceInfoJSON,
 		m.IsRedistributable,
 		m.HasGoMod,
+		retractionsJSON,
+		m.GoModDeprecation,
+		m.GoVersion,
 	).Scan(&moduleID)
 	if err != nil {
 		return 0, err
@@ -357,6 +392,137 @@ func insertImportsUnique(ctx context.Context, tx *database.DB, m *internal.Modul
 	return tx.BulkUpsert(ctx, "imports_unique", cols, values, cols)
 }
 
+// insertSymbols replaces the rows in the symbols table for the packages in the
+// module. It should only be called if the given module's version is the latest,
+// after the packages have been inserted into search_documents.
+func insertSymbols(ctx context.Context, tx *database.DB, m *internal.Module) (err error) {
+	ctx, span := trace.StartSpan(ctx, "insertSymbols")
+	defer span.End()
+	defer derrors.Wrap(&err, "insertSymbols(%q, %q)", m.ModulePath, m.Version)
+
+	// Remove the previous rows for this module. We'll replace them with
+	// new ones below.
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM symbols WHERE module_path = $1`,
+		m.ModulePath); err != nil {
+		return err
+	}
+
+	var values []interface{}
+	for _, p := range m.LegacyPackages {
+		if isInternalPackage(p.Path) {
+			// Internal packages are not in search_documents.
+			continue
+		}
+		for _, s := range p.Symbols {
+			if s.Kind == internal.SymbolKindField {
+				// Fields are only recorded in symbol_history, to compare
+				// the APIs of versions.
+				continue
+			}
+			values = append(values, p.Path, m.ModulePath, s.Name, s.ParentName, s.Kind, makeValidUnicode(s.Synopsis))
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	cols := []string{"package_path", "module_path", "name", "parent_name", "kind", "synopsis"}
+	return tx.BulkUpsert(ctx, "symbols", cols, values, []string{"package_path", "parent_name", "name"})
+}
+
+// insertSymbolHistory replaces the rows in the symbol_history and
+// symbol_history_packages tables for the module version. Unlike
+// insertSymbols, it is called for every version.
+//
+// The documentation of a release version shows the version in which each
+// identifier was added, as of the versions stored when it was fetched. So the
+// first time the history of a release version is recorded, the later release
+// versions of the module are marked to be reprocessed.
+func insertSymbolHistory(ctx context.Context, tx *database.DB, m *internal.Module) (err error) {
+	ctx, span := trace.StartSpan(ctx, "insertSymbolHistory")
+	defer span.End()
+	defer derrors.Wrap(&err, "insertSymbolHistory(%q, %q)", m.ModulePath, m.Version)
+
+	res, err := tx.Exec(ctx,
+		`DELETE FROM symbol_history WHERE module_path = $1 AND version = $2`,
+		m.ModulePath, m.Version)
+	if err != nil {
+		return err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("RowsAffected(): %v", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM symbol_history_packages WHERE module_path = $1 AND version = $2`,
+		m.ModulePath, m.Version); err != nil {
+		return err
+	}
+
+	var pkgValues, values []interface{}
+	for _, p := range m.LegacyPackages {
+		pkgValues = append(pkgValues, p.Path, m.ModulePath, m.Version)
+		for _, s := range p.Symbols {
+			values = append(values, p.Path, m.ModulePath, m.Version, s.Name, s.ParentName, s.Kind, makeValidUnicode(s.Synopsis))
+		}
+	}
+	if len(pkgValues) > 0 {
+		pkgCols := []string{"package_path", "module_path", "version"}
+		if err := tx.BulkInsert(ctx, "symbol_history_packages", pkgCols, pkgValues, database.OnConflictDoNothing); err != nil {
+			return err
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	cols := []string{"package_path", "module_path", "version", "name", "parent_name", "kind", "signature"}
+	if err := tx.BulkInsert(ctx, "symbol_history", cols, values, database.OnConflictDoNothing); err != nil {
+		return err
+	}
+	if deleted > 0 {
+		return nil
+	}
+	if t, err := version.ParseType(m.Version); err != nil || t != version.TypeRelease {
+		return nil
+	}
+	return requeueLaterVersions(ctx, tx, m.ModulePath, m.Version)
+}
+
+// requeueLaterVersions marks the release versions of the module that are
+// later than vers, and whose documentation is stored, to be reprocessed.
+func requeueLaterVersions(ctx context.Context, tx *database.DB, modulePath, vers string) (err error) {
+	defer derrors.Wrap(&err, "requeueLaterVersions(%q, %q)", modulePath, vers)
+
+	query := `
+		UPDATE module_version_states s
+		SET
+			status = CASE WHEN s.status = $3 THEN $4 ELSE $6 END,
+			next_processed_after = CURRENT_TIMESTAMP,
+			last_processed_at = NULL
+		FROM modules m
+		WHERE s.module_path = $1
+		AND m.module_path = s.module_path
+		AND m.version = s.version
+		AND m.version_type = 'release'
+		AND m.sort_version > $2
+		AND s.status IN ($3, $5);`
+	res, err := tx.Exec(ctx, query, modulePath, version.ForSorting(vers),
+		http.StatusOK, derrors.ToReprocessStatus(http.StatusOK),
+		derrors.ToHTTPStatus(derrors.HasIncompletePackages),
+		derrors.ToReprocessStatus(derrors.ToHTTPStatus(derrors.HasIncompletePackages)))
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("RowsAffected(): %v", err)
+	}
+	if affected > 0 {
+		log.Infof(ctx, "requeueLaterVersions(%q, %q): marked %d versions to be reprocessed", modulePath, vers, affected)
+	}
+	return nil
+}
+
 func insertDirectories(ctx context.Context, db *database.DB, m *internal.Module, moduleID int) (err error) {
 	defer derrors.Wrap(&err, "insertDirectories(ctx, tx, %q, %q)", m.ModulePath, m.Version)
 	ctx, span := trace.StartSpan(ctx, "insertDirectories")
@@ -383,7 +549,7 @@ func insertDirectories(ctx context.Context, db *database.DB, m *internal.Module,
 		paths         []string
 		pathToID      = map[string]int{}
 		pathToReadme  = map[string]*internal.Readme{}
-		pathToDoc     = map[string]*internal.Documentation{}
+		pathToDoc     = map[string][]*internal.Documentation{}
 		pathToImports = map[string][]string{}
 	)
 	for _, d := range m.Directories {
@@ -403,9 +569,15 @@ func insertDirectories(ctx context.Context, db *database.DB, m *internal.Module,
 				}
 			}
 		}
-		var name string
+		var (
+			name          string
+			usesCgo       bool
+			goReleaseTags []string
+		)
 		if d.Package != nil {
 			name = d.Package.Name
+			usesCgo = d.Package.UsesCgo
+			goReleaseTags = d.Package.GoReleaseTags
 		}
 		pathValues = append(pathValues,
 			d.Path,
@@ -415,6 +587,8 @@ func insertDirectories(ctx context.Context, db *database.DB, m *internal.Module,
 			pq.Array(licenseTypes),
 			pq.Array(licensePaths),
 			d.IsRedistributable,
+			usesCgo,
+			pq.Array(goReleaseTags),
 		)
 		if d.Readme != nil {
 			pathToReadme[d.Path] = d.Readme
@@ -423,7 +597,7 @@ func insertDirectories(ctx context.Context, db *database.DB, m *internal.Module,
 			if d.Package.Documentation == nil || d.Package.Documentation.HTML.String() == internal.StringFieldMissing {
 				return errors.New("saveModule: package missing DocumentationHTML")
 			}
-			pathToDoc[d.Path] = d.Package.Documentation
+			pathToDoc[d.Path] = append([]*internal.Documentation{d.Package.Documentation}, d.Package.OtherDocumentation...)
 			if len(d.Package.Imports) > 0 {
 				pathToImports[d.Path] = d.Package.Imports
 			}
@@ -439,6 +613,8 @@ func insertDirectories(ctx context.Context, db *database.DB, m *internal.Module,
 			"license_types",
 			"license_paths",
 			"redistributable",
+			"uses_cgo",
+			"go_release_tags",
 		}
 		logMemory(ctx, "before inserting into paths")
 
@@ -486,12 +662,14 @@ func insertDirectories(ctx context.Context, db *database.DB, m *internal.Module,
 		logMemory(ctx, "before inserting into documentation")
 		var docValues []interface{}
 		for _, path := range paths {
-			doc, ok := pathToDoc[path]
+			docs, ok := pathToDoc[path]
 			if !ok {
 				continue
 			}
 			id := pathToID[path]
-			docValues = append(docValues, id, doc.GOOS, doc.GOARCH, doc.Synopsis, makeValidUnicode(doc.HTML.String()))
+			for _, doc := range docs {
+				docValues = append(docValues, id, doc.GOOS, doc.GOARCH, doc.Synopsis, makeValidUnicode(doc.HTML.String()))
+			}
 		}
 		uniqueCols := []string{"path_id", "goos", "goarch"}
 		docCols := append(uniqueCols, "synopsis", "html")
@@ -677,6 +855,9 @@ func removeNonDistributableData(m *internal.Module) {
 			// Prune derived information that can't be stored.
 			p.Synopsis = ""
 			p.DocumentationHTML = safehtml.HTML{}
+			for _, s := range p.Symbols {
+				s.Synopsis = ""
+			}
 		}
 	}
 	if !m.IsRedistributable {
@@ -702,7 +883,12 @@ func (db *DB) DeleteModule(ctx context.Context, modulePath, version string) (err
 
 		var x int
 		err = db.db.QueryRow(ctx, `SELECT 1 FROM modules WHERE module_path=$1 LIMIT 1`, modulePath).Scan(&x)
-		if err != sql.ErrNoRows || err == nil {
+		if err == nil {
+			// The deleted version may have been the latest one, whose go.mod
+			// file determines the retracted versions.
+			return updateRetractions(ctx, tx, modulePath)
+		}
+		if err != sql.ErrNoRows {
 			return err
 		}
 		// No versions of this module exist; remove it from imports_unique.
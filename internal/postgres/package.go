@@ -68,7 +68,11 @@ func (db *DB) LegacyGetPackage(ctx context.Context, pkgPath, modulePath, version
 			m.version_type,
 		    m.source_info,
 			m.redistributable,
-			m.has_go_mod
+			m.has_go_mod,
+			m.retracted,
+			m.retraction_rationale,
+			m.deprecation,
+			m.go_version
 		FROM
 			modules m
 		INNER JOIN
@@ -87,7 +91,9 @@ func (db *DB) LegacyGetPackage(ctx context.Context, pkgPath, modulePath, version
 			ORDER BY
 				-- Order the versions by release then prerelease.
 				-- The default version should be the first release
-				-- version available, if one exists.
+				-- version available, if one exists. Retracted versions
+				-- are used only if there are no others.
+				m.retracted,
 				m.version_type = 'release' DESC,
 				m.sort_version DESC,
 				m.module_path DESC
@@ -115,7 +121,9 @@ func (db *DB) LegacyGetPackage(ctx context.Context, pkgPath, modulePath, version
 			ORDER BY
 				-- Order the versions by release then prerelease.
 				-- The default version should be the first release
-				-- version available, if one exists.
+				-- version available, if one exists. Retracted versions
+				-- are used only if there are no others.
+				m.retracted,
 				m.version_type = 'release' DESC,
 				m.sort_version DESC
 			LIMIT 1;`
@@ -142,7 +150,8 @@ func (db *DB) LegacyGetPackage(ctx context.Context, pkgPath, modulePath, version
 		database.NullIsEmpty(&docHTML), &pkg.GOOS, &pkg.GOARCH, &pkg.Version,
 		&pkg.CommitTime, database.NullIsEmpty(&pkg.LegacyReadmeFilePath), database.NullIsEmpty(&pkg.LegacyReadmeContents),
 		&pkg.ModulePath, &pkg.VersionType, jsonbScanner{&pkg.SourceInfo}, &pkg.LegacyModuleInfo.IsRedistributable,
-		&pkg.HasGoMod)
+		&pkg.HasGoMod, &pkg.Retracted, &pkg.RetractionRationale, &pkg.Deprecation,
+		&pkg.GoVersion)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("package %s@%s: %w", pkgPath, version, derrors.NotFound)
@@ -155,5 +164,14 @@ func (db *DB) LegacyGetPackage(ctx context.Context, pkgPath, modulePath, version
 	}
 	pkg.Licenses = lics
 	pkg.DocumentationHTML = convertDocumentation(docHTML)
+	docs, err := db.getDocumentation(ctx, pkg.Path, pkg.ModulePath, pkg.Version)
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		if doc.GOOS != pkg.GOOS || doc.GOARCH != pkg.GOARCH {
+			pkg.OtherDocumentation = append(pkg.OtherDocumentation, doc)
+		}
+	}
 	return &pkg, nil
 }
@@ -23,8 +23,9 @@ import (
 //
 // The rules for picking the best are:
 // 1. Match the module path and or version, if they are provided;
-// 2. Prefer newer module versions to older, and release to pre-release;
-// 3. In the unlikely event of two paths at the same version, pick the longer module path.
+// 2. Prefer versions that are not retracted;
+// 3. Prefer newer module versions to older, and release to pre-release;
+// 4. In the unlikely event of two paths at the same version, pick the longer module path.
 func (db *DB) GetPathInfo(ctx context.Context, path, inModulePath, inVersion string) (outModulePath, outVersion string, isPackage bool, err error) {
 	defer derrors.Wrap(&err, "DB.GetPathInfo(ctx, %q, %q, %q)", path, inModulePath, inVersion)
 
@@ -54,6 +55,7 @@ func (db *DB) GetPathInfo(ctx context.Context, path, inModulePath, inVersion str
 		WHERE p.path = $1
 		%s
 		ORDER BY
+			m.retracted,
 			m.version_type = 'release' DESC,
 			m.sort_version DESC,
 			m.module_path DESC
@@ -5,16 +5,25 @@
 package postgres
 
 import (
+	"golang.org/x/pkgsite/internal/config"
 	"golang.org/x/pkgsite/internal/database"
 )
 
 type DB struct {
-	db *database.DB
+	db             *database.DB
+	searchSettings config.SearchSettings
 }
 
 // New returns a new postgres DB.
 func New(db *database.DB) *DB {
-	return &DB{db}
+	return &DB{db: db}
+}
+
+// SetSearchSettings sets the weights used to rank search results. By default,
+// search results are not ranked by recency. SetSearchSettings must be called
+// before db is used to search.
+func (db *DB) SetSearchSettings(s config.SearchSettings) {
+	db.searchSettings = s
 }
 
 // Close closes a DB.
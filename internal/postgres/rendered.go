@@ -0,0 +1,69 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// GetRenderedPackage returns the data of the package rendered by the given
+// renderer version from source with the given content hash, and records that
+// it was used. It returns an error wrapping derrors.NotFound if there is no
+// such package.
+//
+// GetRenderedPackage and InsertRenderedPackage implement fetch.RenderCache.
+func (db *DB) GetRenderedPackage(ctx context.Context, contentHash string, rendererVersion int) (_ []byte, err error) {
+	defer derrors.Wrap(&err, "DB.GetRenderedPackage(ctx, %q, %d)", contentHash, rendererVersion)
+
+	var data []byte
+	err = db.db.QueryRow(ctx, `
+		UPDATE rendered_packages
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE content_hash = $1 AND renderer_version = $2
+		RETURNING data`,
+		contentHash, rendererVersion).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, derrors.NotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// InsertRenderedPackage stores the data of a package rendered by the given
+// renderer version from source with the given content hash. If the package
+// is already stored, it does nothing: the same source and renderer version
+// always produce the same package.
+func (db *DB) InsertRenderedPackage(ctx context.Context, contentHash string, rendererVersion int, data []byte) (err error) {
+	defer derrors.Wrap(&err, "DB.InsertRenderedPackage(ctx, %q, %d, data)", contentHash, rendererVersion)
+
+	_, err = db.db.Exec(ctx, `
+		INSERT INTO rendered_packages (content_hash, renderer_version, data)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (content_hash, renderer_version) DO NOTHING`,
+		contentHash, rendererVersion, data)
+	return err
+}
+
+// DeleteUnusedRenderedPackages deletes the rendered packages that were not
+// rendered by the given renderer version, and those that have not been used
+// since the given time. It returns the number of packages deleted.
+func (db *DB) DeleteUnusedRenderedPackages(ctx context.Context, rendererVersion int, since time.Time) (_ int64, err error) {
+	defer derrors.Wrap(&err, "DB.DeleteUnusedRenderedPackages(ctx, %d, %s)", rendererVersion, since)
+
+	res, err := db.db.Exec(ctx, `
+		DELETE FROM rendered_packages
+		WHERE renderer_version != $1 OR used_at < $2`,
+		rendererVersion, since)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+func TestRenderedPackages(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	if _, err := testDB.GetRenderedPackage(ctx, "h", 1); !errors.Is(err, derrors.NotFound) {
+		t.Fatalf("got error %v, want NotFound", err)
+	}
+	if err := testDB.InsertRenderedPackage(ctx, "h", 1, []byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	// Inserting the same key again keeps the first data.
+	if err := testDB.InsertRenderedPackage(ctx, "h", 1, []byte("other")); err != nil {
+		t.Fatal(err)
+	}
+	if err := testDB.InsertRenderedPackage(ctx, "h", 2, []byte("two")); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		version int
+		want    string
+	}{
+		{1, "one"},
+		{2, "two"},
+	} {
+		got, err := testDB.GetRenderedPackage(ctx, "h", test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("renderer version %d: got %q, want %q", test.version, got, test.want)
+		}
+	}
+	if _, err := testDB.GetRenderedPackage(ctx, "h", 3); !errors.Is(err, derrors.NotFound) {
+		t.Errorf("got error %v, want NotFound", err)
+	}
+
+	// Packages of other renderer versions, and packages that have not been
+	// used since the given time, are deleted.
+	if _, err := testDB.db.Exec(ctx, `UPDATE rendered_packages SET used_at = $1 WHERE renderer_version = 2`,
+		time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := testDB.InsertRenderedPackage(ctx, "old", 2, []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testDB.db.Exec(ctx, `UPDATE rendered_packages SET used_at = $1 WHERE content_hash = 'old'`,
+		time.Now().Add(-2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	// Getting a package records that it was used.
+	if _, err := testDB.GetRenderedPackage(ctx, "h", 2); err != nil {
+		t.Fatal(err)
+	}
+	n, err := testDB.DeleteUnusedRenderedPackages(ctx, 2, time.Now().Add(-90*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ("h", 1) is of another renderer version, and "old" has not been used.
+	if n != 2 {
+		t.Errorf("deleted %d packages, want 2", n)
+	}
+	if _, err := testDB.GetRenderedPackage(ctx, "h", 2); err != nil {
+		t.Errorf("(h, 2) was deleted: %v", err)
+	}
+	for _, key := range []struct {
+		hash    string
+		version int
+	}{
+		{"h", 1},
+		{"old", 2},
+	} {
+		if _, err := testDB.GetRenderedPackage(ctx, key.hash, key.version); !errors.Is(err, derrors.NotFound) {
+			t.Errorf("%v: got error %v, want NotFound", key, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/database"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// updateRetractions applies the retractions and deprecation message of the
+// go.mod file of the latest version of the module to all of its versions. It
+// must be called whenever a version of the module is inserted, since the new
+// version may be the latest one, or may be retracted by the latest one.
+func updateRetractions(ctx context.Context, db *database.DB, modulePath string) (err error) {
+	defer derrors.Wrap(&err, "updateRetractions(ctx, %q)", modulePath)
+
+	var (
+		retractionsJSON []byte
+		retractions     []*internal.Retraction
+		deprecation     string
+	)
+	err = db.QueryRow(ctx, `
+		SELECT go_mod_retractions, go_mod_deprecation
+		FROM modules
+		WHERE module_path = $1
+		ORDER BY
+			-- Order the versions by release then prerelease.
+			version_type = 'release' DESC,
+			sort_version DESC
+		LIMIT 1`, modulePath).Scan(&retractionsJSON, &deprecation)
+	if err != nil {
+		return err
+	}
+	if retractionsJSON != nil {
+		if err := json.Unmarshal(retractionsJSON, &retractions); err != nil {
+			return err
+		}
+	}
+
+	// Update only the versions whose status changes.
+	var ids, retracted, rationales, deprecations []interface{}
+	collect := func(rows *sql.Rows) error {
+		var (
+			id                                    int
+			version, oldRationale, oldDeprecation string
+			oldRetracted                          bool
+		)
+		if err := rows.Scan(&id, &version, &oldRetracted, &oldRationale, &oldDeprecation); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		var (
+			isRetracted bool
+			rationale   string
+		)
+		for _, r := range retractions {
+			if r.Contains(version) {
+				isRetracted = true
+				rationale = r.Rationale
+				break
+			}
+		}
+		if isRetracted != oldRetracted || rationale != oldRationale || deprecation != oldDeprecation {
+			ids = append(ids, id)
+			retracted = append(retracted, isRetracted)
+			rationales = append(rationales, rationale)
+			deprecations = append(deprecations, deprecation)
+		}
+		return nil
+	}
+	if err := db.RunQuery(ctx, `
+		SELECT id, version, retracted, retraction_rationale, deprecation
+		FROM modules
+		WHERE module_path = $1`, collect, modulePath); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return db.BulkUpdate(ctx, "modules",
+		[]string{"id", "retracted", "retraction_rationale", "deprecation"},
+		[]string{"INT", "BOOLEAN", "TEXT", "TEXT"},
+		[][]interface{}{ids, retracted, rationales, deprecations})
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestRetractions(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	const modulePath = "example.com/retract"
+	insert := func(version string, retractions []*internal.Retraction, deprecation string) {
+		t.Helper()
+		m := sample.Module(modulePath, version, "")
+		m.GoModRetractions = retractions
+		m.GoModDeprecation = deprecation
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(version string, wantRetracted bool, wantRationale, wantDeprecation string) {
+		t.Helper()
+		mi, err := testDB.GetModuleInfo(ctx, modulePath, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mi.Retracted != wantRetracted || mi.RetractionRationale != wantRationale || mi.Deprecation != wantDeprecation {
+			t.Errorf("%s: got (%t, %q, %q), want (%t, %q, %q)", version,
+				mi.Retracted, mi.RetractionRationale, mi.Deprecation,
+				wantRetracted, wantRationale, wantDeprecation)
+		}
+	}
+	checkLatest := func(want string) {
+		t.Helper()
+		_, got, _, err := testDB.GetPathInfo(ctx, modulePath, internal.UnknownModulePath, internal.LatestVersion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("GetPathInfo: got latest version %q, want %q", got, want)
+		}
+		mi, err := testDB.LegacyGetModuleInfo(ctx, modulePath, internal.LatestVersion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mi.Version != want {
+			t.Errorf("LegacyGetModuleInfo: got latest version %q, want %q", mi.Version, want)
+		}
+	}
+
+	insert("v1.0.0", nil, "")
+	insert("v1.1.0", nil, "")
+	checkLatest("v1.1.0")
+
+	// The latest version's go.mod file retracts v1.1.0 and itself.
+	insert("v1.2.0", []*internal.Retraction{
+		{Low: "v1.1.0", High: "v1.1.9", Rationale: "Data race."},
+		{Low: "v1.2.0", High: "v1.2.0"},
+	}, "use example.com/retract/v2")
+	check("v1.0.0", false, "", "use example.com/retract/v2")
+	check("v1.1.0", true, "Data race.", "use example.com/retract/v2")
+	check("v1.2.0", true, "", "use example.com/retract/v2")
+	checkLatest("v1.0.0")
+
+	versions, err := testDB.GetTaggedVersionsForModule(ctx, modulePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var retracted []string
+	for _, v := range versions {
+		if v.Retracted {
+			retracted = append(retracted, v.Version)
+		}
+	}
+	if len(retracted) != 2 {
+		t.Errorf("GetTaggedVersionsForModule: got retracted versions %v, want v1.2.0 and v1.1.0", retracted)
+	}
+
+	// Older versions inserted later are subject to the latest go.mod file.
+	insert("v1.1.1", nil, "")
+	check("v1.1.1", true, "Data race.", "use example.com/retract/v2")
+
+	// Once the latest version is deleted, the retractions no longer apply.
+	if err := testDB.DeleteModule(ctx, modulePath, "v1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	check("v1.1.0", false, "", "")
+	checkLatest("v1.1.1")
+}
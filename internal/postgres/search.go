@@ -9,8 +9,11 @@ import (
 	"database/sql"
 	"fmt"
 	"math"
+	"path"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lib/pq"
@@ -19,8 +22,10 @@ import (
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/tag"
 	"go.opencensus.io/trace"
+	"golang.org/x/mod/module"
 	"golang.org/x/mod/semver"
 	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/config"
 	"golang.org/x/pkgsite/internal/database"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/log"
@@ -71,6 +76,20 @@ type searchResponse struct {
 	// estimate, or for an alternate search method to return with
 	// uncounted=false.
 	uncounted bool
+	// fallback reports whether this response is from a fallback searcher. Its
+	// results are never returned on their own, but are added to a first page
+	// with fewer than minSearchResults results.
+	fallback bool
+	// facets holds the facet counts over all matching documents. It is
+	// computed only by counted searchers; for uncounted responses it is filled
+	// in with estimated counts along with the result count estimate.
+	facets *internal.SearchFacets
+	// events holds the search events that occurred before the response was
+	// returned by hedgedSearch or search.
+	events []searchEvent
+	// sources maps the package path of each result to the searcher that
+	// returned it. It is set only by hedgedSearch.
+	sources map[string]string
 }
 
 // searchEvent is used to log structured information about search events for
@@ -85,16 +104,19 @@ type searchEvent struct {
 	Err error
 }
 
-// A searcher is used to execute a single search request.
-type searcher func(db *DB, ctx context.Context, q string, limit, offset int) searchResponse
+// A searcher is used to execute a single search request. If cursor is
+// non-nil, the results start after it rather than at offset.
+type searcher func(db *DB, ctx context.Context, q string, filters internal.SearchFilters, limit, offset int, cursor *internal.SearchCursor) searchResponse
 
 // The searchers used by Search.
 var searchers = map[string]searcher{
 	"popular": (*DB).popularSearch,
 	"deep":    (*DB).deepSearch,
+	"trigram": (*DB).trigramSearch,
 }
 
-// Search executes two search requests concurrently:
+// Search executes two search requests concurrently, along with the fallback
+// trigram search:
 //   - a sequential scan of packages in descending order of popularity.
 //   - all packages ("deep" search) using an inverted index to filter to search
 //     terms.
@@ -117,24 +139,183 @@ var searchers = map[string]searcher{
 // The gap in this optimization is search terms that are very frequent, but
 // rarely relevant: "int" or "package", for example. In these cases we'll pay
 // the penalty of a deep search that scans nearly every package.
-func (db *DB) Search(ctx context.Context, q string, limit, offset int) (_ []*internal.SearchResult, err error) {
-	defer derrors.Wrap(&err, "DB.Search(ctx, %q, %d, %d)", q, limit, offset)
-	resp, err := db.hedgedSearch(ctx, q, limit, offset, searchers, nil)
+//
+// If the first page has fewer than minSearchResults results, the results of
+// trigram search, which finds packages whose path or name is similar to the
+// query, are added to it.
+//
+// Only packages matching filters are returned, and only they are counted.
+//
+// Search also returns the facet counts over all matching packages, which may
+// be approximate.
+//
+// If cursor is non-nil, Search returns the results after it, and offset is
+// ignored. The cursor of the last result on a page is used to get the next
+// page, so that popular search can exit as early on later pages as on the
+// first, and deep search does not need to skip the earlier results.
+func (db *DB) Search(ctx context.Context, q string, filters internal.SearchFilters, limit, offset int, cursor *internal.SearchCursor) (_ []*internal.SearchResult, _ *internal.SearchFacets, err error) {
+	defer derrors.Wrap(&err, "DB.Search(ctx, %q, %+v, %d, %d, %+v)", q, filters, limit, offset, cursor)
+	resp, err := db.search(ctx, q, filters, limit, offset, cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.results, resp.facets, nil
+}
+
+// SearchWithExplanation is like Search, but also explains how the search was
+// executed and how the score of each result was computed.
+func (db *DB) SearchWithExplanation(ctx context.Context, q string, filters internal.SearchFilters, limit, offset int, cursor *internal.SearchCursor) (_ []*internal.SearchResult, _ *internal.SearchFacets, _ *internal.SearchExplanation, err error) {
+	defer derrors.Wrap(&err, "DB.SearchWithExplanation(ctx, %q, %+v, %d, %d, %+v)", q, filters, limit, offset, cursor)
+	resp, err := db.search(ctx, q, filters, limit, offset, cursor)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	exp := &internal.SearchExplanation{}
+	for _, e := range resp.events {
+		t := &internal.SearcherTiming{Name: e.Type, Latency: e.Latency}
+		if e.Err != nil {
+			t.Err = e.Err.Error()
+		}
+		exp.Searchers = append(exp.Searchers, t)
+	}
+	exp.Results, err = db.explainScores(ctx, q, resp)
 	if err != nil {
+		return nil, nil, nil, err
+	}
+	return resp.results, resp.facets, exp, nil
+}
+
+// search implements Search. The results of the returned response are
+// complete, and exclude excluded paths.
+func (db *DB) search(ctx context.Context, q string, filters internal.SearchFilters, limit, offset int, cursor *internal.SearchCursor) (*searchResponse, error) {
+	if cursor != nil {
+		offset = 0
+	}
+	ss := searchers
+	if filters.MajorVersion != "" {
+		// popular_search does not take the major version filter.
+		ss = map[string]searcher{
+			"deep":    (*DB).deepSearch,
+			"trigram": (*DB).trigramSearch,
+		}
+	}
+	return db.hedgedSearch(ctx, q, filters, limit, offset, cursor, ss, nil)
+}
+
+// explainScores returns the factors of the score of each result in resp,
+// which was returned by search for the query q.
+func (db *DB) explainScores(ctx context.Context, q string, resp *searchResponse) (_ []*internal.ScoreExplanation, err error) {
+	defer derrors.Wrap(&err, "DB.explainScores(ctx, %q)", q)
+
+	var paths []string
+	for _, r := range resp.results {
+		paths = append(paths, r.PackagePath)
+	}
+	query := fmt.Sprintf(`
+		SELECT
+			package_path,
+			%s,
+			%s,
+			%s,
+			%s,
+			%s,
+			%s,
+			%s
+		FROM search_documents
+		WHERE package_path = ANY($2)`,
+		textRankExpr, similarityExpr, popularityExpr, redistributablePenaltyExpr,
+		goModPenaltyExpr, recencyExpr(db.searchSettings), boostExpr)
+	explanations := map[string]*internal.ScoreExplanation{}
+	collect := func(rows *sql.Rows) error {
+		var e internal.ScoreExplanation
+		if err := rows.Scan(&e.PackagePath, &e.TextRank, &e.Similarity, &e.Popularity,
+			&e.RedistributablePenalty, &e.GoModPenalty, &e.Recency, &e.Boost); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		explanations[e.PackagePath] = &e
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, q, pq.Array(paths)); err != nil {
 		return nil, err
 	}
-	// Filter out excluded paths.
-	var results []*internal.SearchResult
+	var exps []*internal.ScoreExplanation
 	for _, r := range resp.results {
-		ex, err := db.IsExcluded(ctx, r.PackagePath)
-		if err != nil {
-			return nil, err
+		e, ok := explanations[r.PackagePath]
+		if !ok {
+			e = &internal.ScoreExplanation{PackagePath: r.PackagePath}
 		}
-		if !ex {
-			results = append(results, r)
+		e.Source = resp.sources[r.PackagePath]
+		e.Score = r.Score
+		exps = append(exps, e)
+	}
+	return exps, nil
+}
+
+// minSearchResults is the number of results below which hedgedSearch adds the
+// results of the fallback searcher to the first page of results.
+const minSearchResults = 5
+
+// addFallbackResults appends the results of the fallback response that are
+// not already in results, up to limit results in total, and returns the
+// combined results and the number of results added. The added results are
+// counted along with the results of the query, and if any are added, each
+// result is marked with a suggested correction of q.
+//
+// The added results are not in the order of the query's results, so their
+// cursor is that of the last result of the query: the next page continues
+// after the query's results. If there are none, the cursor has a score of
+// zero, which no result of the query follows.
+func (db *DB) addFallbackResults(ctx context.Context, q string, filters internal.SearchFilters, limit int, results []*internal.SearchResult, fallback searchResponse) (_ []*internal.SearchResult, _ int, err error) {
+	defer derrors.Wrap(&err, "DB.addFallbackResults(ctx, %q, %+v, %d)", q, filters, limit)
+
+	var (
+		total       uint64
+		approximate bool
+	)
+	if len(results) > 0 {
+		total = results[0].NumResults
+		approximate = results[0].Approximate
+	}
+	seen := map[string]bool{}
+	candidates := results
+	for _, r := range results {
+		seen[r.PackagePath] = true
+	}
+	for _, r := range fallback.results {
+		if !seen[r.PackagePath] {
+			candidates = append(candidates, r)
 		}
 	}
-	return results, nil
+	// Grouping keeps the existing results, since they are already grouped,
+	// and removes the candidates whose series is already present.
+	candidates, err = db.groupSeries(ctx, q, trigramScoreExpr(db.searchSettings), filters, candidates)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	added := len(candidates) - len(results)
+	if added == 0 {
+		return results, 0, nil
+	}
+	for _, r := range candidates[len(results):] {
+		if len(results) > 0 {
+			r.Cursor = results[len(results)-1].Cursor
+		} else {
+			r.Cursor = &internal.SearchCursor{CommitTime: time.Unix(0, 0).UTC(), PackagePath: r.PackagePath}
+		}
+	}
+	suggestion, err := db.searchSuggestion(ctx, q)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, r := range candidates {
+		r.NumResults = total + uint64(added)
+		r.Approximate = approximate
+		r.Suggestion = suggestion
+	}
+	return candidates, added, nil
 }
 
 // Penalties to search scores, applied as multipliers to the score.
@@ -147,43 +328,264 @@ const (
 	noGoModPenalty = 0.8
 )
 
-// scoreExpr is the expression that computes the search score.
-// It is the product of:
-// - The Postgres ts_rank score, based the relevance of the document to the query.
-// - The log of the module's popularity, estimated by the number of importing packages.
-//   The log factor contains exp(1) so that it is always >= 1. Taking the log
-//   of imported_by_count instead of using it directly makes the effect less
-//   dramatic: being 2x as popular only has an additive effect.
-// - A penalty factor for non-redistributable modules, since a lot of
-//   details cannot be displayed.
-// The first argument to ts_rank is an array of weights for the four tsvector sections,
-// in the order D, C, B, A.
-// The weights below match the defaults except for B.
-var scoreExpr = fmt.Sprintf(`
-		ts_rank('{0.1, 0.2, 1.0, 1.0}', tsv_search_tokens, websearch_to_tsquery($1)) *
-		ln(exp(1)+imported_by_count) *
-		CASE WHEN redistributable THEN 1 ELSE %f END *
-		CASE WHEN COALESCE(has_go_mod, true) THEN 1 ELSE %f END
-	`, nonRedistributablePenalty, noGoModPenalty)
+// Expressions for the factors of the search score. Each is explained by a
+// field of internal.ScoreExplanation.
+var (
+	// textRankExpr is the Postgres ts_rank score, based on the relevance of
+	// the document to the query.
+	// The first argument to ts_rank is an array of weights for the four
+	// tsvector sections, in the order D, C, B, A.
+	// The weights below match the defaults except for B.
+	textRankExpr = `ts_rank('{0.1, 0.2, 1.0, 1.0}', tsv_search_tokens, websearch_to_tsquery($1))`
+
+	// similarityExpr is the trigram similarity of the document's package path
+	// or name to the query. It is used instead of textRankExpr by
+	// trigramSearch.
+	similarityExpr = `GREATEST(word_similarity($1, package_path), word_similarity($1, name))`
+
+	// popularityExpr is the log of the module's popularity, estimated by the
+	// number of importing packages.
+	// The log factor contains exp(1) so that it is always >= 1. Taking the log
+	// of imported_by_count instead of using it directly makes the effect less
+	// dramatic: being 2x as popular only has an additive effect.
+	popularityExpr = `ln(exp(1)+imported_by_count)`
+
+	// redistributablePenaltyExpr is a penalty factor for non-redistributable
+	// modules, since a lot of details cannot be displayed.
+	redistributablePenaltyExpr = fmt.Sprintf(`CASE WHEN redistributable THEN 1 ELSE %s END`, realLiteral(nonRedistributablePenalty))
+
+	// goModPenaltyExpr is a penalty factor for modules without a go.mod file.
+	goModPenaltyExpr = fmt.Sprintf(`CASE WHEN COALESCE(has_go_mod, true) THEN 1 ELSE %s END`, realLiteral(noGoModPenalty))
+
+	// boostExpr is the multiplier of the longest prefix in the search_boosts
+	// table that matches the package (see internal.SearchBoost), or 1 if none
+	// does. The same factor is computed by the popular_search stored function.
+	boostExpr = `COALESCE((
+			SELECT b.multiplier FROM search_boosts b
+			WHERE starts_with(search_documents.package_path, b.prefix)
+			OR search_documents.module_path = b.prefix
+			ORDER BY length(b.prefix) DESC
+			LIMIT 1), 1)`
+)
+
+// realLiteral returns a literal of type real for f, written as the driver
+// sends a float64 argument. The popular_search stored function takes the
+// penalties and recency settings as real arguments, so the expressions above
+// use real literals for them, to compute exactly the same scores. Otherwise a
+// cursor from one searcher would not be at the same position in the results
+// of another.
+func realLiteral(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64) + "::real"
+}
+
+// scoreExpr returns the expression that computes the search score.
+// It is the product of textRankExpr and the factors that do not depend on the
+// query (see rankFactorsExpr).
+func scoreExpr(settings config.SearchSettings) string {
+	return fmt.Sprintf(`
+		%s *
+		%s
+	`, textRankExpr, rankFactorsExpr(settings))
+}
+
+// trigramScoreExpr returns the expression that computes the score of the
+// results of trigramSearch. It is the product of similarityExpr and the
+// factors that do not depend on the query.
+func trigramScoreExpr(settings config.SearchSettings) string {
+	return fmt.Sprintf(`
+		%s *
+		%s
+	`, similarityExpr, rankFactorsExpr(settings))
+}
+
+// rankFactorsExpr returns the expression that computes the part of the search
+// score that does not depend on the query. It is the product of
+// popularityExpr, the penalty factors, the recency factor (see recencyExpr)
+// and boostExpr.
+func rankFactorsExpr(settings config.SearchSettings) string {
+	return fmt.Sprintf(`
+		%s *
+		%s *
+		%s *
+		%s *
+		%s
+	`, popularityExpr, redistributablePenaltyExpr, goModPenaltyExpr, recencyExpr(settings), boostExpr)
+}
+
+// recencyArgs returns the arguments to the popular_search stored function that
+// determine the recency factor.
+func recencyArgs(settings config.SearchSettings) []interface{} {
+	if settings.RecencyWeight == 0 {
+		// Avoid dividing by zero in the unused part of the factor.
+		return []interface{}{0, 1, 1}
+	}
+	return []interface{}{settings.RecencyWeight, settings.RecencyHalfLifeDays, settings.ActiveVersions}
+}
+
+// recencyExpr returns the expression that computes the recency factor of the
+// search score, as described at config.SearchSettings. The factor decays
+// exponentially with the age of the module's latest commit, but not below the
+// fraction of settings.ActiveVersions that the module tagged in the past year.
+//
+// The factor is never greater than 1, so the popular search can still exit
+// early once its results cannot be beaten by ln(e+imported_by_count) times
+// the largest boost.
+//
+// The same factor is computed by the popular_search stored function, so the
+// two must be kept in sync.
+func recencyExpr(settings config.SearchSettings) string {
+	if settings.RecencyWeight == 0 {
+		return "1"
+	}
+	return fmt.Sprintf(`(1 - %s * (1 - LEAST(1, GREATEST(
+			power(0.5, EXTRACT(EPOCH FROM (CURRENT_TIMESTAMP - commit_time)) / (86400 * %s)),
+			num_recent_versions / %d::real))))`,
+		realLiteral(settings.RecencyWeight), realLiteral(settings.RecencyHalfLifeDays), settings.ActiveVersions)
+}
+
+// filterExpr returns a boolean expression that is true for the search
+// documents that match the search filters and are not excluded. The filters
+// are passed as seven query parameters (see filterArgs), beginning with
+// parameter number n. A NULL parameter matches every document.
+//
+// The same filters, except for the major version, are applied by the
+// popular_search stored function, so the two must be kept in sync.
+func filterExpr(n int) string {
+	return fmt.Sprintf(`(
+		($%[1]d::text[] IS NULL OR license_types && $%[1]d::text[]) AND
+		($%[2]d::text IS NULL OR module_path = $%[2]d OR starts_with(module_path, $%[2]d || '/')) AND
+		($%[3]d::text IS NULL OR package_path IN (SELECT from_path FROM imports_unique WHERE to_path = $%[3]d)) AND
+		($%[4]d::boolean IS NULL OR has_go_mod = $%[4]d) AND
+		($%[5]d::version_type IS NULL OR EXISTS (
+			SELECT 1 FROM modules m
+			WHERE m.module_path = search_documents.module_path
+			AND m.version = search_documents.version
+			AND m.version_type = $%[5]d)) AND
+		($%[6]d::text IS NULL OR EXISTS (
+			SELECT 1 FROM modules m
+			WHERE m.module_path = search_documents.module_path
+			AND m.version = search_documents.version
+			AND (m.go_version = '' OR
+				string_to_array(m.go_version, '.')::int[] <= string_to_array($%[6]d, '.')::int[]))) AND
+		($%[7]d::text IS NULL OR split_part(version, '.', 1) = $%[7]d) AND
+		%[8]s
+	)`, n, n+1, n+2, n+3, n+4, n+5, n+6, notExcludedExpr("search_documents.package_path"))
+}
+
+// notExcludedExpr returns a boolean expression that is true if the path in
+// column does not begin with any of the prefixes in the excluded_prefixes
+// table. It is evaluated in the query, rather than with IsExcluded on the
+// results, so that excluded packages do not take up results or get counted.
+//
+// The prefixes are read into an array by an uncorrelated subquery, which
+// Postgres evaluates once per query rather than once per row.
+func notExcludedExpr(column string) string {
+	return fmt.Sprintf(`NOT (%s ^@ ANY (ARRAY(SELECT prefix FROM excluded_prefixes)))`, column)
+}
+
+// filterArgs returns the query parameters for filterExpr that represent
+// filters. Unset filters are passed as NULL. The first numPopularFilterArgs of
+// them are also the filter parameters of popular_search.
+func filterArgs(filters internal.SearchFilters) []interface{} {
+	var licenses, modulePath, imports, hasGoMod, versionType, goVersion, major interface{}
+	if len(filters.Licenses) > 0 {
+		licenses = pq.Array(filters.Licenses)
+	}
+	if filters.ModulePath != "" {
+		modulePath = filters.ModulePath
+	}
+	if filters.Imports != "" {
+		imports = filters.Imports
+	}
+	if filters.HasGoMod != nil {
+		hasGoMod = *filters.HasGoMod
+	}
+	if filters.VersionType != "" {
+		versionType = filters.VersionType.String()
+	}
+	if filters.GoVersion != "" {
+		goVersion = filters.GoVersion
+	}
+	if filters.MajorVersion != "" {
+		major = filters.MajorVersion
+	}
+	return []interface{}{licenses, modulePath, imports, hasGoMod, versionType, goVersion, major}
+}
+
+// numPopularFilterArgs is the number of filterArgs that popular_search takes.
+// It does not take the major version filter, so popularSearch is not used
+// when that filter is set.
+const numPopularFilterArgs = 6
+
+// cursorExpr returns a boolean expression that is true for the search results
+// after a cursor, in the order of decreasing score, decreasing commit time and
+// increasing package path. The cursor is passed as three query parameters
+// (see cursorArgs), beginning with parameter number n. A NULL cursor matches
+// every result.
+//
+// The same comparison is made by the popular_search stored function, so the
+// two must be kept in sync.
+func cursorExpr(n int) string {
+	return fmt.Sprintf(`(
+		$%[1]d::double precision IS NULL OR
+		score < $%[1]d OR
+		(score = $%[1]d AND commit_time < $%[2]d::timestamptz) OR
+		(score = $%[1]d AND commit_time = $%[2]d AND package_path > $%[3]d::text)
+	)`, n, n+1, n+2)
+}
+
+// cursorArgs returns the query parameters for cursorExpr that represent
+// cursor. A nil cursor is passed as NULL.
+func cursorArgs(cursor *internal.SearchCursor) []interface{} {
+	if cursor == nil {
+		return []interface{}{nil, nil, nil}
+	}
+	return []interface{}{cursor.Score, cursor.CommitTime, cursor.PackagePath}
+}
 
 // hedgedSearch executes multiple search methods and returns the first
-// available result.
+// available result. The results of a fallback searcher are added to the first
+// page if it has fewer than minSearchResults results.
 // The optional guardTestResult func may be used to allow tests to control the
 // order in which search results are returned.
-func (db *DB) hedgedSearch(ctx context.Context, q string, limit, offset int, searchers map[string]searcher, guardTestResult func(string) func()) (*searchResponse, error) {
+func (db *DB) hedgedSearch(ctx context.Context, q string, filters internal.SearchFilters, limit, offset int, cursor *internal.SearchCursor, searchers map[string]searcher, guardTestResult func(string) func()) (*searchResponse, error) {
 	searchStart := time.Now()
+	var (
+		mu     sync.Mutex
+		events []searchEvent
+	)
+	logEvent := func(e searchEvent) {
+		log.Debug(ctx, e)
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}
 	responses := make(chan searchResponse, len(searchers))
 	// cancel all unfinished searches when a result (or error) is returned. The
 	// effectiveness of this depends on the database driver.
 	searchCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	// Asynchronously query for the estimated result count.
+	// Asynchronously query for the estimated result count, and the estimated
+	// facet counts alongside it.
 	estimateChan := make(chan estimateResponse, 1)
 	go func() {
 		start := time.Now()
-		estimateResp := db.estimateResultsCount(searchCtx, q)
-		log.Debug(ctx, searchEvent{
+		type facetsResponse struct {
+			facets *internal.SearchFacets
+			err    error
+		}
+		facetsChan := make(chan facetsResponse, 1)
+		go func() {
+			facets, err := db.searchFacets(searchCtx, q, filters, facetSampleRegisters)
+			facetsChan <- facetsResponse{facets, err}
+		}()
+		estimateResp := db.estimateResultsCount(searchCtx, q, filters)
+		fr := <-facetsChan
+		if estimateResp.err == nil {
+			estimateResp.facets, estimateResp.err = fr.facets, fr.err
+		}
+		logEvent(searchEvent{
 			Type:    "estimate",
 			Latency: time.Since(start),
 			Err:     estimateResp.err,
@@ -199,8 +601,8 @@ func (db *DB) hedgedSearch(ctx context.Context, q string, limit, offset int, sea
 		s := s
 		go func() {
 			start := time.Now()
-			resp := s(db, searchCtx, q, limit, offset)
-			log.Debug(ctx, searchEvent{
+			resp := s(db, searchCtx, q, filters, limit, offset, cursor)
+			logEvent(searchEvent{
 				Type:    resp.source,
 				Latency: time.Since(start),
 				Err:     resp.err,
@@ -214,7 +616,22 @@ func (db *DB) hedgedSearch(ctx context.Context, q string, limit, offset int, sea
 	// Note for future readers: in previous iterations of this code we kept
 	// reading responses if the first one had an error, with the goal to minimize
 	// error ratio. That didn't behave well if Postgres was overloaded.
-	resp := <-responses
+	//
+	// A fallback response is set aside until it is needed.
+	var (
+		resp     searchResponse
+		fallback *searchResponse
+		pending  = len(searchers)
+	)
+	for {
+		r := <-responses
+		pending--
+		if !r.fallback {
+			resp = r
+			break
+		}
+		fallback = &r
+	}
 	if resp.err != nil {
 		return nil, fmt.Errorf("%q search failed: %v", resp.source, resp.err)
 	}
@@ -225,7 +642,10 @@ func (db *DB) hedgedSearch(ctx context.Context, q string, limit, offset int, sea
 		for {
 			select {
 			case nextResp := <-responses:
+				pending--
 				switch {
+				case nextResp.fallback:
+					fallback = &nextResp
 				case nextResp.err != nil:
 					// There are alternatives here: we could continue waiting for the
 					// estimate. But on the principle that errors are most likely to be
@@ -248,12 +668,28 @@ func (db *DB) hedgedSearch(ctx context.Context, q string, limit, offset int, sea
 					r.NumResults = estr.estimate
 					r.Approximate = true
 				}
+				resp.facets = estr.facets
 				break loop
 			case <-ctx.Done():
 				return nil, fmt.Errorf("context deadline exceeded while waiting for estimated result count")
 			}
 		}
 	}
+	fillPage := offset == 0 && cursor == nil && len(resp.results) < minSearchResults
+	if fillPage {
+		// Wait for the fallback response, if there is a fallback searcher.
+		for fallback == nil && pending > 0 {
+			select {
+			case nextResp := <-responses:
+				pending--
+				if nextResp.fallback {
+					fallback = &nextResp
+				}
+			case <-ctx.Done():
+				return nil, fmt.Errorf("context deadline exceeded while waiting for fallback results")
+			}
+		}
+	}
 	// cancel proactively here: we've got the search result we need.
 	cancel()
 	// latency is only recorded for valid search results, as fast failures could
@@ -264,6 +700,33 @@ func (db *DB) hedgedSearch(ctx context.Context, q string, limit, offset int, sea
 	stats.RecordWithTags(ctx,
 		[]tag.Mutator{tag.Upsert(keySearchSource, resp.source)},
 		searchLatency.M(latency))
+	// Record the position of each result before groupSeries replaces it with
+	// another member of its series.
+	for _, r := range resp.results {
+		r.Cursor = &internal.SearchCursor{Score: r.Score, CommitTime: r.CommitTime, PackagePath: r.PackagePath}
+	}
+	results, err := db.groupSeries(ctx, q, scoreExpr(db.searchSettings), filters, resp.results)
+	if err != nil {
+		return nil, err
+	}
+	resp.sources = map[string]string{}
+	for _, r := range results {
+		resp.sources[r.PackagePath] = resp.source
+	}
+	if fillPage && fallback != nil {
+		if fallback.err != nil {
+			return nil, fmt.Errorf("%q search failed: %v", fallback.source, fallback.err)
+		}
+		var added int
+		results, added, err = db.addFallbackResults(ctx, q, filters, limit, results, *fallback)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range results[len(results)-added:] {
+			resp.sources[r.PackagePath] = fallback.source
+		}
+	}
+	resp.results = results
 	// To avoid fighting with the query planner, our searches only hit the
 	// search_documents table and we enrich after getting the results. In the
 	// future, we may want to fully denormalize and put all search data in the
@@ -271,6 +734,12 @@ func (db *DB) hedgedSearch(ctx context.Context, q string, limit, offset int, sea
 	if err := db.addPackageDataToSearchResults(ctx, resp.results); err != nil {
 		return nil, err
 	}
+	if err := db.addReadmeSectionsToSearchResults(ctx, q, resp.results); err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	resp.events = append([]searchEvent(nil), events...)
+	mu.Unlock()
 	return &resp, nil
 }
 
@@ -301,7 +770,8 @@ const hllRegisterCount = 128
 //   This should work for any register count >= 128. If we are to decrease this
 //   register count, we should adjust the estimate for a_m below according to
 //   the formulas in the wikipedia article above.
-var hllQuery = fmt.Sprintf(`
+func hllQuery(settings config.SearchSettings) string {
+	return fmt.Sprintf(`
 	WITH hll_data AS (
 		SELECT (
 			SELECT * FROM (
@@ -311,6 +781,7 @@ var hllQuery = fmt.Sprintf(`
 					%[2]s *
 					CASE WHEN tsv_search_tokens @@ websearch_to_tsquery($1) THEN 1 ELSE 0 END
 				) > 0.1
+				AND %[3]s
 				AND hll_register=generate_series
 				ORDER BY hll_leading_zeros DESC
 			) t
@@ -335,17 +806,20 @@ var hllQuery = fmt.Sprintf(`
 			)::int AS result_count,
 			%[1]d - count(1) AS empty_register_count
 		FROM nonempty_registers
-	) d`, hllRegisterCount, scoreExpr)
+	) d`, hllRegisterCount, scoreExpr(settings), filterExpr(2))
+}
 
 type estimateResponse struct {
 	estimate uint64
+	facets   *internal.SearchFacets // estimated from a sample of the documents
 	err      error
 }
 
 // EstimateResultsCount uses the hyperloglog algorithm to estimate the number
-// of results for the given search term.
-func (db *DB) estimateResultsCount(ctx context.Context, q string) estimateResponse {
-	row := db.db.QueryRow(ctx, hllQuery, q)
+// of results for the given search term and filters.
+func (db *DB) estimateResultsCount(ctx context.Context, q string, filters internal.SearchFilters) estimateResponse {
+	args := append([]interface{}{q}, filterArgs(filters)...)
+	row := db.db.QueryRow(ctx, hllQuery(db.searchSettings), args...)
 	var estimate sql.NullInt64
 	if err := row.Scan(&estimate); err != nil {
 		return estimateResponse{err: fmt.Errorf("row.Scan(): %v", err)}
@@ -355,9 +829,334 @@ func (db *DB) estimateResultsCount(ctx context.Context, q string) estimateRespon
 	return estimateResponse{estimate: uint64(estimate.Int64)}
 }
 
+const (
+	// maxFacetValues is the maximum number of values returned for each
+	// facet, other than GoMod.
+	maxFacetValues = 10
+
+	// facetSampleRegisters is the number of hll registers whose documents
+	// are counted when estimating facet counts. Documents are distributed
+	// evenly among the registers, so this counts about 1/8 of them.
+	facetSampleRegisters = hllRegisterCount / 8
+)
+
+// facetQuery returns the query that computes facet counts over the search
+// documents that match the query and filters, using the same criteria as
+// hllQuery. Only documents whose hll_register is less than the last parameter
+// are counted.
+func facetQuery(settings config.SearchSettings) string {
+	return fmt.Sprintf(`
+	WITH matched AS (
+		SELECT package_path, module_path, version, license_types, has_go_mod
+		FROM search_documents
+		WHERE tsv_search_tokens @@ websearch_to_tsquery($1)
+		AND (%[1]s) > 0.1
+		AND %[2]s
+		AND hll_register < $9
+	)
+	(
+		SELECT 'license', l, COUNT(DISTINCT package_path) AS n
+		FROM matched, unnest(license_types) l
+		WHERE l != ''
+		GROUP BY l
+		ORDER BY n DESC, l
+		LIMIT %[3]d
+	)
+	UNION ALL
+	(
+		SELECT 'host', split_part(module_path, '/', 1) AS h, COUNT(*) AS n
+		FROM matched
+		GROUP BY h
+		ORDER BY n DESC, h
+		LIMIT %[3]d
+	)
+	UNION ALL
+	(
+		SELECT 'module', module_path, COUNT(*) AS n
+		FROM matched
+		GROUP BY module_path
+		ORDER BY n DESC, module_path
+		LIMIT %[3]d
+	)
+	UNION ALL
+	(
+		SELECT 'major', split_part(version, '.', 1) AS v, COUNT(*) AS n
+		FROM matched
+		GROUP BY v
+		ORDER BY n DESC, v
+		LIMIT %[3]d
+	)
+	UNION ALL
+	(
+		SELECT 'gomod', has_go_mod::text AS g, COUNT(*) AS n
+		FROM matched
+		WHERE has_go_mod IS NOT NULL
+		GROUP BY g
+		ORDER BY n DESC, g
+	)`, scoreExpr(settings), filterExpr(2), maxFacetValues)
+}
+
+// searchFacets returns the facet counts for the documents matching q and
+// filters. It counts only the documents in the first numRegisters hll
+// registers. If that is fewer than all of them, the counts are scaled up
+// accordingly and marked approximate.
+func (db *DB) searchFacets(ctx context.Context, q string, filters internal.SearchFilters, numRegisters int) (_ *internal.SearchFacets, err error) {
+	defer derrors.Wrap(&err, "searchFacets(ctx, %q, %+v, %d)", q, filters, numRegisters)
+
+	facets := &internal.SearchFacets{Approximate: numRegisters < hllRegisterCount}
+	collect := func(rows *sql.Rows) error {
+		var (
+			facet string
+			v     internal.FacetValue
+		)
+		if err := rows.Scan(&facet, &v.Value, &v.Count); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		v.Count = v.Count * hllRegisterCount / uint64(numRegisters)
+		switch facet {
+		case "license":
+			facets.Licenses = append(facets.Licenses, &v)
+		case "host":
+			facets.Hosts = append(facets.Hosts, &v)
+		case "module":
+			facets.Modules = append(facets.Modules, &v)
+		case "major":
+			facets.Majors = append(facets.Majors, &v)
+		case "gomod":
+			facets.GoMod = append(facets.GoMod, &v)
+		}
+		return nil
+	}
+	args := append([]interface{}{q}, filterArgs(filters)...)
+	args = append(args, numRegisters)
+	if err := db.db.RunQuery(ctx, facetQuery(db.searchSettings), collect, args...); err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
+
 // deepSearch searches all packages for the query. It is slower, but results
-// are always valid.
-func (db *DB) deepSearch(ctx context.Context, q string, limit, offset int) searchResponse {
+// are always valid. Like popular_search, it returns only the best result of
+// each module series, and counts the series.
+func (db *DB) deepSearch(ctx context.Context, q string, filters internal.SearchFilters, limit, offset int, cursor *internal.SearchCursor) searchResponse {
+	// The series are counted before the results before the cursor are
+	// removed.
+	query := fmt.Sprintf(`
+		SELECT
+			package_path,
+			version,
+			module_path,
+			commit_time,
+			imported_by_count,
+			score,
+			total
+		FROM (
+			SELECT *, COUNT(*) OVER() AS total
+			FROM (
+				-- Keep only the best result of each series.
+				SELECT DISTINCT ON (series) *
+				FROM (
+					SELECT
+						package_path,
+						version,
+						module_path,
+						commit_time,
+						imported_by_count,
+						(%s) AS score,
+						COALESCE(v1_path, package_path) AS series
+						FROM
+							search_documents
+						WHERE tsv_search_tokens @@ websearch_to_tsquery($1)
+						AND %s
+				) d
+				WHERE d.score > 0.1
+				ORDER BY
+					series,
+					score DESC,
+					commit_time DESC,
+					package_path
+			) g
+		) r
+		WHERE %s
+		ORDER BY
+			score DESC,
+			commit_time DESC,
+			package_path
+		LIMIT $2
+		OFFSET $3`, scoreExpr(db.searchSettings), filterExpr(4), cursorExpr(11))
+	var results []*internal.SearchResult
+	collect := func(rows *sql.Rows) error {
+		var r internal.SearchResult
+		if err := rows.Scan(&r.PackagePath, &r.Version, &r.ModulePath, &r.CommitTime,
+			&r.NumImportedBy, &r.Score, &r.NumResults); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		results = append(results, &r)
+		return nil
+	}
+	args := append([]interface{}{q, limit, offset}, filterArgs(filters)...)
+	args = append(args, cursorArgs(cursor)...)
+	// Count the facets over all the matching documents while the results
+	// are being read.
+	var (
+		facets    *internal.SearchFacets
+		facetsErr error
+		wg        sync.WaitGroup
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		facets, facetsErr = db.searchFacets(ctx, q, filters, hllRegisterCount)
+	}()
+	err := db.db.RunQuery(ctx, query, collect, args...)
+	wg.Wait()
+	if err == nil {
+		err = facetsErr
+	}
+	if err != nil {
+		results = nil
+		facets = nil
+	}
+	return searchResponse{
+		source:  "deep",
+		results: results,
+		err:     err,
+		facets:  facets,
+	}
+}
+
+// groupSeries groups results that are in the same module series: different
+// major versions of the same package. Each group is represented by the
+// package in the newest major version, and the others are listed in its
+// OtherMajor field. The group takes the position of its first result in
+// results, and the score of the package that represents it, computed by
+// score for the query q.
+//
+// The other major versions are read from search_documents, so they need
+// not be in results. Only the packages that match filters and are not
+// excluded are members of a series, so a result none of whose other major
+// versions match is left as it is.
+func (db *DB) groupSeries(ctx context.Context, q, score string, filters internal.SearchFilters, results []*internal.SearchResult) (_ []*internal.SearchResult, err error) {
+	defer derrors.Wrap(&err, "DB.groupSeries(ctx, %q, score, %+v, results)", q, filters)
+	if len(results) == 0 {
+		return results, nil
+	}
+	var paths []string
+	for _, r := range results {
+		paths = append(paths, r.PackagePath)
+	}
+	query := fmt.Sprintf(`
+		SELECT
+			r.package_path,
+			sd.v1_path,
+			sd.package_path,
+			sd.module_path,
+			sd.version,
+			sd.commit_time,
+			sd.imported_by_count,
+			sd.is_module,
+			sd.score
+		FROM search_documents r
+		INNER JOIN (
+			SELECT *, (%s) AS score
+			FROM search_documents
+			WHERE %s
+		) sd
+		ON sd.v1_path = r.v1_path
+		WHERE r.package_path = ANY($2)`, score, filterExpr(3))
+	var (
+		series   = map[string]string{}                   // package path to v1 path
+		members  = map[string][]*internal.SearchResult{} // v1 path to members
+		isMember = map[string]bool{}                     // package path
+	)
+	collect := func(rows *sql.Rows) error {
+		var (
+			path, v1Path string
+			m            internal.SearchResult
+		)
+		if err := rows.Scan(&path, &v1Path, &m.PackagePath, &m.ModulePath, &m.Version,
+			&m.CommitTime, &m.NumImportedBy, &m.IsModule, &m.Score); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		series[path] = v1Path
+		// A member is returned once for each of the results in its series.
+		if !isMember[m.PackagePath] {
+			isMember[m.PackagePath] = true
+			members[v1Path] = append(members[v1Path], &m)
+		}
+		return nil
+	}
+	args := append([]interface{}{q, pq.Array(paths)}, filterArgs(filters)...)
+	if err := db.db.RunQuery(ctx, query, collect, args...); err != nil {
+		return nil, err
+	}
+
+	var grouped []*internal.SearchResult
+	seen := map[string]bool{}
+	for _, r := range results {
+		v1Path, ok := series[r.PackagePath]
+		if !ok {
+			// The package has no v1 path, so it is not grouped.
+			grouped = append(grouped, r)
+			continue
+		}
+		if seen[v1Path] {
+			continue
+		}
+		seen[v1Path] = true
+		ms := members[v1Path]
+		// Order the members by decreasing major version, preferring r among
+		// the members with the same major version.
+		sort.SliceStable(ms, func(i, j int) bool {
+			mi, mj := majorVersion(ms[i].ModulePath), majorVersion(ms[j].ModulePath)
+			if mi != mj {
+				return mi > mj
+			}
+			return ms[i].PackagePath == r.PackagePath && ms[j].PackagePath != r.PackagePath
+		})
+		if newest := ms[0]; newest.PackagePath != r.PackagePath {
+			r.PackagePath = newest.PackagePath
+			r.ModulePath = newest.ModulePath
+			r.Version = newest.Version
+			r.CommitTime = newest.CommitTime
+			r.NumImportedBy = newest.NumImportedBy
+			r.IsModule = newest.IsModule
+			r.Score = newest.Score
+		}
+		if len(ms) > 1 {
+			r.OtherMajor = ms[1:]
+		}
+		grouped = append(grouped, r)
+	}
+	return grouped, nil
+}
+
+// majorVersion returns the major version of the module with the given path,
+// which is 1 if the path has no major version suffix.
+func majorVersion(modulePath string) int {
+	_, pathMajor, ok := module.SplitPathVersion(modulePath)
+	if !ok || pathMajor == "" {
+		return 1
+	}
+	n, err := strconv.Atoi(strings.TrimLeft(pathMajor, "/.v"))
+	if err != nil {
+		return 1
+	}
+	return n
+}
+
+// trigramSearch searches for packages whose path or name is similar to the
+// query, using the pg_trgm extension. Unlike the other searchers, it finds
+// packages whose path or name is misspelled or only partially given in the
+// query. A package matches if the query is similar to a part of its path or
+// name, as determined by pg_trgm.word_similarity_threshold.
+//
+// It is a fallback searcher: its results are only added to a first page with
+// few results, so it does not search for later pages.
+func (db *DB) trigramSearch(ctx context.Context, q string, filters internal.SearchFilters, limit, offset int, cursor *internal.SearchCursor) searchResponse {
+	if offset > 0 || cursor != nil {
+		return searchResponse{source: "trigram", fallback: true}
+	}
 	query := fmt.Sprintf(`
 		SELECT *, COUNT(*) OVER() AS total
 		FROM (
@@ -367,18 +1166,17 @@ func (db *DB) deepSearch(ctx context.Context, q string, limit, offset int) searc
 				module_path,
 				commit_time,
 				imported_by_count,
-				(%s) AS score
+				%s AS score
 				FROM
 					search_documents
-				WHERE tsv_search_tokens @@ websearch_to_tsquery($1)
+				WHERE ($1 <%% package_path OR $1 <%% name)
+				AND %s
 				ORDER BY
 					score DESC,
 					commit_time DESC,
 					package_path
 		) r
-		WHERE r.score > 0.1
-		LIMIT $2
-		OFFSET $3`, scoreExpr)
+		LIMIT $2`, trigramScoreExpr(db.searchSettings), filterExpr(3))
 	var results []*internal.SearchResult
 	collect := func(rows *sql.Rows) error {
 		var r internal.SearchResult
@@ -389,18 +1187,91 @@ func (db *DB) deepSearch(ctx context.Context, q string, limit, offset int) searc
 		results = append(results, &r)
 		return nil
 	}
-	err := db.db.RunQuery(ctx, query, collect, q, limit, offset)
+	args := append([]interface{}{q, limit}, filterArgs(filters)...)
+	err := db.db.RunQuery(ctx, query, collect, args...)
 	if err != nil {
 		results = nil
 	}
 	return searchResponse{
-		source:  "deep",
-		results: results,
-		err:     err,
+		source:   "trigram",
+		results:  results,
+		err:      err,
+		fallback: true,
 	}
 }
 
-func (db *DB) popularSearch(ctx context.Context, searchQuery string, limit, offset int) searchResponse {
+// searchSuggestion returns a correction of the search query q, in which each
+// word is replaced by the most similar package name. It returns the empty
+// string if no word is replaced. Quoted phrases and the OR and "-" operators
+// of websearch_to_tsquery are left as they are. Names of excluded packages are
+// never suggested.
+func (db *DB) searchSuggestion(ctx context.Context, q string) (_ string, err error) {
+	defer derrors.Wrap(&err, "DB.searchSuggestion(ctx, %q)", q)
+
+	words := strings.Fields(q)
+	// indexes holds the index in words of each word to be corrected.
+	var (
+		indexes []int
+		terms   []string
+	)
+	for i, w := range words {
+		if w == "OR" || strings.HasPrefix(w, "-") || strings.Contains(w, `"`) {
+			continue
+		}
+		indexes = append(indexes, i)
+		terms = append(terms, w)
+	}
+	if len(terms) == 0 {
+		return "", nil
+	}
+	query := fmt.Sprintf(`
+		SELECT (
+			SELECT name
+			FROM search_documents
+			WHERE name %% t.term
+			AND %s
+			ORDER BY similarity(name, t.term) DESC, imported_by_count DESC, name
+			LIMIT 1
+		)
+		FROM unnest($1::text[]) WITH ORDINALITY AS t(term, n)
+		ORDER BY t.n`, notExcludedExpr("package_path"))
+	var names []sql.NullString
+	collect := func(rows *sql.Rows) error {
+		var name sql.NullString
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		names = append(names, name)
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, pq.Array(terms)); err != nil {
+		return "", err
+	}
+	changed := false
+	for j, name := range names {
+		i := indexes[j]
+		if name.Valid && !strings.EqualFold(name.String, words[i]) {
+			words[i] = name.String
+			changed = true
+		}
+	}
+	if !changed {
+		return "", nil
+	}
+	return strings.Join(words, " "), nil
+}
+
+// popularSearch scans packages in decreasing order of popularity, and exits
+// once the requested page is provably complete (see Search). If cursor is
+// non-nil, it calls the variant of popular_search that starts after the
+// cursor, which only needs to keep a page of results while scanning.
+func (db *DB) popularSearch(ctx context.Context, searchQuery string, filters internal.SearchFilters, limit, offset int, cursor *internal.SearchCursor) searchResponse {
+	call := `popular_search($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+	args := []interface{}{searchQuery, limit, offset}
+	if cursor != nil {
+		call = `popular_search($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`
+		args = append([]interface{}{searchQuery, limit}, cursorArgs(cursor)...)
+	}
 	query := `
 		SELECT
 			package_path,
@@ -409,7 +1280,7 @@ func (db *DB) popularSearch(ctx context.Context, searchQuery string, limit, offs
 			commit_time,
 			imported_by_count,
 			score
-		FROM popular_search($1, $2, $3, $4, $5)`
+		FROM ` + call
 	var results []*internal.SearchResult
 	collect := func(rows *sql.Rows) error {
 		var r internal.SearchResult
@@ -420,7 +1291,10 @@ func (db *DB) popularSearch(ctx context.Context, searchQuery string, limit, offs
 		results = append(results, &r)
 		return nil
 	}
-	err := db.db.RunQuery(ctx, query, collect, searchQuery, limit, offset, nonRedistributablePenalty, noGoModPenalty)
+	args = append(args, nonRedistributablePenalty, noGoModPenalty)
+	args = append(args, filterArgs(filters)[:numPopularFilterArgs]...)
+	args = append(args, recencyArgs(db.searchSettings)...)
+	err := db.db.RunQuery(ctx, query, collect, args...)
 	if err != nil {
 		results = nil
 	}
@@ -451,22 +1325,38 @@ func (db *DB) addPackageDataToSearchResults(ctx context.Context, results []*inte
 			pq.QuoteLiteral(r.Version), pq.QuoteLiteral(r.ModulePath))
 		keys = append(keys, key)
 	}
+	// Modules without a root package have no row in packages, so their data
+	// is read from search_documents.
 	query := fmt.Sprintf(`
 		SELECT
 			path,
 			name,
 			synopsis,
-			license_types
+			license_types,
+			FALSE
 		FROM
 			packages
 		WHERE
-			(path, version, module_path) IN (%s)`, strings.Join(keys, ","))
+			(path, version, module_path) IN (%[1]s)
+		UNION ALL
+		SELECT
+			package_path,
+			name,
+			synopsis,
+			license_types,
+			TRUE
+		FROM
+			search_documents
+		WHERE
+			is_module
+			AND (package_path, version, module_path) IN (%[1]s)`, strings.Join(keys, ","))
 	collect := func(rows *sql.Rows) error {
 		var (
 			path, name, synopsis string
 			licenseTypes         []string
+			isModule             bool
 		)
-		if err := rows.Scan(&path, &name, &synopsis, pq.Array(&licenseTypes)); err != nil {
+		if err := rows.Scan(&path, &name, &synopsis, pq.Array(&licenseTypes), &isModule); err != nil {
 			return fmt.Errorf("rows.Scan(): %v", err)
 		}
 		r, ok := resultMap[path]
@@ -475,6 +1365,7 @@ func (db *DB) addPackageDataToSearchResults(ctx context.Context, results []*inte
 		}
 		r.Name = name
 		r.Synopsis = synopsis
+		r.IsModule = isModule
 		for _, l := range licenseTypes {
 			if l != "" {
 				r.Licenses = append(r.Licenses, l)
@@ -485,6 +1376,49 @@ func (db *DB) addPackageDataToSearchResults(ctx context.Context, results []*inte
 	return db.db.RunQuery(ctx, query, collect)
 }
 
+// addReadmeSectionsToSearchResults sets the README section of each result
+// to the section of its README that best matches q, if any.
+func (db *DB) addReadmeSectionsToSearchResults(ctx context.Context, q string, results []*internal.SearchResult) (err error) {
+	defer derrors.Wrap(&err, "DB.addReadmeSectionsToSearchResults(ctx, %q, results)", q)
+	if len(results) == 0 {
+		return nil
+	}
+	var paths []string
+	resultMap := make(map[string]*internal.SearchResult)
+	for _, r := range results {
+		paths = append(paths, r.PackagePath)
+		resultMap[r.PackagePath] = r
+	}
+	query := `
+		SELECT DISTINCT ON (package_path)
+			package_path,
+			title,
+			anchor
+		FROM
+			search_readme_sections
+		WHERE
+			package_path = ANY($2)
+			AND tsv_search_tokens @@ websearch_to_tsquery($1)
+		ORDER BY
+			package_path,
+			ts_rank(tsv_search_tokens, websearch_to_tsquery($1)) DESC,
+			section_number`
+	collect := func(rows *sql.Rows) error {
+		var path, title, anchor string
+		if err := rows.Scan(&path, &title, &anchor); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		r, ok := resultMap[path]
+		if !ok {
+			return fmt.Errorf("BUG: unexpected package path: %q", path)
+		}
+		r.ReadmeSection = title
+		r.ReadmeAnchor = anchor
+		return nil
+	}
+	return db.db.RunQuery(ctx, query, collect, q, pq.Array(paths))
+}
+
 var upsertSearchStatement = fmt.Sprintf(`
 	INSERT INTO search_documents (
 		package_path,
@@ -497,6 +1431,9 @@ var upsertSearchStatement = fmt.Sprintf(`
 		version_updated_at,
 		commit_time,
 		has_go_mod,
+		num_recent_versions,
+		v1_path,
+		is_module,
 		tsv_search_tokens,
 		hll_register,
 		hll_leading_zeros
@@ -512,14 +1449,19 @@ var upsertSearchStatement = fmt.Sprintf(`
 		CURRENT_TIMESTAMP,
 		m.commit_time,
 		m.has_go_mod,
+		%[2]s,
+		p.v1_path,
+		FALSE,
 		(
 			SETWEIGHT(TO_TSVECTOR('path_tokens', $2), 'A') ||
 			SETWEIGHT(TO_TSVECTOR($3), 'B') ||
 			SETWEIGHT(TO_TSVECTOR($4), 'C') ||
 			SETWEIGHT(TO_TSVECTOR($5), 'D')
 		),
-		hll_hash(p.path) & (%[1]d - 1),
-		hll_zeros(hll_hash(p.path))
+		-- Packages in the same series share hll fields, so that the search
+		-- result estimate counts series.
+		hll_hash(p.v1_path) & (%[1]d - 1),
+		hll_zeros(hll_hash(p.v1_path))
 	FROM
 		packages p
 	INNER JOIN
@@ -549,21 +1491,124 @@ var upsertSearchStatement = fmt.Sprintf(`
 		redistributable=excluded.redistributable,
 		commit_time=excluded.commit_time,
 		has_go_mod=excluded.has_go_mod,
+		num_recent_versions=excluded.num_recent_versions,
+		v1_path=excluded.v1_path,
+		is_module=excluded.is_module,
+		tsv_search_tokens=excluded.tsv_search_tokens,
+		-- the hll fields are functions of v1_path, so they don't change
+		version_updated_at=(
+			CASE WHEN excluded.version = search_documents.version
+			THEN search_documents.version_updated_at
+			ELSE CURRENT_TIMESTAMP
+			END)
+	;`, hllRegisterCount, numRecentVersionsExpr("p.module_path"))
+
+// upsertModuleSearchStatement inserts a search document for the latest
+// version of a module that has no package at its root. The document is
+// linked to the module, not a package, and its license types are those of
+// the licenses at the module root. A package document with the same path
+// takes precedence: it replaces a module document, and is not replaced by
+// one.
+var upsertModuleSearchStatement = fmt.Sprintf(`
+	INSERT INTO search_documents (
+		package_path,
+		version,
+		module_path,
+		name,
+		synopsis,
+		license_types,
+		redistributable,
+		version_updated_at,
+		commit_time,
+		has_go_mod,
+		num_recent_versions,
+		v1_path,
+		is_module,
+		tsv_search_tokens,
+		hll_register,
+		hll_leading_zeros
+	)
+	SELECT
+		m.module_path,
+		m.version,
+		m.module_path,
+		$7,
+		$6,
+		ARRAY(
+			SELECT DISTINCT t
+			FROM licenses l, unnest(l.types) t
+			WHERE l.module_path = m.module_path
+			AND l.version = m.version
+			AND position('/' IN l.file_path) = 0
+			ORDER BY t
+		),
+		m.redistributable,
+		CURRENT_TIMESTAMP,
+		m.commit_time,
+		m.has_go_mod,
+		%[2]s,
+		m.series_path,
+		TRUE,
+		(
+			SETWEIGHT(TO_TSVECTOR('path_tokens', $2), 'A') ||
+			SETWEIGHT(TO_TSVECTOR($3), 'B') ||
+			SETWEIGHT(TO_TSVECTOR($4), 'C') ||
+			SETWEIGHT(TO_TSVECTOR($5), 'D')
+		),
+		-- The series path of a module is the v1 path of its root package, so
+		-- the module is in the same series as the root packages of its other
+		-- major versions.
+		hll_hash(m.series_path) & (%[1]d - 1),
+		hll_zeros(hll_hash(m.series_path))
+	FROM
+		modules m
+	WHERE
+		m.module_path = $1
+	ORDER BY
+		m.version_type = 'release' DESC,
+		m.sort_version DESC
+	LIMIT 1
+	ON CONFLICT (package_path)
+	DO UPDATE SET
+		version=excluded.version,
+		name=excluded.name,
+		synopsis=excluded.synopsis,
+		license_types=excluded.license_types,
+		redistributable=excluded.redistributable,
+		commit_time=excluded.commit_time,
+		has_go_mod=excluded.has_go_mod,
+		num_recent_versions=excluded.num_recent_versions,
 		tsv_search_tokens=excluded.tsv_search_tokens,
-		-- the hll fields are functions of path, so they don't change
 		version_updated_at=(
 			CASE WHEN excluded.version = search_documents.version
 			THEN search_documents.version_updated_at
 			ELSE CURRENT_TIMESTAMP
 			END)
-	;`, hllRegisterCount)
+	WHERE
+		search_documents.is_module
+	;`, hllRegisterCount, numRecentVersionsExpr("m.module_path"))
+
+// numRecentVersionsExpr returns an SQL expression that counts the release
+// versions of the module whose path is given by the SQL expression modulePath
+// that were committed in the past year.
+func numRecentVersionsExpr(modulePath string) string {
+	return fmt.Sprintf(`(
+		SELECT COUNT(*) FROM modules rv
+		WHERE rv.module_path = %s
+		AND rv.version_type = 'release'
+		AND rv.commit_time > CURRENT_TIMESTAMP - INTERVAL '1 year')`, modulePath)
+}
 
 // UpsertSearchDocuments adds search information for mod ot the search_documents table.
 func UpsertSearchDocuments(ctx context.Context, db *database.DB, mod *internal.Module) (err error) {
 	defer derrors.Wrap(&err, "UpsertSearchDocuments(ctx, %q)", mod.ModulePath)
 	ctx, span := trace.StartSpan(ctx, "UpsertSearchDocuments")
 	defer span.End()
+	hasRootPackage := false
 	for _, pkg := range mod.LegacyPackages {
+		if pkg.Path == mod.ModulePath {
+			hasRootPackage = true
+		}
 		if isInternalPackage(pkg.Path) {
 			continue
 		}
@@ -578,7 +1623,18 @@ func UpsertSearchDocuments(ctx context.Context, db *database.DB, mod *internal.M
 			return err
 		}
 	}
-	return nil
+	if hasRootPackage {
+		return nil
+	}
+	// Without a root package, nothing in the module would be found by its
+	// path or README, so add a document for the module itself.
+	return UpsertSearchDocument(ctx, db, upsertSearchDocumentArgs{
+		PackagePath:    mod.ModulePath,
+		ModulePath:     mod.ModulePath,
+		ReadmeFilePath: mod.LegacyReadmeFilePath,
+		ReadmeContents: mod.LegacyReadmeContents,
+		IsModule:       true,
+	})
 }
 
 type upsertSearchDocumentArgs struct {
@@ -587,10 +1643,15 @@ type upsertSearchDocumentArgs struct {
 	Synopsis       string
 	ReadmeFilePath string
 	ReadmeContents string
+	// IsModule reports whether the document is for a module that has no
+	// root package. Synopsis is ignored for such documents.
+	IsModule bool
 }
 
 // UpsertSearchDocument inserts a row for each package in the module, if that
-// package is the latest version and is not internal.
+// package is the latest version and is not internal. If args.IsModule is
+// true, it inserts a row for the module instead, unless there is already a
+// row for a package with the module path.
 //
 // The given module should have already been validated via a call to
 // validateModule.
@@ -603,8 +1664,67 @@ func UpsertSearchDocument(ctx context.Context, db *database.DB, args upsertSearc
 		args.ReadmeContents = ""
 	}
 	pathTokens := strings.Join(GeneratePathTokens(args.PackagePath), " ")
-	sectionB, sectionC, sectionD := SearchDocumentSections(args.Synopsis, args.ReadmeFilePath, args.ReadmeContents)
-	_, err = db.Exec(ctx, upsertSearchStatement, args.PackagePath, pathTokens, sectionB, sectionC, sectionD)
+	if args.IsModule {
+		// The first sentence of the README is both the synopsis and, as for
+		// packages without a synopsis, the B section.
+		sectionB, sectionC, sectionD := SearchDocumentSections("", args.ReadmeFilePath, args.ReadmeContents)
+		synopsis := readmeSynopsis(args.ReadmeFilePath, args.ReadmeContents)
+		name := path.Base(internal.SeriesPathForModule(args.ModulePath))
+		_, err = db.Exec(ctx, upsertModuleSearchStatement, args.ModulePath, pathTokens, sectionB, sectionC, sectionD, synopsis, name)
+	} else {
+		sectionB, sectionC, sectionD := SearchDocumentSections(args.Synopsis, args.ReadmeFilePath, args.ReadmeContents)
+		_, err = db.Exec(ctx, upsertSearchStatement, args.PackagePath, pathTokens, sectionB, sectionC, sectionD)
+	}
+	if err != nil {
+		return err
+	}
+	if args.PackagePath != args.ModulePath {
+		return nil
+	}
+	return upsertReadmeSections(ctx, db, args.PackagePath, args.ReadmeFilePath, args.ReadmeContents)
+}
+
+// upsertReadmeSections replaces the README sections of the search document
+// for pkgPath with the sections of the given README. It does not insert
+// sections if there is no such search document.
+func upsertReadmeSections(ctx context.Context, db *database.DB, pkgPath, readmeFilePath, readmeContents string) (err error) {
+	defer derrors.Wrap(&err, "upsertReadmeSections(ctx, db, %q)", pkgPath)
+
+	if _, err := db.Exec(ctx, `DELETE FROM search_readme_sections WHERE package_path = $1`, pkgPath); err != nil {
+		return err
+	}
+	sections := readmeSections(readmeFilePath, readmeContents)
+	if len(sections) == 0 {
+		return nil
+	}
+	var titles, titleWords, anchors, words []string
+	for _, s := range sections {
+		titles = append(titles, s.title)
+		titleWords = append(titleWords, strings.Join(processWords(s.title), " "))
+		anchors = append(anchors, s.anchor)
+		words = append(words, s.words)
+	}
+	// Weight the words of the heading more, so that a section whose heading
+	// matches is preferred to one that only mentions the query.
+	_, err = db.Exec(ctx, `
+		INSERT INTO search_readme_sections (
+			package_path,
+			section_number,
+			title,
+			anchor,
+			tsv_search_tokens
+		)
+		SELECT
+			$1,
+			s.n,
+			s.title,
+			s.anchor,
+			SETWEIGHT(TO_TSVECTOR(s.title_words), 'A') || SETWEIGHT(TO_TSVECTOR(s.words), 'D')
+		FROM
+			unnest($2::text[], $3::text[], $4::text[], $5::text[])
+			WITH ORDINALITY AS s(title, title_words, anchor, words, n)
+		WHERE EXISTS (SELECT 1 FROM search_documents WHERE package_path = $1)`,
+		pkgPath, pq.Array(titles), pq.Array(titleWords), pq.Array(anchors), pq.Array(words))
 	return err
 }
 
@@ -614,7 +1734,7 @@ func (db *DB) GetPackagesForSearchDocumentUpsert(ctx context.Context, before tim
 	defer derrors.Wrap(&err, "GetPackagesForSearchDocumentUpsert(ctx, %s, %d)", before, limit)
 
 	query := `
-		SELECT sd.package_path, sd.module_path, sd.synopsis, m.readme_file_path, m.readme_contents
+		SELECT sd.package_path, sd.module_path, sd.synopsis, m.readme_file_path, m.readme_contents, sd.is_module
 		FROM search_documents sd
 		INNER JOIN modules m
 		USING (module_path, version)
@@ -623,7 +1743,7 @@ func (db *DB) GetPackagesForSearchDocumentUpsert(ctx context.Context, before tim
 
 	collect := func(rows *sql.Rows) error {
 		var a upsertSearchDocumentArgs
-		if err := rows.Scan(&a.PackagePath, &a.ModulePath, &a.Synopsis, &a.ReadmeFilePath, &a.ReadmeContents); err != nil {
+		if err := rows.Scan(&a.PackagePath, &a.ModulePath, &a.Synopsis, &a.ReadmeFilePath, &a.ReadmeContents, &a.IsModule); err != nil {
 			return err
 		}
 		argsList = append(argsList, a)
@@ -666,6 +1786,30 @@ func (db *DB) UpdateSearchDocumentsImportedByCount(ctx context.Context) (nUpdate
 	return nUpdated, err
 }
 
+// UpdateSearchDocumentsRecentVersionCounts recomputes num_recent_versions for
+// all search documents whose count has changed. Counts change without the
+// module being reprocessed as its versions age, so this should be run
+// periodically.
+//
+// UpdateSearchDocumentsRecentVersionCounts returns the number of rows updated.
+func (db *DB) UpdateSearchDocumentsRecentVersionCounts(ctx context.Context) (nUpdated int64, err error) {
+	defer derrors.Wrap(&err, "UpdateSearchDocumentsRecentVersionCounts(ctx)")
+
+	res, err := db.db.Exec(ctx, fmt.Sprintf(`
+		UPDATE search_documents sd
+		SET num_recent_versions = c.n
+		FROM (
+			SELECT package_path, %s AS n
+			FROM search_documents
+		) c
+		WHERE sd.package_path = c.package_path
+		AND sd.num_recent_versions != c.n`, numRecentVersionsExpr("search_documents.module_path")))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 // getSearchPackages returns the set of package paths that are in the search_documents table.
 func (db *DB) getSearchPackages(ctx context.Context) (set map[string]bool, err error) {
 	defer derrors.Wrap(&err, "DB.getSearchPackages(ctx)")
@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"path"
 	"sort"
 	"strings"
 	"testing"
@@ -22,8 +23,11 @@ import (
 	"github.com/lib/pq"
 	"go.opencensus.io/stats/view"
 	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/config"
 	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/licenses"
 	"golang.org/x/pkgsite/internal/testing/sample"
+	"golang.org/x/pkgsite/internal/version"
 )
 
 func TestPathTokens(t *testing.T) {
@@ -151,8 +155,10 @@ func TestPathTokens(t *testing.T) {
 
 // importGraph constructs a simple import graph where all importers import
 // one popular package.  For performance purposes, all importers are added to
-// a single importing module.
-func importGraph(popularPath, importerModule string, importerCount int) []*internal.Module {
+// a single importing module: the importers are importerPrefix/importerN, and
+// their module is the host of importerPrefix, so that the search document of
+// the module does not match the path of importerPrefix.
+func importGraph(popularPath, importerPrefix string, importerCount int) []*internal.Module {
 	m := sample.Module(popularPath, "v1.2.3", "")
 	m.LegacyPackages[0].Imports = nil
 	// Try to improve the ts_rank of the 'foo' search term.
@@ -160,9 +166,13 @@ func importGraph(popularPath, importerModule string, importerCount int) []*inter
 	m.LegacyReadmeContents = "foo"
 	mods := []*internal.Module{m}
 	if importerCount > 0 {
-		m := sample.Module(importerModule, "v1.2.3")
+		modulePath, dir := importerPrefix, ""
+		if i := strings.Index(importerPrefix, "/"); i >= 0 {
+			modulePath, dir = importerPrefix[:i], importerPrefix[i+1:]
+		}
+		m := sample.Module(modulePath, "v1.2.3")
 		for i := 0; i < importerCount; i++ {
-			p := sample.LegacyPackage(importerModule, fmt.Sprintf("importer%d", i))
+			p := sample.LegacyPackage(modulePath, path.Join(dir, fmt.Sprintf("importer%d", i)))
 			p.Imports = []string{popularPath}
 			sample.AddPackage(m, p)
 		}
@@ -188,6 +198,11 @@ func resultGuard(resultOrder []string) func(string) func() {
 		}
 	}
 	guardTestResult := func(source string) func() {
+		// Sources that are not in resultOrder, like the fallback searcher,
+		// are not ordered.
+		if _, ok := done[source]; !ok {
+			return func() {}
+		}
 		// This test is inherently racy as 'estimate' results are are on a
 		// separate channel, and therefore even after guarding still race to
 		// the select statement.
@@ -208,6 +223,68 @@ func resultGuard(resultOrder []string) func(string) func() {
 	return guardTestResult
 }
 
+// primarySearchers returns the searchers other than the fallback searcher,
+// which all return the same results for the same query.
+func primarySearchers() map[string]searcher {
+	ss := make(map[string]searcher)
+	for name, s := range searchers {
+		if name != "trigram" {
+			ss[name] = s
+		}
+	}
+	return ss
+}
+
+// insertModules inserts the modules into testDB.
+func insertModules(ctx context.Context, t *testing.T, modules ...*internal.Module) {
+	t.Helper()
+	for _, m := range modules {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// searcherTest is a query to run with every primary searcher.
+type searcherTest struct {
+	name    string
+	q       string
+	filters internal.SearchFilters
+	limit   int      // 10 if zero
+	want    []string // package paths of the results, in order
+}
+
+// runSearcherTests runs each test with every primary searcher against
+// testDB, and compares the package paths of the results.
+func runSearcherTests(ctx context.Context, t *testing.T, tests []searcherTest) {
+	t.Helper()
+	for _, test := range tests {
+		limit := test.limit
+		if limit == 0 {
+			limit = 10
+		}
+		for method, searcher := range primarySearchers() {
+			name := method
+			if test.name != "" {
+				name = test.name + ":" + method
+			}
+			t.Run(name, func(t *testing.T) {
+				res := searcher(testDB, ctx, test.q, test.filters, limit, 0, nil)
+				if res.err != nil {
+					t.Fatal(res.err)
+				}
+				var got []string
+				for _, r := range res.results {
+					got = append(got, r.PackagePath)
+				}
+				if diff := cmp.Diff(test.want, got); diff != "" {
+					t.Errorf("%q: mismatch (-want +got):\n%s", test.q, diff)
+				}
+			})
+		}
+	}
+}
+
 func TestSearch(t *testing.T) {
 	tests := []struct {
 		label       string
@@ -299,7 +376,7 @@ func TestSearch(t *testing.T) {
 				t.Fatal(err)
 			}
 			guardTestResult := resultGuard(test.resultOrder)
-			resp, err := testDB.hedgedSearch(ctx, "foo", 2, 0, searchers, guardTestResult)
+			resp, err := testDB.hedgedSearch(ctx, "foo", internal.SearchFilters{}, 2, 0, nil, searchers, guardTestResult)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -334,7 +411,7 @@ func TestSearchErrors(t *testing.T) {
 		for name, search := range searchers {
 			if name == searcherName {
 				name := name
-				newSearchers[name] = func(*DB, context.Context, string, int, int) searchResponse {
+				newSearchers[name] = func(*DB, context.Context, string, internal.SearchFilters, int, int, *internal.SearchCursor) searchResponse {
 					return searchResponse{
 						source: name,
 						err:    errors.New("bad"),
@@ -394,7 +471,7 @@ func TestSearchErrors(t *testing.T) {
 				t.Fatal(err)
 			}
 			guardTestResult := resultGuard(test.resultOrder)
-			resp, err := testDB.hedgedSearch(ctx, "foo", 2, 0, test.searchers, guardTestResult)
+			resp, err := testDB.hedgedSearch(ctx, "foo", internal.SearchFilters{}, 2, 0, nil, test.searchers, guardTestResult)
 			if (err != nil) != test.wantErr {
 				t.Fatalf("hedgedSearch(): got error %v, want error: %t", err, test.wantErr)
 			}
@@ -528,7 +605,7 @@ func TestInsertSearchDocumentAndSearch(t *testing.T) {
 			},
 		},
 	} {
-		for method, searcher := range searchers {
+		for method, searcher := range primarySearchers() {
 			t.Run(tc.name+":"+method, func(t *testing.T) {
 				defer ResetTestDB(testDB, t)
 
@@ -548,7 +625,7 @@ func TestInsertSearchDocumentAndSearch(t *testing.T) {
 					tc.limit = 10
 				}
 
-				got := searcher(testDB, ctx, tc.searchQuery, tc.limit, tc.offset)
+				got := searcher(testDB, ctx, tc.searchQuery, internal.SearchFilters{}, tc.limit, tc.offset, nil)
 				if got.err != nil {
 					t.Fatal(got.err)
 				}
@@ -592,7 +669,7 @@ func TestSearchPenalties(t *testing.T) {
 	}
 
 	for path, m := range modules {
-		v := sample.Module(path, sample.VersionString, "p")
+		v := sample.Module(path, sample.VersionString, "")
 		v.LegacyPackages[0].IsRedistributable = m.redist
 		v.IsRedistributable = m.redist
 		v.HasGoMod = m.hasGoMod
@@ -601,24 +678,580 @@ func TestSearchPenalties(t *testing.T) {
 		}
 	}
 
-	for method, searcher := range searchers {
+	// scores maps each searcher to the scores of its results. The searchers
+	// must compute exactly the same scores, so that a cursor from one of them
+	// is at the same position in the results of the others.
+	scores := map[string]map[string]float64{}
+	for method, searcher := range primarySearchers() {
 		t.Run(method, func(t *testing.T) {
-			res := searcher(testDB, ctx, "foo", 10, 0)
+			res := searcher(testDB, ctx, "foo", internal.SearchFilters{}, 10, 0, nil)
 			if res.err != nil {
 				t.Fatal(res.err)
 			}
 			if got, want := len(res.results), len(modules); got != want {
 				t.Fatalf("got %d search results, want %d", got, want)
 			}
+			scores[method] = map[string]float64{}
 			for _, r := range res.results {
 				got := r.Score
 				want := res.results[0].Score * modules[r.ModulePath].multiplier
 				if math.Abs(got-want) > 1e6 {
 					t.Errorf("%s: got %f, want %f", r.ModulePath, got, want)
 				}
+				scores[method][r.ModulePath] = r.Score
+			}
+		})
+	}
+	if diff := cmp.Diff(scores["popular"], scores["deep"]); diff != "" {
+		t.Errorf("scores mismatch (-popular +deep):\n%s", diff)
+	}
+}
+
+func TestSearchRecency(t *testing.T) {
+	// Verify that the recency factor is applied the same way by every searcher.
+	defer ResetTestDB(testDB, t)
+	defer testDB.SetSearchSettings(config.SearchSettings{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	testDB.SetSearchSettings(config.SearchSettings{
+		RecencyWeight:       0.5,
+		RecencyHalfLifeDays: 365,
+		ActiveVersions:      1,
+	})
+	// Both modules have the same text ranking for the search term "foo".
+	// fresh.com has a recent release, so it gets the full score. stale.com
+	// was last committed four half-lives ago, so its score is reduced by
+	// 0.5 * (1 - 0.5^4).
+	fresh := sample.Module("fresh.com/foo", sample.VersionString, "")
+	stale := sample.Module("stale.com/foo", sample.VersionString, "")
+	stale.CommitTime = sample.CommitTime.Add(-4 * 365 * 24 * time.Hour)
+	insertModules(ctx, t, fresh, stale)
+	const wantRatio = 1 - 0.5*(1-0.0625)
+
+	for method, searcher := range primarySearchers() {
+		t.Run(method, func(t *testing.T) {
+			res := searcher(testDB, ctx, "foo", internal.SearchFilters{}, 10, 0, nil)
+			if res.err != nil {
+				t.Fatal(res.err)
+			}
+			if got, want := len(res.results), 2; got != want {
+				t.Fatalf("got %d search results, want %d", got, want)
+			}
+			if got, want := res.results[0].ModulePath, fresh.ModulePath; got != want {
+				t.Fatalf("got first result %q, want %q", got, want)
+			}
+			if got := res.results[1].Score / res.results[0].Score; math.Abs(got-wantRatio) > 1e-3 {
+				t.Errorf("got score ratio %f, want %f", got, wantRatio)
+			}
+		})
+	}
+}
+
+func TestSearchTrigramFallback(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	insertModules(ctx, t,
+		sample.Module("k8s.io/client", sample.VersionString, "kubernetes"),
+		sample.Module("other.com/yaml", sample.VersionString, "parser"),
+		// The name of an excluded package is closer to the misspelling
+		// below, but is not suggested.
+		sample.Module("excluded.com/k8s", sample.VersionString, "kubrnetez"))
+	if err := testDB.InsertExcludedPrefix(ctx, "excluded.com", "no user", "no reason"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		q              string
+		wantPaths      []string
+		wantSuggestion string
+	}{
+		// A misspelling of the package name.
+		{"kubrnetes", []string{"k8s.io/client/kubernetes"}, "kubernetes"},
+		// An exact match does not fall back.
+		{"kubernetes", []string{"k8s.io/client/kubernetes"}, ""},
+		{"nothing", nil, ""},
+	} {
+		t.Run(test.q, func(t *testing.T) {
+			results, _, err := testDB.Search(ctx, test.q, internal.SearchFilters{}, 10, 0, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotPaths []string
+			for _, r := range results {
+				gotPaths = append(gotPaths, r.PackagePath)
+				if r.Suggestion != test.wantSuggestion {
+					t.Errorf("%s: got suggestion %q, want %q", r.PackagePath, r.Suggestion, test.wantSuggestion)
+				}
+				if r.NumResults != uint64(len(test.wantPaths)) {
+					t.Errorf("%s: got NumResults = %d, want %d", r.PackagePath, r.NumResults, len(test.wantPaths))
+				}
+			}
+			if diff := cmp.Diff(test.wantPaths, gotPaths); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSearchGroupsSeries(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	insertModules(ctx, t,
+		sample.Module("gopkg.in/yaml.v2", "v2.3.0", ""),
+		sample.Module("gopkg.in/yaml.v3", "v3.0.0", ""),
+		sample.Module("github.com/foo/bar", "v1.0.0", "baz"),
+		sample.Module("github.com/foo/bar/v4", "v4.1.0", "baz"))
+	// Make the older major version more popular, so that it is the best
+	// result of its series.
+	if _, err := testDB.db.Exec(ctx, `UPDATE search_documents SET imported_by_count = 10 WHERE package_path = 'github.com/foo/bar/baz'`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each searcher returns the best result of the series.
+	runSearcherTests(ctx, t, []searcherTest{{q: "baz", want: []string{"github.com/foo/bar/baz"}}})
+
+	// Search shows the newest major version, and lists the others.
+	for _, test := range []struct {
+		q             string
+		wantPath      string
+		wantOtherPath string
+	}{
+		{"yaml", "gopkg.in/yaml.v3", "gopkg.in/yaml.v2"},
+		{"baz", "github.com/foo/bar/v4/baz", "github.com/foo/bar/baz"},
+	} {
+		t.Run(test.q, func(t *testing.T) {
+			results, _, err := testDB.Search(ctx, test.q, internal.SearchFilters{}, 10, 0, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("got %d results, want 1", len(results))
+			}
+			r := results[0]
+			if r.PackagePath != test.wantPath || r.NumResults != 1 {
+				t.Errorf("got %s with NumResults = %d, want %s with NumResults = 1", r.PackagePath, r.NumResults, test.wantPath)
+			}
+			if len(r.OtherMajor) != 1 || r.OtherMajor[0].PackagePath != test.wantOtherPath {
+				t.Errorf("got OtherMajor = %v, want [%s]", r.OtherMajor, test.wantOtherPath)
+			}
+		})
+	}
+
+	// Major versions that don't match the filters are not shown.
+	if _, err := testDB.db.Exec(ctx, `UPDATE search_documents SET license_types = '{"Apache-2.0"}' WHERE package_path = 'github.com/foo/bar/v4/baz'`); err != nil {
+		t.Fatal(err)
+	}
+	unfiltered, _, err := testDB.Search(ctx, "baz", internal.SearchFilters{}, 10, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, _, err := testDB.Search(ctx, "baz", internal.SearchFilters{Licenses: []string{"MIT"}}, 10, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("filtered: got %d results, want 1", len(results))
+	}
+	if r := results[0]; r.PackagePath != "github.com/foo/bar/baz" || len(r.OtherMajor) != 0 {
+		t.Errorf("filtered: got %s with OtherMajor = %v, want github.com/foo/bar/baz with none", r.PackagePath, r.OtherMajor)
+	}
+	// The score of a group is that of the package that represents it.
+	// popular_search computes scores with less precision.
+	if got, want := results[0].Score, unfiltered[0].OtherMajor[0].Score; math.Abs(got-want) > 1e-6*want {
+		t.Errorf("filtered: got score %f, want %f", got, want)
+	}
+}
+
+func TestSearchCursor(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	insertModules(ctx, t,
+		sample.Module("page.com/a", "v1.0.0", "foo"),
+		sample.Module("page.com/b", "v1.0.0", "foo"),
+		sample.Module("page.com/c", "v1.0.0", "foo"),
+		sample.Module("page.com/c/v2", "v2.0.0", "foo"),
+		sample.Module("page.com/d", "v1.0.0", "foo"),
+		sample.Module("page.com/e", "v1.0.0", "foo"))
+	// Give the packages different scores. The two major versions of
+	// page.com/c/foo have scores on different pages, so the series must not be
+	// returned again on the later page.
+	for path, count := range map[string]int{
+		"page.com/a/foo":    50,
+		"page.com/b/foo":    40,
+		"page.com/c/foo":    30,
+		"page.com/d/foo":    20,
+		"page.com/c/v2/foo": 10,
+	} {
+		if _, err := testDB.db.Exec(ctx, `UPDATE search_documents SET imported_by_count = $1 WHERE package_path = $2`, count, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"page.com/a/foo", "page.com/b/foo", "page.com/c/foo", "page.com/d/foo", "page.com/e/foo"}
+
+	// Each searcher returns the same pages after a cursor as at an offset.
+	for method, searcher := range primarySearchers() {
+		t.Run(method, func(t *testing.T) {
+			var (
+				got    []string
+				cursor *internal.SearchCursor
+			)
+			for offset := 0; offset < len(want); offset += 2 {
+				byCursor := searcher(testDB, ctx, "foo", internal.SearchFilters{}, 2, 0, cursor)
+				if byCursor.err != nil {
+					t.Fatal(byCursor.err)
+				}
+				byOffset := searcher(testDB, ctx, "foo", internal.SearchFilters{}, 2, offset, nil)
+				if byOffset.err != nil {
+					t.Fatal(byOffset.err)
+				}
+				var cursorPaths, offsetPaths []string
+				for _, r := range byCursor.results {
+					cursorPaths = append(cursorPaths, r.PackagePath)
+					cursor = &internal.SearchCursor{Score: r.Score, CommitTime: r.CommitTime, PackagePath: r.PackagePath}
+				}
+				for _, r := range byOffset.results {
+					offsetPaths = append(offsetPaths, r.PackagePath)
+				}
+				if diff := cmp.Diff(offsetPaths, cursorPaths); diff != "" {
+					t.Errorf("offset %d: page after cursor mismatch (-offset +cursor):\n%s", offset, diff)
+				}
+				got = append(got, cursorPaths...)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
 			}
 		})
 	}
+
+	// Search sets the cursor of each result, and ignores the offset if
+	// given a cursor.
+	results, _, err := testDB.Search(ctx, "foo", internal.SearchFilters{}, 2, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := results[len(results)-1]
+	if last.Cursor == nil || last.Cursor.PackagePath != "page.com/b/foo" {
+		t.Fatalf("got cursor %+v, want cursor at page.com/b/foo", last.Cursor)
+	}
+	results, _, err = testDB.Search(ctx, "foo", internal.SearchFilters{}, 2, 10, last.Cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range results {
+		got = append(got, r.PackagePath)
+	}
+	// page.com/c/foo is shown as its newest major version.
+	if diff := cmp.Diff([]string{"page.com/c/v2/foo", "page.com/d/foo"}, got); diff != "" {
+		t.Errorf("Search after cursor mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSearchWithExplanation(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	m := sample.Module("explain.com/foo", sample.VersionString, "")
+	m.HasGoMod = false
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	results, _, exp, err := testDB.SearchWithExplanation(ctx, "foo", internal.SearchFilters{}, 10, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || len(exp.Results) != 1 {
+		t.Fatalf("got %d results and %d explanations, want 1 of each", len(results), len(exp.Results))
+	}
+	if len(exp.Searchers) == 0 {
+		t.Error("got no searchers, want at least one")
+	}
+	got := exp.Results[0]
+	if got.PackagePath != results[0].PackagePath {
+		t.Errorf("got explanation for %q, want %q", got.PackagePath, results[0].PackagePath)
+	}
+	if got.Source != "popular" && got.Source != "deep" {
+		t.Errorf("got source %q, want popular or deep", got.Source)
+	}
+	if math.Abs(got.Popularity-1) > 1e-6 || got.RedistributablePenalty != 1 || got.GoModPenalty != noGoModPenalty || got.Recency != 1 {
+		t.Errorf("got factors %+v, want popularity 1, no redistributable penalty, go.mod penalty %f and recency 1", got, noGoModPenalty)
+	}
+	if want := got.TextRank * got.GoModPenalty; math.Abs(got.Score-want) > 1e-6 {
+		t.Errorf("got score %f, want the product of the factors, %f", got.Score, want)
+	}
+}
+
+func TestSearchFilters(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	// Each of these modules has a package matching the search term "foo".
+	a := sample.Module("filter.com/a", "v1.0.0", "foo")
+	a.LegacyPackages[0].Imports = []string{"filter.com/b/foo"}
+	a.GoVersion = "1.14"
+
+	b := sample.Module("filter.com/b", "v1.1.0-pre", "foo")
+	b.HasGoMod = false
+	b.GoVersion = "1.9"
+	b.LegacyPackages[0].Imports = nil
+	b.LegacyPackages[0].Licenses = []*licenses.Metadata{{Types: []string{"BSD-3-Clause"}, FilePath: "LICENSE"}}
+
+	c := sample.Module("other.com/c", "v0.0.0-20200101000000-abcdef123456", "foo")
+	c.LegacyPackages[0].Imports = nil
+
+	insertModules(ctx, t, a, b, c)
+
+	// The package without a go.mod file is penalized, so it is ranked last.
+	noGoMod := false
+	tests := []searcherTest{
+		{
+			name: "no filters",
+			q:    "foo",
+			want: []string{"filter.com/a/foo", "other.com/c/foo", "filter.com/b/foo"},
+		},
+		{
+			name:    "license",
+			q:       "foo",
+			filters: internal.SearchFilters{Licenses: []string{"BSD-3-Clause"}},
+			want:    []string{"filter.com/b/foo"},
+		},
+		{
+			name:    "any license",
+			q:       "foo",
+			filters: internal.SearchFilters{Licenses: []string{"BSD-3-Clause", "MIT"}},
+			want:    []string{"filter.com/a/foo", "other.com/c/foo", "filter.com/b/foo"},
+		},
+		{
+			name:    "module path prefix",
+			q:       "foo",
+			filters: internal.SearchFilters{ModulePath: "filter.com"},
+			want:    []string{"filter.com/a/foo", "filter.com/b/foo"},
+		},
+		{
+			name:    "module path",
+			q:       "foo",
+			filters: internal.SearchFilters{ModulePath: "filter.com/a"},
+			want:    []string{"filter.com/a/foo"},
+		},
+		{
+			name:    "module path is not a string prefix",
+			q:       "foo",
+			filters: internal.SearchFilters{ModulePath: "filter.com/"},
+			want:    nil,
+		},
+		{
+			name:    "imports",
+			q:       "foo",
+			filters: internal.SearchFilters{Imports: "filter.com/b/foo"},
+			want:    []string{"filter.com/a/foo"},
+		},
+		{
+			name:    "go.mod",
+			q:       "foo",
+			filters: internal.SearchFilters{HasGoMod: &noGoMod},
+			want:    []string{"filter.com/b/foo"},
+		},
+		{
+			name:    "version type",
+			q:       "foo",
+			filters: internal.SearchFilters{VersionType: version.TypePseudo},
+			want:    []string{"other.com/c/foo"},
+		},
+		{
+			// Versions are compared numerically, and modules without a go
+			// directive match any version.
+			name:    "go version",
+			q:       "foo",
+			filters: internal.SearchFilters{GoVersion: "1.13"},
+			want:    []string{"other.com/c/foo", "filter.com/b/foo"},
+		},
+		{
+			name:    "multiple filters",
+			q:       "foo",
+			filters: internal.SearchFilters{ModulePath: "filter.com", VersionType: version.TypeRelease},
+			want:    []string{"filter.com/a/foo"},
+		},
+	}
+	runSearcherTests(ctx, t, tests)
+}
+
+func TestSearchFacets(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	a := sample.Module("facet.com/a", "v1.0.0", "foo")
+	b := sample.Module("facet.com/b", "v1.0.0", "foo")
+	b.HasGoMod = false
+	b.LegacyPackages[0].Licenses = []*licenses.Metadata{{Types: []string{"BSD-3-Clause"}, FilePath: "LICENSE"}}
+	c := sample.Module("other.com/c/v2", "v2.0.0", "foo")
+	insertModules(ctx, t, a, b, c)
+
+	want := &internal.SearchFacets{
+		Licenses: []*internal.FacetValue{{Value: "MIT", Count: 2}, {Value: "BSD-3-Clause", Count: 1}},
+		Hosts:    []*internal.FacetValue{{Value: "facet.com", Count: 2}, {Value: "other.com", Count: 1}},
+		Modules: []*internal.FacetValue{
+			{Value: "facet.com/a", Count: 1},
+			{Value: "facet.com/b", Count: 1},
+			{Value: "other.com/c/v2", Count: 1},
+		},
+		Majors: []*internal.FacetValue{{Value: "v1", Count: 2}, {Value: "v2", Count: 1}},
+		GoMod:  []*internal.FacetValue{{Value: "true", Count: 2}, {Value: "false", Count: 1}},
+	}
+	got, err := testDB.searchFacets(ctx, "foo", internal.SearchFilters{}, hllRegisterCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("searchFacets mismatch (-want +got):\n%s", diff)
+	}
+
+	// Facets are computed over the filtered results.
+	want = &internal.SearchFacets{
+		Licenses: []*internal.FacetValue{{Value: "MIT", Count: 1}},
+		Hosts:    []*internal.FacetValue{{Value: "other.com", Count: 1}},
+		Modules:  []*internal.FacetValue{{Value: "other.com/c/v2", Count: 1}},
+		Majors:   []*internal.FacetValue{{Value: "v2", Count: 1}},
+		GoMod:    []*internal.FacetValue{{Value: "true", Count: 1}},
+	}
+	for _, filters := range []internal.SearchFilters{
+		{ModulePath: "other.com"},
+		{MajorVersion: "v2"},
+	} {
+		got, err = testDB.searchFacets(ctx, "foo", filters, hllRegisterCount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("searchFacets with filters %+v mismatch (-want +got):\n%s", filters, diff)
+		}
+	}
+
+	// A sample of the registers yields approximate counts.
+	got, err = testDB.searchFacets(ctx, "foo", internal.SearchFilters{}, facetSampleRegisters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Approximate {
+		t.Error("searchFacets with sample: got Approximate = false, want true")
+	}
+}
+
+func TestSearchModuleDocuments(t *testing.T) {
+	// Verify that a module without a root package is found by its README,
+	// and that a root package replaces its module document.
+	defer ResetTestDB(testDB, t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	const modulePath = "sdk.com/cloud"
+	insert := func(version string, suffixes ...string) {
+		t.Helper()
+		m := sample.Module(modulePath, version, suffixes...)
+		m.LegacyReadmeFilePath = "README"
+		m.LegacyReadmeContents = "The SDK provides clients for cloud services. See the packages."
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	insert("v1.0.0", "storage", "compute")
+
+	want := []*internal.SearchResult{{
+		Name:        "cloud",
+		PackagePath: modulePath,
+		ModulePath:  modulePath,
+		Version:     "v1.0.0",
+		Synopsis:    "The SDK provides clients for cloud services.",
+		Licenses:    []string{"MIT"},
+		IsModule:    true,
+	}}
+	opts := cmpopts.IgnoreFields(internal.SearchResult{}, "CommitTime", "Score", "NumResults", "Cursor")
+	for method, searcher := range primarySearchers() {
+		t.Run(method, func(t *testing.T) {
+			resp := searcher(testDB, ctx, "clients", internal.SearchFilters{}, 10, 0, nil)
+			if resp.err != nil {
+				t.Fatal(resp.err)
+			}
+			if err := testDB.addPackageDataToSearchResults(ctx, resp.results); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, resp.results, opts); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// A later version with a root package replaces the module document.
+	insert("v1.1.0", "", "storage", "compute")
+	var isModule bool
+	if err := testDB.db.QueryRow(ctx, `SELECT is_module FROM search_documents WHERE package_path = $1`, modulePath).Scan(&isModule); err != nil {
+		t.Fatal(err)
+	}
+	if isModule {
+		t.Errorf("%s: got is_module = true, want false", modulePath)
+	}
+}
+
+func TestSearchReadmeSections(t *testing.T) {
+	// Verify that search results report the README section that best
+	// matches the query.
+	defer ResetTestDB(testDB, t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	m := sample.Module("readme.com/cfg", "v1.0.0", "")
+	m.LegacyPackages[0].Synopsis = "Package cfg reads configuration files."
+	m.LegacyReadmeFilePath = "README.md"
+	m.LegacyReadmeContents = `
+Read settings from files.
+
+## Installation
+
+No configuration is needed to install it.
+
+## Configuration
+
+Configuration files are read from the current directory.
+`
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		q                       string
+		wantSection, wantAnchor string
+	}{
+		{"configuration", "Configuration", "configuration"},
+		{"install", "Installation", "installation"},
+		{"cfg", "", ""},
+	} {
+		results, _, err := testDB.Search(ctx, test.q, internal.SearchFilters{}, 10, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("%q: got %d results, want 1", test.q, len(results))
+		}
+		if got := results[0]; got.ReadmeSection != test.wantSection || got.ReadmeAnchor != test.wantAnchor {
+			t.Errorf("%q: got section %q, anchor %q; want %q, %q",
+				test.q, got.ReadmeSection, got.ReadmeAnchor, test.wantSection, test.wantAnchor)
+		}
+	}
 }
 
 func TestExcludedFromSearch(t *testing.T) {
@@ -629,27 +1262,39 @@ func TestExcludedFromSearch(t *testing.T) {
 
 	// Insert a module with two packages.
 	const domain = "exclude.com"
-	sm := sample.Module(domain, "v1.2.3", "pkg", "exclude")
+	sm := sample.Module(domain, "v1.2.3", "foo/pkg", "foo/exclude")
 	if err := testDB.InsertModule(ctx, sm); err != nil {
 		t.Fatal(err)
 	}
 	// Exclude a prefix that matches one of the packages.
-	if err := testDB.InsertExcludedPrefix(ctx, domain+"/ex", "no user", "no reason"); err != nil {
+	if err := testDB.InsertExcludedPrefix(ctx, domain+"/foo/ex", "no user", "no reason"); err != nil {
 		t.Fatal(err)
 	}
-	// Search for both packages.
-	gotResults, err := testDB.Search(ctx, domain, 10, 0)
-	if err != nil {
+	// Make the excluded package the most popular, so that it would be the
+	// first result.
+	if _, err := testDB.db.Exec(ctx, `UPDATE search_documents SET imported_by_count = 10 WHERE package_path = $1`, domain+"/foo/exclude"); err != nil {
 		t.Fatal(err)
 	}
-	var got []string
-	for _, g := range gotResults {
-		got = append(got, g.Name)
-	}
-	want := []string{"pkg"}
-	if !cmp.Equal(got, want) {
-		t.Errorf("got %v, want %v", got, want)
+	// Search for both packages by their directory. The excluded package
+	// neither takes up a result nor is counted.
+	for _, limit := range []int{1, 10} {
+		gotResults, _, err := testDB.Search(ctx, "foo", internal.SearchFilters{}, limit, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, g := range gotResults {
+			got = append(got, g.Name)
+			if g.NumResults != 1 {
+				t.Errorf("limit %d: %s: got NumResults = %d, want 1", limit, g.Name, g.NumResults)
+			}
+		}
+		want := []string{"pkg"}
+		if !cmp.Equal(got, want) {
+			t.Errorf("limit %d: got %v, want %v", limit, got, want)
+		}
 	}
+	runSearcherTests(ctx, t, []searcherTest{{q: "foo", limit: 1, want: []string{domain + "/foo/pkg"}}})
 }
 
 type searchDocument struct {
@@ -906,6 +1551,50 @@ func TestUpdateSearchDocumentsImportedByCount(t *testing.T) {
 	})
 }
 
+func TestUpdateSearchDocumentsRecentVersionCounts(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	for _, v := range []string{"v1.0.0", "v1.1.0"} {
+		if err := testDB.InsertModule(ctx, sample.Module("mod.com", v, "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	numRecentVersions := func() int {
+		t.Helper()
+		var n int
+		if err := testDB.db.QueryRow(ctx,
+			`SELECT num_recent_versions FROM search_documents WHERE package_path = 'mod.com'`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if got, want := numRecentVersions(), 2; got != want {
+		t.Fatalf("after insert: got %d recent versions, want %d", got, want)
+	}
+
+	// Age the first version, so it no longer counts as recent.
+	if _, err := testDB.db.Exec(ctx,
+		`UPDATE modules SET commit_time = $1 WHERE module_path = 'mod.com' AND version = 'v1.0.0'`,
+		time.Now().Add(-2*365*24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []int64{1, 0} {
+		n, err := testDB.UpdateSearchDocumentsRecentVersionCounts(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Errorf("got %d rows updated, want %d", n, want)
+		}
+	}
+	if got, want := numRecentVersions(), 1; got != want {
+		t.Errorf("after update: got %d recent versions, want %d", got, want)
+	}
+}
+
 func TestGetPackagesForSearchDocumentUpsert(t *testing.T) {
 	defer ResetTestDB(testDB, t)
 
@@ -919,13 +1608,20 @@ func TestGetPackagesForSearchDocumentUpsert(t *testing.T) {
 	}
 
 	// We are asking for all packages in search_documents updated before now, which is
-	// all the non-internal packages.
+	// all the non-internal packages, and the module itself, which has no root package.
 	got, err := testDB.GetPackagesForSearchDocumentUpsert(ctx, time.Now(), 10)
 	if err != nil {
 		t.Fatal(err)
 	}
 	sort.Slice(got, func(i, j int) bool { return got[i].PackagePath < got[j].PackagePath })
 	want := []upsertSearchDocumentArgs{
+		{
+			PackagePath:    "mod.com",
+			ModulePath:     "mod.com",
+			ReadmeFilePath: "README.md",
+			ReadmeContents: "readme",
+			IsModule:       true,
+		},
 		{
 			PackagePath:    "mod.com/A",
 			ModulePath:     "mod.com",
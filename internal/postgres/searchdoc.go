@@ -5,6 +5,7 @@
 package postgres
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
 	"unicode"
@@ -15,6 +16,10 @@ import (
 const (
 	maxSectionWords   = 50
 	maxReadmeFraction = 0.5
+
+	// maxReadmeSectionWords is the maximum number of words indexed for each
+	// section of a README.
+	maxReadmeSectionWords = 500
 )
 
 // SearchDocumentSections computes the B and C sections of a Postgres search
@@ -73,6 +78,21 @@ func searchDocumentSections(synopsis, readmeFilename, readme string, maxSecWords
 	return prep(sectionB), prep(sectionC), prep(sectionD)
 }
 
+// readmeSynopsis returns a synopsis for a module from its README: the first
+// sentence, with whitespace collapsed and limited to maxSectionWords words.
+// It returns the empty string if the README has no sentence end.
+func readmeSynopsis(readmeFilename, readme string) string {
+	if isMarkdown(readmeFilename) {
+		readme = processMarkdown(readme)
+	}
+	i := sentenceEndIndex(readme)
+	if i < 0 {
+		return ""
+	}
+	words, _ := split(strings.Fields(readme[:i+1]), maxSectionWords)
+	return makeValidUnicode(strings.Join(words, " "))
+}
+
 // split splits a slice of strings into two parts. The first has length <= n,
 // and the second is the rest of the slice. If n is negative, the first part is nil and
 // the second part is the entire slice.
@@ -211,3 +231,76 @@ func walkMarkdown(n *blackfriday.Node, buf []byte, level int) []byte {
 	}
 	return buf
 }
+
+// A readmeSection is the part of a markdown README that starts at a top-level
+// heading and ends before the next one.
+type readmeSection struct {
+	title  string // the text of the heading
+	anchor string // the id of the heading on the overview tab
+	words  string // the processed words of the section, including the heading
+}
+
+// readmeSections splits a markdown README into sections at its top-level
+// headings. Text before the first heading is not in any section, and
+// headings without an id are merged into the preceding section.
+// readmeSections returns nil if the README is not markdown.
+func readmeSections(readmeFilename, readme string) []*readmeSection {
+	if !isMarkdown(readmeFilename) {
+		return nil
+	}
+	// Parse the README as readmeHTML in internal/frontend does, so that the
+	// heading ids are the same as on the overview tab.
+	parser := blackfriday.New(blackfriday.WithExtensions(blackfriday.CommonExtensions | blackfriday.AutoHeadingIDs))
+	root := parser.Parse([]byte(readme))
+	var (
+		sections []*readmeSection
+		texts    [][]byte
+		ids      = map[string]int{}
+	)
+	for n := root.FirstChild; n != nil; n = n.Next {
+		// The renderer makes the ids of all headings unique, including
+		// those nested in other blocks, in document order.
+		var anchor string
+		n.Walk(func(c *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+			if entering && c.Type == blackfriday.Heading && c.HeadingID != "" {
+				id := uniqueHeadingID(ids, c.HeadingID)
+				if c == n {
+					anchor = id
+				}
+			}
+			return blackfriday.GoToNext
+		})
+		if anchor != "" {
+			title := strings.Join(strings.Fields(string(walkMarkdown(n, nil, 0))), " ")
+			sections = append(sections, &readmeSection{title: makeValidUnicode(title), anchor: anchor})
+			texts = append(texts, nil)
+		}
+		if len(texts) > 0 {
+			texts[len(texts)-1] = walkMarkdown(n, texts[len(texts)-1], 0)
+		}
+	}
+	for i, s := range sections {
+		words, _ := split(processWords(string(texts[i])), maxReadmeSectionWords)
+		s.words = makeValidUnicode(strings.Join(words, " "))
+	}
+	return sections
+}
+
+// uniqueHeadingID returns id, or a variant of it that is not in ids, and
+// records the result in ids. It follows blackfriday's HTMLRenderer, which
+// appends a count to repeated heading ids.
+func uniqueHeadingID(ids map[string]int, id string) string {
+	for count, found := ids[id]; found; count, found = ids[id] {
+		tmp := fmt.Sprintf("%s-%d", id, count+1)
+		if _, tmpFound := ids[tmp]; !tmpFound {
+			ids[id] = count + 1
+			id = tmp
+		} else {
+			id = id + "-1"
+		}
+	}
+	if _, found := ids[id]; !found {
+		ids[id] = 0
+	}
+	return id
+}
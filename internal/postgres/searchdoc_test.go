@@ -131,3 +131,57 @@ func TestSentenceEndIndex(t *testing.T) {
 		}
 	}
 }
+
+func TestReadmeSynopsis(t *testing.T) {
+	for _, test := range []struct {
+		filename, readme, want string
+	}{
+		{"README", "", ""},
+		{"README", "no end", ""},
+		{"README", "The SDK for\n  cloud services. More text.", "The SDK for cloud services."},
+		{"README.md", "# SDK\n\nThe *SDK* for [cloud](https://cloud.example.com) services.", "SDK The SDK for cloud services."},
+	} {
+		got := readmeSynopsis(test.filename, test.readme)
+		if got != test.want {
+			t.Errorf("readmeSynopsis(%q, %q) = %q, want %q", test.filename, test.readme, got, test.want)
+		}
+	}
+}
+
+func TestReadmeSections(t *testing.T) {
+	const readme = `
+Intro text that is not in any section.
+
+# Installation
+
+Run *go get*.
+
+> ## Quoted
+
+## Configuration {#config}
+
+Set the timeout.
+
+## Usage
+
+Call Run.
+
+## Usage
+
+Call Stop.
+`
+	got := readmeSections("README.md", readme)
+	want := []*readmeSection{
+		{title: "Installation", anchor: "installation", words: "installation run go get quoted"},
+		{title: "Configuration", anchor: "config", words: "configuration set the timeout"},
+		{title: "Usage", anchor: "usage", words: "usage call run"},
+		{title: "Usage", anchor: "usage-1", words: "usage call stop"},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(readmeSection{})); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := readmeSections("README", readme); got != nil {
+		t.Errorf("got %v for a README that is not markdown, want nil", got)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"go/token"
+	"strings"
+
+	"github.com/lib/pq"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// SymbolSearch searches for exported symbols matching q, in the latest version
+// of each package that satisfies filters. It returns at most limit results,
+// ordered by the number of packages that import the symbol's package.
+//
+// The query must have the form "Name" or "Qualifier.Name", where Qualifier is
+// the name of a type or of a package. Matching is case-insensitive. If q does
+// not have that form, SymbolSearch returns no results.
+func (db *DB) SymbolSearch(ctx context.Context, q string, filters internal.SearchFilters, limit int) (_ []*internal.SymbolSearchResult, err error) {
+	defer derrors.Wrap(&err, "DB.SymbolSearch(ctx, %q, %+v, %d)", q, filters, limit)
+
+	qualifier, name, ok := parseSymbolQuery(q)
+	if !ok {
+		return nil, nil
+	}
+	// The symbols and search_documents tables are joined with USING so that
+	// the unqualified column names in filterExpr are not ambiguous.
+	query := fmt.Sprintf(`
+		SELECT
+			package_path,
+			module_path,
+			search_documents.version,
+			search_documents.name,
+			search_documents.imported_by_count,
+			s.name,
+			s.parent_name,
+			s.kind,
+			s.synopsis
+		FROM symbols s
+		INNER JOIN search_documents USING (package_path, module_path)
+		WHERE lower(s.name) = lower($1)
+		AND (
+			$2 = ''
+			OR lower(s.parent_name) = lower($2)
+			OR (s.parent_name = '' AND lower(search_documents.name) = lower($2))
+		)
+		AND %s
+		ORDER BY
+			search_documents.imported_by_count DESC,
+			package_path,
+			s.parent_name,
+			s.name
+		LIMIT $3`, filterExpr(4))
+	var results []*internal.SymbolSearchResult
+	collect := func(rows *sql.Rows) error {
+		var r internal.SymbolSearchResult
+		if err := rows.Scan(&r.PackagePath, &r.ModulePath, &r.Version, &r.PackageName, &r.NumImportedBy,
+			&r.Name, &r.ParentName, &r.Kind, &r.Synopsis); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		results = append(results, &r)
+		return nil
+	}
+	args := append([]interface{}{name, qualifier, limit}, filterArgs(filters)...)
+	if err := db.db.RunQuery(ctx, query, collect, args...); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetPackageSymbols returns the name and exported identifiers of each of the
+// packages with the given paths in the given version of the module with
+// modulePath, ordered by path. Packages that are not in that version are
+// omitted.
+//
+// If version is empty, the latest version of each package is used, whatever
+// its module, and packages that are not in search_documents, including
+// internal packages, are omitted.
+func (db *DB) GetPackageSymbols(ctx context.Context, modulePath, version string, paths []string) (_ []*internal.PackageSymbols, err error) {
+	defer derrors.Wrap(&err, "DB.GetPackageSymbols(ctx, %q, %q, %v)", modulePath, version, paths)
+
+	query := `
+		SELECT
+			p.path,
+			p.name,
+			s.name,
+			s.parent_name,
+			s.kind
+		FROM packages p
+		LEFT JOIN symbol_history s
+		ON s.package_path = p.path
+		AND s.module_path = p.module_path
+		AND s.version = p.version
+		WHERE p.path = ANY($1)
+		AND p.module_path = $2
+		AND p.version = $3
+		ORDER BY
+			p.path,
+			s.parent_name,
+			s.name`
+	args := []interface{}{pq.Array(paths), modulePath, version}
+	if version == "" {
+		query = `
+			SELECT
+				d.package_path,
+				d.name,
+				s.name,
+				s.parent_name,
+				s.kind
+			FROM search_documents d
+			LEFT JOIN symbols s
+			ON s.package_path = d.package_path
+			AND s.module_path = d.module_path
+			WHERE d.package_path = ANY($1)
+			ORDER BY
+				d.package_path,
+				s.parent_name,
+				s.name`
+		args = args[:1]
+	}
+	var pss []*internal.PackageSymbols
+	collect := func(rows *sql.Rows) error {
+		var (
+			path, pkgName          string
+			name, parentName, kind sql.NullString
+		)
+		if err := rows.Scan(&path, &pkgName, &name, &parentName, &kind); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		if len(pss) == 0 || pss[len(pss)-1].Path != path {
+			pss = append(pss, &internal.PackageSymbols{Path: path, Name: pkgName})
+		}
+		if name.Valid {
+			ps := pss[len(pss)-1]
+			ps.Symbols = append(ps.Symbols, &internal.Symbol{
+				Name:       name.String,
+				ParentName: parentName.String,
+				Kind:       internal.SymbolKind(kind.String),
+			})
+		}
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, args...); err != nil {
+		return nil, err
+	}
+	return pss, nil
+}
+
+// parseSymbolQuery splits a symbol search query of the form "Name" or
+// "Qualifier.Name" into its parts. It reports whether q has one of those
+// forms.
+func parseSymbolQuery(q string) (qualifier, name string, ok bool) {
+	name = q
+	if i := strings.IndexByte(q, '.'); i >= 0 {
+		qualifier, name = q[:i], q[i+1:]
+		if !token.IsIdentifier(qualifier) {
+			return "", "", false
+		}
+	}
+	if !token.IsIdentifier(name) {
+		return "", "", false
+	}
+	return qualifier, name, true
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/version"
+)
+
+// GetSymbolHistory returns the first version in which each exported identifier
+// of the package with packagePath appears, among the release versions of the
+// module with modulePath that are no later than vers. The result is keyed by
+// the anchor ID of the identifier, as returned by internal.Symbol.Anchor.
+func (db *DB) GetSymbolHistory(ctx context.Context, packagePath, modulePath, vers string) (_ map[string]string, err error) {
+	defer derrors.Wrap(&err, "DB.GetSymbolHistory(ctx, %q, %q, %q)", packagePath, modulePath, vers)
+
+	query := `
+		SELECT DISTINCT ON (s.parent_name, s.name)
+			s.name,
+			s.parent_name,
+			s.version
+		FROM symbol_history s
+		INNER JOIN modules m
+		ON m.module_path = s.module_path
+		AND m.version = s.version
+		WHERE s.package_path = $1
+		AND s.module_path = $2
+		AND m.version_type = 'release'
+		AND m.sort_version <= $3
+		ORDER BY
+			s.parent_name,
+			s.name,
+			m.sort_version`
+	history := map[string]string{}
+	collect := func(rows *sql.Rows) error {
+		var (
+			s internal.Symbol
+			v string
+		)
+		if err := rows.Scan(&s.Name, &s.ParentName, &v); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		history[s.Anchor()] = v
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, packagePath, modulePath, version.ForSorting(vers)); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// GetSymbols returns the exported identifiers of the package with packagePath
+// in the given version of the module with modulePath, ordered by parent name
+// and name. The Synopsis of each symbol is its recorded signature.
+//
+// It returns an error wrapping derrors.NotFound if the identifiers of the
+// package version were not recorded, because it was processed before
+// symbol_history was added.
+func (db *DB) GetSymbols(ctx context.Context, packagePath, modulePath, vers string) (_ []*internal.Symbol, err error) {
+	defer derrors.Wrap(&err, "DB.GetSymbols(ctx, %q, %q, %q)", packagePath, modulePath, vers)
+
+	var recorded bool
+	err = db.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM symbol_history_packages
+			WHERE package_path = $1
+			AND module_path = $2
+			AND version = $3
+		)`, packagePath, modulePath, vers).Scan(&recorded)
+	if err != nil {
+		return nil, err
+	}
+	if !recorded {
+		return nil, derrors.NotFound
+	}
+
+	query := `
+		SELECT
+			name,
+			parent_name,
+			kind,
+			signature
+		FROM symbol_history
+		WHERE package_path = $1
+		AND module_path = $2
+		AND version = $3
+		ORDER BY
+			parent_name,
+			name`
+	var symbols []*internal.Symbol
+	collect := func(rows *sql.Rows) error {
+		var s internal.Symbol
+		if err := rows.Scan(&s.Name, &s.ParentName, &s.Kind, &s.Synopsis); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		symbols = append(symbols, &s)
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, packagePath, modulePath, vers); err != nil {
+		return nil, err
+	}
+	return symbols, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestGetSymbolHistory(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	var (
+		client = &internal.Symbol{Name: "Client", Kind: internal.SymbolKindType, Synopsis: "type Client struct"}
+		do     = &internal.Symbol{Name: "Do", Kind: internal.SymbolKindMethod, ParentName: "Client", Synopsis: "func (*Client) Do()"}
+		get    = &internal.Symbol{Name: "Get", Kind: internal.SymbolKindFunction, Synopsis: "func Get()"}
+		put    = &internal.Symbol{Name: "Put", Kind: internal.SymbolKindFunction, Synopsis: "func Put()"}
+	)
+	for _, v := range []struct {
+		version string
+		symbols []*internal.Symbol
+	}{
+		// Inserted out of order, to check that versions are compared
+		// semantically.
+		{"v1.10.0", []*internal.Symbol{client, do, get, put}},
+		{"v1.2.0", []*internal.Symbol{client, do}},
+		{"v1.0.0", []*internal.Symbol{client}},
+		// Prerelease versions are ignored.
+		{"v1.1.0-pre", []*internal.Symbol{client, do, get}},
+		{"v1.3.0", []*internal.Symbol{client, do, get}},
+	} {
+		m := sample.Module("symbol.com/a", v.version, "api")
+		m.LegacyPackages[0].Symbols = v.symbols
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		version string
+		want    map[string]string
+	}{
+		{"v1.10.0", map[string]string{"Client": "v1.0.0", "Client.Do": "v1.2.0", "Get": "v1.3.0", "Put": "v1.10.0"}},
+		{"v1.4.0", map[string]string{"Client": "v1.0.0", "Client.Do": "v1.2.0", "Get": "v1.3.0"}},
+		{"v0.9.0", map[string]string{}},
+	} {
+		got, err := testDB.GetSymbolHistory(ctx, "symbol.com/a/api", "symbol.com/a", test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", test.version, diff)
+		}
+	}
+}
+
+func TestGetSymbols(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	var (
+		get    = &internal.Symbol{Name: "Get", Kind: internal.SymbolKindFunction, Synopsis: "func Get()"}
+		client = &internal.Symbol{Name: "Client", Kind: internal.SymbolKindType, Synopsis: "type Client struct"}
+		do     = &internal.Symbol{Name: "Do", Kind: internal.SymbolKindMethod, ParentName: "Client", Synopsis: "func (*Client) Do()"}
+	)
+	m := sample.Module("symbol.com/a", "v1.0.0", "api")
+	m.LegacyPackages[0].Symbols = []*internal.Symbol{get, client, do}
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := testDB.GetSymbols(ctx, "symbol.com/a/api", "symbol.com/a", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*internal.Symbol{client, get, do}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// A package with no exported identifiers is recorded as such.
+	m = sample.Module("symbol.com/a", "v1.1.0", "api")
+	m.LegacyPackages[0].Symbols = nil
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	got, err = testDB.GetSymbols(ctx, "symbol.com/a/api", "symbol.com/a", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("got %d symbols for a package with none, want 0", len(got))
+	}
+
+	if _, err := testDB.GetSymbols(ctx, "symbol.com/a/api", "symbol.com/a", "v1.2.0"); !errors.Is(err, derrors.NotFound) {
+		t.Errorf("got error %v for a missing version, want NotFound", err)
+	}
+}
+
+func TestInsertSymbolHistoryRequeuesLaterVersions(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	const modulePath = "symbol.com/a"
+	insert := func(vers string) {
+		t.Helper()
+		m := sample.Module(modulePath, vers, "api")
+		m.LegacyPackages[0].Symbols = []*internal.Symbol{
+			{Name: "Get", Kind: internal.SymbolKindFunction, Synopsis: "func Get()"},
+		}
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+		if err := testDB.UpsertModuleVersionState(ctx, modulePath, vers, "appVersion", time.Now(), http.StatusOK, "", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkStatus := func(vers string, want int) {
+		t.Helper()
+		got, err := testDB.GetModuleVersionState(ctx, modulePath, vers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != want {
+			t.Errorf("%s: status = %d, want %d", vers, got.Status, want)
+		}
+	}
+
+	insert("v1.2.0")
+	insert("v1.1.0-pre")
+	// Inserting an earlier release version for the first time requeues the
+	// later release versions.
+	insert("v1.0.0")
+	checkStatus("v1.2.0", derrors.ToHTTPStatus(derrors.ReprocessStatusOK))
+	checkStatus("v1.1.0-pre", http.StatusOK)
+
+	// Inserting it again does not.
+	insert("v1.2.0")
+	insert("v1.0.0")
+	checkStatus("v1.2.0", http.StatusOK)
+}
@@ -0,0 +1,152 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestSymbolSearch(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	var (
+		newClient = internal.Symbol{Name: "NewClient", Kind: internal.SymbolKindFunction, Synopsis: "func NewClient() *Client"}
+		client    = internal.Symbol{Name: "Client", Kind: internal.SymbolKindType, Synopsis: "type Client struct{ ... }"}
+		do        = internal.Symbol{Name: "Do", Kind: internal.SymbolKindMethod, ParentName: "Client", Synopsis: "func (c *Client) Do() error"}
+		doFunc    = internal.Symbol{Name: "Do", Kind: internal.SymbolKindFunction, Synopsis: "func Do() error"}
+	)
+	a := sample.Module("symbol.com/a", "v1.0.0", "api")
+	a.LegacyPackages[0].Symbols = []*internal.Symbol{&newClient, &client, &do}
+	b := sample.Module("symbol.com/b", "v1.0.0", "run", "internal/run")
+	b.LegacyPackages[0].Symbols = []*internal.Symbol{&doFunc}
+	// Symbols of internal packages are not searchable.
+	b.LegacyPackages[1].Symbols = []*internal.Symbol{&client}
+	insertModules(ctx, t, a, b)
+	// Make symbol.com/b/run more popular, so its symbols are listed first.
+	if _, err := testDB.db.Exec(ctx, `UPDATE search_documents SET imported_by_count = 10 WHERE package_path = 'symbol.com/b/run'`); err != nil {
+		t.Fatal(err)
+	}
+
+	result := func(modulePath, pkgName string, s internal.Symbol) *internal.SymbolSearchResult {
+		r := &internal.SymbolSearchResult{
+			Symbol:      s,
+			PackageName: pkgName,
+			PackagePath: modulePath + "/" + pkgName,
+			ModulePath:  modulePath,
+			Version:     "v1.0.0",
+		}
+		if r.PackagePath == "symbol.com/b/run" {
+			r.NumImportedBy = 10
+		}
+		return r
+	}
+	for _, test := range []struct {
+		q       string
+		filters internal.SearchFilters
+		want    []*internal.SymbolSearchResult
+	}{
+		{q: "NewClient", want: []*internal.SymbolSearchResult{result("symbol.com/a", "api", newClient)}},
+		{q: "newclient", want: []*internal.SymbolSearchResult{result("symbol.com/a", "api", newClient)}},
+		{q: "Client", want: []*internal.SymbolSearchResult{result("symbol.com/a", "api", client)}},
+		{q: "Client.Do", want: []*internal.SymbolSearchResult{result("symbol.com/a", "api", do)}},
+		{q: "run.Do", want: []*internal.SymbolSearchResult{result("symbol.com/b", "run", doFunc)}},
+		{q: "api.NewClient", want: []*internal.SymbolSearchResult{result("symbol.com/a", "api", newClient)}},
+		{
+			q: "Do",
+			want: []*internal.SymbolSearchResult{
+				result("symbol.com/b", "run", doFunc),
+				result("symbol.com/a", "api", do),
+			},
+		},
+		{
+			q:       "Do",
+			filters: internal.SearchFilters{ModulePath: "symbol.com/a"},
+			want:    []*internal.SymbolSearchResult{result("symbol.com/a", "api", do)},
+		},
+		{q: "Other.Do", want: nil},
+		{q: "Client Do", want: nil},
+		{q: "a.b.c", want: nil},
+	} {
+		t.Run(test.q, func(t *testing.T) {
+			got, err := testDB.SymbolSearch(ctx, test.q, test.filters, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("SymbolSearch(%q, %+v) mismatch (-want +got):\n%s", test.q, test.filters, diff)
+			}
+		})
+	}
+}
+
+func TestGetPackageSymbols(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	var (
+		client = internal.Symbol{Name: "Client", Kind: internal.SymbolKindType}
+		do     = internal.Symbol{Name: "Do", Kind: internal.SymbolKindMethod, ParentName: "Client"}
+		get    = internal.Symbol{Name: "Get", Kind: internal.SymbolKindFunction}
+	)
+	m := sample.Module("symbol.com/a", "v1.0.0", "api", "empty", "internal/hidden")
+	m.LegacyPackages[0].Symbols = []*internal.Symbol{&client, &do}
+	m.LegacyPackages[1].Symbols = nil
+	m.LegacyPackages[2].Symbols = []*internal.Symbol{&client}
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	m = sample.Module("symbol.com/a", "v1.1.0", "api", "empty", "internal/hidden")
+	m.LegacyPackages[0].Symbols = []*internal.Symbol{&client, &do, &get}
+	m.LegacyPackages[1].Symbols = nil
+	m.LegacyPackages[2].Symbols = []*internal.Symbol{&client}
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := []string{"symbol.com/a/api", "symbol.com/a/empty", "symbol.com/a/internal/hidden", "symbol.com/a/missing"}
+	for _, test := range []struct {
+		modulePath, version string
+		want                []*internal.PackageSymbols
+	}{
+		{
+			modulePath: "symbol.com/a",
+			version:    "v1.0.0",
+			want: []*internal.PackageSymbols{
+				{Path: "symbol.com/a/api", Name: "api", Symbols: []*internal.Symbol{&client, &do}},
+				{Path: "symbol.com/a/empty", Name: "empty"},
+				{Path: "symbol.com/a/internal/hidden", Name: "hidden", Symbols: []*internal.Symbol{&client}},
+			},
+		},
+		{
+			modulePath: "symbol.com/a",
+			version:    "v2.0.0",
+		},
+		{
+			// The latest version of each package, without internal packages.
+			want: []*internal.PackageSymbols{
+				{Path: "symbol.com/a/api", Name: "api", Symbols: []*internal.Symbol{&client, &get, &do}},
+				{Path: "symbol.com/a/empty", Name: "empty"},
+			},
+		},
+	} {
+		got, err := testDB.GetPackageSymbols(ctx, test.modulePath, test.version, paths)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("%q: mismatch (-want +got):\n%s", test.version, diff)
+		}
+	}
+}
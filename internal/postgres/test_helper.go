@@ -118,6 +118,12 @@ func ResetTestDB(db *DB, t *testing.T) {
 		if _, err := tx.Exec(ctx, `TRUNCATE excluded_prefixes;`); err != nil {
 			return err
 		}
+		if _, err := tx.Exec(ctx, `TRUNCATE search_boosts;`); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `TRUNCATE rendered_packages;`); err != nil {
+			return err
+		}
 		setExcludedPrefixesLastFetched(time.Time{})
 		return nil
 	}); err != nil {
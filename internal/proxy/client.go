@@ -7,7 +7,6 @@ package proxy
 import (
 	"archive/zip"
 	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -16,11 +15,14 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 	"time"
 
 	"go.opencensus.io/plugin/ochttp"
 	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	modzip "golang.org/x/mod/zip"
 	"golang.org/x/net/context/ctxhttp"
 	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/derrors"
@@ -34,6 +36,11 @@ type Client struct {
 
 	// client used for HTTP requests. It is mutable for testing purposes.
 	httpClient *http.Client
+
+	// noLatest reports whether the proxy does not serve the @latest
+	// endpoint, so that the latest version must be computed from the list
+	// of versions. This is the case for file:// URLs.
+	noLatest bool
 }
 
 // A VersionInfo contains metadata about a given version of a module.
@@ -44,23 +51,48 @@ type VersionInfo struct {
 
 // New constructs a *Client using the provided rawurl, which is expected to
 // be an absolute URI that can be directly passed to http.Get.
+//
+// The URL may also be a file:// URL for a directory with the layout of a
+// module proxy, such as $GOMODCACHE/cache/download. Such a directory
+// has no @latest endpoint, so the latest version of a module is the
+// highest version in its list.
 func New(rawurl string) (_ *Client, err error) {
 	defer derrors.Wrap(&err, "proxy.New(%q)", rawurl)
 	url, err := url.Parse(rawurl)
 	if err != nil {
 		return nil, fmt.Errorf("url.Parse: %v", err)
 	}
-	if url.Scheme != "https" {
-		return nil, fmt.Errorf("scheme must be https (got %s)", url.Scheme)
-	}
 	cleanURL := strings.TrimRight(rawurl, "/")
-	return &Client{url: cleanURL, httpClient: &http.Client{Transport: &ochttp.Transport{}}}, nil
+	switch url.Scheme {
+	case "https":
+		return &Client{url: cleanURL, httpClient: &http.Client{Transport: &ochttp.Transport{}}}, nil
+	case "file":
+		if url.Host != "" {
+			return nil, fmt.Errorf("file URL must not have a host (got %s)", url.Host)
+		}
+		if url.Path == "" {
+			return nil, errors.New("file URL must have an absolute path")
+		}
+		// A missing file results in a 404, which is treated like
+		// a 404 from a proxy.
+		t := &http.Transport{}
+		t.RegisterProtocol("file", http.NewFileTransport(http.Dir("/")))
+		return &Client{url: cleanURL, httpClient: &http.Client{Transport: t}, noLatest: true}, nil
+	default:
+		return nil, fmt.Errorf("scheme must be https or file (got %s)", url.Scheme)
+	}
 }
 
 // GetInfo makes a request to $GOPROXY/<module>/@v/<requestedVersion>.info and
 // transforms that data into a *VersionInfo.
 func (c *Client) GetInfo(ctx context.Context, modulePath, requestedVersion string) (_ *VersionInfo, err error) {
 	defer derrors.Wrap(&err, "proxy.Client.GetInfo(%q, %q)", modulePath, requestedVersion)
+	if requestedVersion == internal.LatestVersion && c.noLatest {
+		requestedVersion, err = c.latestFromList(ctx, modulePath)
+		if err != nil {
+			return nil, err
+		}
+	}
 	data, err := c.readBody(ctx, modulePath, requestedVersion, "info")
 	if err != nil {
 		return nil, err
@@ -72,32 +104,100 @@ func (c *Client) GetInfo(ctx context.Context, modulePath, requestedVersion strin
 	return &v, nil
 }
 
+// latestFromList returns the latest version of modulePath from the list of
+// its versions: the highest release version if there is one, and otherwise the
+// highest version.
+func (c *Client) latestFromList(ctx context.Context, modulePath string) (_ string, err error) {
+	versions, err := c.ListVersions(ctx, modulePath)
+	if err != nil {
+		return "", err
+	}
+	var latest, latestRelease string
+	for _, v := range versions {
+		if !semver.IsValid(v) {
+			continue
+		}
+		if semver.Compare(v, latest) > 0 {
+			latest = v
+		}
+		if semver.Prerelease(v) == "" && semver.Compare(v, latestRelease) > 0 {
+			latestRelease = v
+		}
+	}
+	if latestRelease != "" {
+		return latestRelease, nil
+	}
+	if latest != "" {
+		return latest, nil
+	}
+	return "", fmt.Errorf("no versions in list: %w", derrors.NotFound)
+}
+
 // GetMod makes a request to $GOPROXY/<module>/@v/<resolvedVersion>.mod and returns the raw data.
 func (c *Client) GetMod(ctx context.Context, modulePath, resolvedVersion string) (_ []byte, err error) {
 	defer derrors.Wrap(&err, "proxy.Client.GetMod(%q, %q)", modulePath, resolvedVersion)
 	return c.readBody(ctx, modulePath, resolvedVersion, "mod")
 }
 
+// maxZipSize is the maximum size of a module zip. The go command rejects
+// larger zips, so they are treated as bad modules.
+//
+// It is a variable for testing.
+var maxZipSize int64 = modzip.MaxZipFile
+
 // GetZip makes a request to $GOPROXY/<path>/@v/<resolvedVersion>.zip and transforms
 // that data into a *zip.Reader. <resolvedVersion> is obtained by first making a
 // request to $GOPROXY/<path>/@v/<requestedVersion>.info to obtained the valid
 // semantic version.
-func (c *Client) GetZip(ctx context.Context, requestedPath, requestedVersion string) (_ *zip.Reader, err error) {
+//
+// The zip is written to a temporary file rather than held in memory, so that
+// the memory used does not depend on the size of the module. The caller must
+// call cleanup when it is done with the *zip.Reader, to remove the file.
+func (c *Client) GetZip(ctx context.Context, requestedPath, requestedVersion string) (_ *zip.Reader, cleanup func(), err error) {
 	defer derrors.Wrap(&err, "proxy.Client.GetZip(ctx, %q, %q)", requestedPath, requestedVersion)
 
 	info, err := c.GetInfo(ctx, requestedPath, requestedVersion)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	bodyBytes, err := c.readBody(ctx, requestedPath, info.Version, "zip")
+	u, err := c.escapedURL(requestedPath, info.Version, "zip")
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	f, err := ioutil.TempFile("", "pkgsite-zip-")
+	if err != nil {
+		return nil, nil, err
+	}
+	removeFile := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	defer func() {
+		if err != nil {
+			removeFile()
+		}
+	}()
+	var size int64
+	err = c.executeRequest(ctx, u, func(body io.Reader) error {
+		// Read one byte more than the limit, to detect larger zips.
+		n, err := io.Copy(f, io.LimitReader(body, maxZipSize+1))
+		if err != nil {
+			return err
+		}
+		if n > maxZipSize {
+			return fmt.Errorf("zip is larger than %d bytes: %w", maxZipSize, derrors.BadModule)
+		}
+		size = n
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
-	zipReader, err := zip.NewReader(bytes.NewReader(bodyBytes), int64(len(bodyBytes)))
+	zipReader, err := zip.NewReader(f, size)
 	if err != nil {
-		return nil, fmt.Errorf("zip.NewReader: %v", err)
+		return nil, nil, fmt.Errorf("zip.NewReader: %v", err)
 	}
-	return zipReader, nil
+	return zipReader, removeFile, nil
 }
 
 func (c *Client) escapedURL(modulePath, version, suffix string) (_ string, err error) {
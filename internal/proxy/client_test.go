@@ -7,6 +7,10 @@ package proxy
 import (
 	"context"
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -204,10 +208,11 @@ func TestGetZip(t *testing.T) {
 		},
 	} {
 		t.Run(tc.path, func(t *testing.T) {
-			zipReader, err := client.GetZip(ctx, tc.path, tc.version)
+			zipReader, cleanup, err := client.GetZip(ctx, tc.path, tc.version)
 			if err != nil {
 				t.Fatal(err)
 			}
+			defer cleanup()
 
 			if len(zipReader.File) != len(tc.wantFiles) {
 				t.Errorf("GetZip(ctx, %q, %q) returned number of files: got %d, want %d",
@@ -238,11 +243,25 @@ func TestGetZipNonExist(t *testing.T) {
 
 	path := "my.mod/nonexistmodule"
 	version := "v1.0.0"
-	if _, err := client.GetZip(ctx, path, version); !errors.Is(err, derrors.NotFound) {
+	if _, _, err := client.GetZip(ctx, path, version); !errors.Is(err, derrors.NotFound) {
 		t.Errorf("got %v, want %v", err, derrors.NotFound)
 	}
 }
 
+func TestGetZipTooLarge(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	client, teardownProxy := SetupTestProxy(t, []*TestModule{sampleModule})
+	defer teardownProxy()
+
+	defer func(max int64) { maxZipSize = max }(maxZipSize)
+	maxZipSize = 100
+	if _, _, err := client.GetZip(ctx, sampleModule.ModulePath, sampleModule.Version); !errors.Is(err, derrors.BadModule) {
+		t.Errorf("got %v, want %v", err, derrors.BadModule)
+	}
+}
+
 func TestEncodedURL(t *testing.T) {
 	c := &Client{url: "u"}
 	for _, test := range []struct {
@@ -308,3 +327,95 @@ func TestEncodedURL(t *testing.T) {
 		}
 	}
 }
+
+func TestFileProxy(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	// Lay out the modules as in $GOMODCACHE/cache/download.
+	dir, err := ioutil.TempDir("", "proxy-file-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	modulePath := "example.com/Foo"
+	var versions []string
+	for _, v := range []string{"v1.0.0", "v1.1.0", "v1.2.0-pre"} {
+		m := cleanTestModule(t, &TestModule{
+			ModulePath: modulePath,
+			Version:    v,
+			Files:      map[string]string{"foo.go": "package foo"},
+		})
+		vdir := filepath.Join(dir, "example.com", "!foo", "@v")
+		if err := os.MkdirAll(vdir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for suffix, contents := range map[string][]byte{
+			"info": []byte(defaultInfo(v)),
+			"mod":  []byte(goMod(m)),
+			"zip":  m.zip,
+		} {
+			if err := ioutil.WriteFile(filepath.Join(vdir, v+"."+suffix), contents, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		versions = append(versions, v)
+	}
+	list := strings.Join(versions, "\n") + "\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "example.com", "!foo", "@v", "list"), []byte(list), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := New("file://" + filepath.ToSlash(dir) + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gotVersions, err := client.ListVersions(ctx, modulePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(versions, gotVersions); diff != "" {
+		t.Errorf("ListVersions(%q) diff:\n%s", modulePath, diff)
+	}
+
+	info, err := client.GetInfo(ctx, modulePath, internal.LatestVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := info.Version, "v1.1.0"; got != want {
+		t.Errorf("GetInfo(ctx, %q, %q): Version = %q, want %q", modulePath, internal.LatestVersion, got, want)
+	}
+
+	mod, err := client.GetMod(ctx, modulePath, "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(mod), defaultGoMod(modulePath); got != want {
+		t.Errorf("GetMod(ctx, %q, %q) = %q, want %q", modulePath, "v1.0.0", got, want)
+	}
+
+	zr, cleanup, err := client.GetZip(ctx, modulePath, "v1.2.0-pre")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	if len(zr.File) != 1 || zr.File[0].Name != modulePath+"@v1.2.0-pre/foo.go" {
+		t.Errorf("GetZip(ctx, %q, %q): got %d files, want only foo.go", modulePath, "v1.2.0-pre", len(zr.File))
+	}
+
+	if _, err := client.GetInfo(ctx, modulePath, "v3.0.0"); !errors.Is(err, derrors.NotFound) {
+		t.Errorf("GetInfo(ctx, %q, %q): got %v, want %v", modulePath, "v3.0.0", err, derrors.NotFound)
+	}
+	if _, err := client.GetInfo(ctx, "example.com/missing", internal.LatestVersion); !errors.Is(err, derrors.NotFound) {
+		t.Errorf("GetInfo(ctx, %q, %q): got %v, want %v", "example.com/missing", internal.LatestVersion, err, derrors.NotFound)
+	}
+}
+
+func TestNewFileURL(t *testing.T) {
+	for _, rawurl := range []string{"http://proxy.example.com", "file://host/dir"} {
+		if _, err := New(rawurl); err == nil {
+			t.Errorf("New(%q): got nil error, want error", rawurl)
+		}
+	}
+}
@@ -18,9 +18,9 @@ import (
 
 	"golang.org/x/mod/semver"
 	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/datasource"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/fetch"
-	"golang.org/x/pkgsite/internal/licenses"
 	"golang.org/x/pkgsite/internal/proxy"
 	"golang.org/x/pkgsite/internal/source"
 	"golang.org/x/pkgsite/internal/stdlib"
@@ -31,18 +31,22 @@ var _ internal.DataSource = (*DataSource)(nil)
 
 // New returns a new direct proxy datasource.
 func New(proxyClient *proxy.Client) *DataSource {
-	return &DataSource{
+	ds := &DataSource{
 		proxyClient:          proxyClient,
 		sourceClient:         source.NewClient(1 * time.Minute),
 		versionCache:         make(map[versionKey]*versionEntry),
 		modulePathToVersions: make(map[string][]string),
 		packagePathToModules: make(map[string][]string),
 	}
+	ds.ModuleDataSource = datasource.NewModuleDataSource(ds.getModule, ds.getModuleForPath)
+	return ds
 }
 
 // DataSource implements the frontend.DataSource interface, by querying a
 // module proxy directly and caching the results in memory.
 type DataSource struct {
+	*datasource.ModuleDataSource
+
 	proxyClient  *proxy.Client
 	sourceClient *source.Client
 
@@ -67,128 +71,6 @@ type versionEntry struct {
 	err    error
 }
 
-// LegacyGetDirectory returns packages contained in the given subdirectory of a module version.
-func (ds *DataSource) LegacyGetDirectory(ctx context.Context, dirPath, modulePath, version string, _ internal.FieldSet) (_ *internal.LegacyDirectory, err error) {
-	defer derrors.Wrap(&err, "LegacyGetDirectory(%q, %q, %q)", dirPath, modulePath, version)
-
-	var info *proxy.VersionInfo
-	if modulePath == internal.UnknownModulePath {
-		modulePath, info, err = ds.findModule(ctx, dirPath, version)
-		if err != nil {
-			return nil, err
-		}
-		version = info.Version
-	}
-	v, err := ds.getModule(ctx, modulePath, version)
-	if err != nil {
-		return nil, err
-	}
-	return &internal.LegacyDirectory{
-		LegacyModuleInfo: internal.LegacyModuleInfo{ModuleInfo: v.ModuleInfo},
-		Path:             dirPath,
-		Packages:         v.LegacyPackages,
-	}, nil
-}
-
-// GetDirectoryNew returns information about a directory at a path.
-func (ds *DataSource) GetDirectoryNew(ctx context.Context, dirPath, modulePath, version string) (_ *internal.VersionedDirectory, err error) {
-	m, err := ds.getModule(ctx, modulePath, version)
-	if err != nil {
-		return nil, err
-	}
-	return &internal.VersionedDirectory{
-		ModuleInfo: m.ModuleInfo,
-		DirectoryNew: internal.DirectoryNew{
-			DirectoryMeta: internal.DirectoryMeta{
-				Path:   dirPath,
-				V1Path: internal.V1Path(modulePath, strings.TrimPrefix(dirPath, modulePath+"/")),
-			},
-		},
-	}, nil
-}
-
-// GetImports returns package imports as extracted from the module zip.
-func (ds *DataSource) GetImports(ctx context.Context, pkgPath, modulePath, version string) (_ []string, err error) {
-	defer derrors.Wrap(&err, "GetImports(%q, %q, %q)", pkgPath, modulePath, version)
-	vp, err := ds.LegacyGetPackage(ctx, pkgPath, modulePath, version)
-	if err != nil {
-		return nil, err
-	}
-	return vp.Imports, nil
-}
-
-// LegacyGetModuleLicenses returns root-level licenses detected within the module zip
-// for modulePath and version.
-func (ds *DataSource) LegacyGetModuleLicenses(ctx context.Context, modulePath, version string) (_ []*licenses.License, err error) {
-	defer derrors.Wrap(&err, "LegacyGetModuleLicenses(%q, %q)", modulePath, version)
-	v, err := ds.getModule(ctx, modulePath, version)
-	if err != nil {
-		return nil, err
-	}
-	var filtered []*licenses.License
-	for _, lic := range v.Licenses {
-		if !strings.Contains(lic.FilePath, "/") {
-			filtered = append(filtered, lic)
-		}
-	}
-	return filtered, nil
-}
-
-// LegacyGetPackage returns a LegacyVersionedPackage for the given pkgPath and version. If
-// such a package exists in the cache, it will be returned without querying the
-// proxy. Otherwise, the proxy is queried to find the longest module path at
-// that version containing the package.
-func (ds *DataSource) LegacyGetPackage(ctx context.Context, pkgPath, modulePath, version string) (_ *internal.LegacyVersionedPackage, err error) {
-	defer derrors.Wrap(&err, "LegacyGetPackage(%q, %q)", pkgPath, version)
-
-	var m *internal.Module
-	if modulePath != internal.UnknownModulePath {
-		m, err = ds.getModule(ctx, modulePath, version)
-	} else {
-		m, err = ds.getPackageVersion(ctx, pkgPath, version)
-	}
-	if err != nil {
-		return nil, err
-	}
-	return packageFromVersion(pkgPath, m)
-}
-
-// LegacyGetPackageLicenses returns the Licenses that apply to pkgPath within the
-// module version specified by modulePath and version.
-func (ds *DataSource) LegacyGetPackageLicenses(ctx context.Context, pkgPath, modulePath, version string) (_ []*licenses.License, err error) {
-	defer derrors.Wrap(&err, "LegacyGetPackageLicenses(%q, %q, %q)", pkgPath, modulePath, version)
-	v, err := ds.getModule(ctx, modulePath, version)
-	if err != nil {
-		return nil, err
-	}
-	for _, p := range v.LegacyPackages {
-		if p.Path == pkgPath {
-			var lics []*licenses.License
-			for _, lmd := range p.Licenses {
-				// lmd is just license metadata, the version has the actual licenses.
-				for _, lic := range v.Licenses {
-					if lic.FilePath == lmd.FilePath {
-						lics = append(lics, lic)
-						break
-					}
-				}
-			}
-			return lics, nil
-		}
-	}
-	return nil, fmt.Errorf("package %s is missing from module %s: %w", pkgPath, modulePath, derrors.NotFound)
-}
-
-// LegacyGetPackagesInModule returns LegacyPackages contained in the module zip corresponding to modulePath and version.
-func (ds *DataSource) LegacyGetPackagesInModule(ctx context.Context, modulePath, version string) (_ []*internal.LegacyPackage, err error) {
-	defer derrors.Wrap(&err, "LegacyGetPackagesInModule(%q, %q)", modulePath, version)
-	v, err := ds.getModule(ctx, modulePath, version)
-	if err != nil {
-		return nil, err
-	}
-	return v.LegacyPackages, nil
-}
-
 // GetPseudoVersionsForModule returns versions from the the proxy /list
 // endpoint, if they are pseudoversions. Otherwise, it returns an empty slice.
 func (ds *DataSource) GetPseudoVersionsForModule(ctx context.Context, modulePath string) (_ []*internal.ModuleInfo, err error) {
@@ -219,28 +101,6 @@ func (ds *DataSource) GetTaggedVersionsForPackageSeries(ctx context.Context, pkg
 	return ds.listPackageVersions(ctx, pkgPath, false)
 }
 
-// GetModuleInfo returns the ModuleInfo as fetched from the proxy for module
-// version specified by modulePath and version.
-func (ds *DataSource) GetModuleInfo(ctx context.Context, modulePath, version string) (_ *internal.ModuleInfo, err error) {
-	defer derrors.Wrap(&err, "GetModuleInfo(%q, %q)", modulePath, version)
-	m, err := ds.getModule(ctx, modulePath, version)
-	if err != nil {
-		return nil, err
-	}
-	return &m.ModuleInfo, nil
-}
-
-// LegacyGetModuleInfo returns the LegacyModuleInfo as fetched from the proxy for module
-// version specified by modulePath and version.
-func (ds *DataSource) LegacyGetModuleInfo(ctx context.Context, modulePath, version string) (_ *internal.LegacyModuleInfo, err error) {
-	defer derrors.Wrap(&err, "LegacyGetModuleInfo(%q, %q)", modulePath, version)
-	m, err := ds.getModule(ctx, modulePath, version)
-	if err != nil {
-		return nil, err
-	}
-	return &m.LegacyModuleInfo, nil
-}
-
 // getModule retrieves a version from the cache, or failing that queries and
 // processes the version from the proxy.
 func (ds *DataSource) getModule(ctx context.Context, modulePath, version string) (_ *internal.Module, err error) {
@@ -263,7 +123,7 @@ func (ds *DataSource) getModule(ctx context.Context, modulePath, version string)
 		}
 		v = stdlib.VersionForTag(v)
 	}
-	res := fetch.FetchModule(ctx, modulePath, v, ds.proxyClient, ds.sourceClient)
+	res := fetch.FetchModule(ctx, modulePath, v, ds.proxyClient, ds.sourceClient, fetch.Options{})
 	m := res.Module
 	ds.versionCache[key] = &versionEntry{module: m, err: err}
 	if res.Error != nil {
@@ -302,6 +162,16 @@ func (ds *DataSource) getModule(ctx context.Context, modulePath, version string)
 	return m, nil
 }
 
+// getModuleForPath returns the module with modulePath, or if it is
+// internal.UnknownModulePath, the longest module at version containing the
+// package or directory with the given path.
+func (ds *DataSource) getModuleForPath(ctx context.Context, fullPath, modulePath, version string) (*internal.Module, error) {
+	if modulePath == internal.UnknownModulePath {
+		return ds.getPackageVersion(ctx, fullPath, version)
+	}
+	return ds.getModule(ctx, modulePath, version)
+}
+
 // findModule finds the longest module path containing the given package path,
 // using the given finder func and iteratively testing parent directories of
 // the import path. It performs no testing as to whether the specified module
@@ -416,49 +286,3 @@ func (ds *DataSource) findModulePathForPackage(pkgPath, version string) (string,
 	}
 	return "", false
 }
-
-// packageFromVersion extracts the LegacyVersionedPackage for pkgPath from the
-// Version payload.
-func packageFromVersion(pkgPath string, m *internal.Module) (_ *internal.LegacyVersionedPackage, err error) {
-	defer derrors.Wrap(&err, "packageFromVersion(%q, ...)", pkgPath)
-	for _, p := range m.LegacyPackages {
-		if p.Path == pkgPath {
-			return &internal.LegacyVersionedPackage{
-				LegacyPackage:    *p,
-				LegacyModuleInfo: m.LegacyModuleInfo,
-			}, nil
-		}
-	}
-	return nil, fmt.Errorf("package missing from module %s: %w", m.ModulePath, derrors.NotFound)
-}
-
-// GetExperiments is unimplemented.
-func (*DataSource) GetExperiments(ctx context.Context) ([]*internal.Experiment, error) {
-	return nil, nil
-}
-
-// GetPathInfo returns information about the given path.
-func (ds *DataSource) GetPathInfo(ctx context.Context, path, inModulePath, inVersion string) (outModulePath, outVersion string, isPackage bool, err error) {
-	defer derrors.Wrap(&err, "GetPathInfo(%q, %q, %q)", path, inModulePath, inVersion)
-
-	var info *proxy.VersionInfo
-	if inModulePath == internal.UnknownModulePath {
-		inModulePath, info, err = ds.findModule(ctx, path, inVersion)
-		if err != nil {
-			return "", "", false, err
-		}
-		inVersion = info.Version
-	}
-	m, err := ds.getModule(ctx, inModulePath, inVersion)
-	if err != nil {
-		return "", "", false, err
-	}
-	isPackage = false
-	for _, p := range m.LegacyPackages {
-		if p.Path == path {
-			isPackage = true
-			break
-		}
-	}
-	return m.ModulePath, m.Version, isPackage, nil
-}
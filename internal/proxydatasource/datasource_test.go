@@ -59,6 +59,9 @@ var (
 		IsRedistributable: true,
 		GOOS:              "linux",
 		GOARCH:            "amd64",
+		Symbols: []*internal.Symbol{
+			{Name: "OK", Kind: internal.SymbolKindConstant, Synopsis: "const OK = http.StatusOK"},
+		},
 	}
 	wantModuleInfo = internal.ModuleInfo{
 		ModulePath:        "foo.com/bar",
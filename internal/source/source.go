@@ -58,6 +58,24 @@ func (i *Info) RepoURL() string {
 	return i.repoURL
 }
 
+// Commit returns the tag or ID of the commit that the URLs of i refer to.
+func (i *Info) Commit() string {
+	if i == nil {
+		return ""
+	}
+	return i.commit
+}
+
+// WithCommit returns a copy of i whose URLs refer to the given commit.
+func (i *Info) WithCommit(commit string) *Info {
+	if i == nil {
+		return nil
+	}
+	c := *i
+	c.commit = commit
+	return &c
+}
+
 // ModuleURL returns a URL for the home page of the module.
 func (i *Info) ModuleURL() string {
 	return i.DirectoryURL("")
@@ -205,7 +205,7 @@ func setupQueue(ctx context.Context, t *testing.T, proxyModules []*proxy.TestMod
 	sourceClient := source.NewClient(1 * time.Second)
 	q := queue.NewInMemory(ctx, 1, experimentNames,
 		func(ctx context.Context, mpath, version string) (int, error) {
-			return frontend.FetchAndUpdateState(ctx, mpath, version, proxyClient, sourceClient, testDB)
+			return frontend.FetchAndUpdateState(ctx, mpath, version, proxyClient, sourceClient, fetch.Options{}, testDB)
 		})
 	return q, func() {
 		teardown()
@@ -224,7 +224,7 @@ func processVersions(ctx context.Context, t *testing.T, testModules []*proxy.Tes
 
 func fetchAndInsertModule(ctx context.Context, t *testing.T, tm *proxy.TestModule, proxyClient *proxy.Client) {
 	sourceClient := source.NewClient(1 * time.Second)
-	res := fetch.FetchModule(ctx, tm.ModulePath, tm.Version, proxyClient, sourceClient)
+	res := fetch.FetchModule(ctx, tm.ModulePath, tm.Version, proxyClient, sourceClient, fetch.Options{})
 	if res.Error != nil {
 		t.Fatal(res.Error)
 	}
@@ -19,6 +19,7 @@ import (
 	"github.com/google/safehtml/template"
 	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/config"
+	"golang.org/x/pkgsite/internal/fetch"
 	"golang.org/x/pkgsite/internal/frontend"
 	"golang.org/x/pkgsite/internal/index"
 	"golang.org/x/pkgsite/internal/postgres"
@@ -79,7 +80,7 @@ func TestEndToEndProcessing(t *testing.T) {
 	// back to worker, rather than calling fetch itself.
 	sourceClient := source.NewClient(1 * time.Second)
 	queue := queue.NewInMemory(ctx, 10, nil, func(ctx context.Context, mpath, version string) (int, error) {
-		return worker.FetchAndUpdateState(ctx, mpath, version, proxyClient, sourceClient, testDB, "test")
+		return worker.FetchAndUpdateState(ctx, mpath, version, proxyClient, sourceClient, fetch.Options{}, testDB, "test")
 	})
 	workerServer, err := worker.NewServer(&config.Config{}, worker.ServerConfig{
 		DB:                   testDB,
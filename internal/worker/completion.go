@@ -159,9 +159,13 @@ func updateRedisIndexes(ctx context.Context, db *database.DB, redisClient *redis
 	//    document data used here.
 	//  - hold two copies of all search results in memory while building the
 	//    redis pipeline below.
+	//
+	// Completions are for packages, so the search documents of modules
+	// without a root package are skipped.
 	query := `
 		SELECT package_path, module_path, version, imported_by_count
-		FROM search_documents`
+		FROM search_documents
+		WHERE NOT is_module`
 	if err := db.RunQuery(ctx, query, processRow); err != nil {
 		return err
 	}
@@ -95,7 +95,7 @@ func TestFetchAndUpdateState_NotFound(t *testing.T) {
 	}
 
 	// Fetch a module@version that the proxy serves successfully.
-	if _, err := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, testDB, "appVersionLabel"); err != nil {
+	if _, err := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, fetch.Options{}, testDB, "appVersionLabel"); err != nil {
 		t.Fatal(err)
 	}
 
@@ -132,8 +132,8 @@ func TestFetchAndUpdateState_NotFound(t *testing.T) {
 	defer teardownProxy2()
 
 	// Now fetch it again.
-	if code, _ := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, testDB, "appVersionLabel"); code != http.StatusNotFound {
-		t.Fatalf("FetchAndUpdateState(ctx, %q, %q, proxyClient, sourceClient, testDB): got code %d, want 404/410", modulePath, version, code)
+	if code, _ := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, fetch.Options{}, testDB, "appVersionLabel"); code != http.StatusNotFound {
+		t.Fatalf("FetchAndUpdateState(ctx, %q, %q, proxyClient, sourceClient, fetch.Options{}, testDB): got code %d, want 404/410", modulePath, version, code)
 	}
 
 	// The new state should have a status of Not Found.
@@ -193,7 +193,7 @@ func TestFetchAndUpdateState_Excluded(t *testing.T) {
 
 func checkModuleNotFound(t *testing.T, ctx context.Context, modulePath, version string, proxyClient *proxy.Client, sourceClient *source.Client, wantCode int, wantErr error) {
 	t.Helper()
-	code, err := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, testDB, "appVersionLabel")
+	code, err := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, fetch.Options{}, testDB, "appVersionLabel")
 	if code != wantCode || !errors.Is(err, wantErr) {
 		t.Fatalf("got %d, %v; want %d, Is(err, %v)", code, err, wantCode, wantErr)
 	}
@@ -230,7 +230,7 @@ func TestFetchAndUpdateState_BadRequestedVersion(t *testing.T) {
 		want       = http.StatusNotFound
 	)
 
-	code, _ := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, testDB, "appVersionLabel")
+	code, _ := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, fetch.Options{}, testDB, "appVersionLabel")
 	if code != want {
 		t.Fatalf("got code %d, want %d", code, want)
 	}
@@ -264,7 +264,7 @@ func TestFetchAndUpdateState_Incomplete(t *testing.T) {
 		want       = hasIncompletePackagesCode
 	)
 
-	code, err := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, testDB, "appVersionLabel")
+	code, err := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, fetch.Options{}, testDB, "appVersionLabel")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -336,7 +336,7 @@ func TestFetchAndUpdateState_Mismatch(t *testing.T) {
 	defer teardownProxy()
 	sourceClient := source.NewClient(sourceTimeout)
 
-	code, err := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, testDB, "appVersionLabel")
+	code, err := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, fetch.Options{}, testDB, "appVersionLabel")
 	wantErr := derrors.AlternativeModule
 	wantCode := derrors.ToHTTPStatus(wantErr)
 	if code != wantCode || !errors.Is(err, wantErr) {
@@ -401,7 +401,7 @@ func TestFetchAndUpdateState_DeleteOlder(t *testing.T) {
 	defer teardownProxy()
 	sourceClient := source.NewClient(sourceTimeout)
 
-	if _, err := FetchAndUpdateState(ctx, modulePath, olderVersion, proxyClient, sourceClient, testDB, "appVersionLabel"); err != nil {
+	if _, err := FetchAndUpdateState(ctx, modulePath, olderVersion, proxyClient, sourceClient, fetch.Options{}, testDB, "appVersionLabel"); err != nil {
 		t.Fatal(err)
 	}
 	gotModule, gotVersion, gotFound := postgres.GetFromSearchDocuments(ctx, t, testDB, modulePath+"/foo")
@@ -409,7 +409,7 @@ func TestFetchAndUpdateState_DeleteOlder(t *testing.T) {
 		t.Fatalf("got (%q, %q, %t), want (%q, %q, true)", gotModule, gotVersion, gotFound, modulePath, olderVersion)
 	}
 
-	code, _ := FetchAndUpdateState(ctx, modulePath, mismatchVersion, proxyClient, sourceClient, testDB, "appVersionLabel")
+	code, _ := FetchAndUpdateState(ctx, modulePath, mismatchVersion, proxyClient, sourceClient, fetch.Options{}, testDB, "appVersionLabel")
 	if want := derrors.ToHTTPStatus(derrors.AlternativeModule); code != want {
 		t.Fatalf("got %d, want %d", code, want)
 	}
@@ -449,7 +449,7 @@ func TestSkipIncompletePackage(t *testing.T) {
 	defer teardownProxy()
 	sourceClient := source.NewClient(sourceTimeout)
 
-	code, err := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, testDB, "appVersionLabel")
+	code, err := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, fetch.Options{}, testDB, "appVersionLabel")
 	if err != nil {
 		t.Fatalf("FetchAndUpdateState(%q, %q, %v, %v, %v): %v", modulePath, version, proxyClient, sourceClient, testDB, err)
 	}
@@ -517,7 +517,7 @@ func TestTrimLargeCode(t *testing.T) {
 	defer teardownProxy()
 	sourceClient := source.NewClient(sourceTimeout)
 
-	code, err := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, testDB, "appVersionLabel")
+	code, err := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, fetch.Options{}, testDB, "appVersionLabel")
 	if err != nil {
 		t.Fatalf("FetchAndUpdateState(%q, %q, %v, %v, %v): %v", modulePath, version, proxyClient, sourceClient, testDB, err)
 	}
@@ -556,7 +556,7 @@ func TestFetch_V1Path(t *testing.T) {
 	})
 	defer tearDown()
 	sourceClient := source.NewClient(sourceTimeout)
-	if _, err := FetchAndUpdateState(ctx, "my.mod/foo", "v1.0.0", proxyClient, sourceClient, testDB, "appVersionLabel"); err != nil {
+	if _, err := FetchAndUpdateState(ctx, "my.mod/foo", "v1.0.0", proxyClient, sourceClient, fetch.Options{}, testDB, "appVersionLabel"); err != nil {
 		t.Fatalf("FetchAndUpdateState: %v", err)
 	}
 	pkg, err := testDB.LegacyGetPackage(ctx, "my.mod/foo", internal.UnknownModulePath, "v1.0.0")
@@ -606,7 +606,7 @@ func TestReFetch(t *testing.T) {
 	})
 	defer teardownProxy()
 	sourceClient := source.NewClient(sourceTimeout)
-	if _, err := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, testDB, "appVersionLabel"); err != nil {
+	if _, err := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, fetch.Options{}, testDB, "appVersionLabel"); err != nil {
 		t.Fatalf("FetchAndUpdateState(%q, %q, %v, %v, %v): %v", modulePath, version, proxyClient, sourceClient, testDB, err)
 	}
 
@@ -624,7 +624,7 @@ func TestReFetch(t *testing.T) {
 	})
 	defer teardownProxy()
 
-	if _, err := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, testDB, "appVersionLabel"); err != nil {
+	if _, err := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, fetch.Options{}, testDB, "appVersionLabel"); err != nil {
 		t.Fatalf("FetchAndUpdateState(%q, %q, %v, %v, %v): %v", modulePath, version, proxyClient, sourceClient, testDB, err)
 	}
 	want := &internal.LegacyVersionedPackage{
@@ -672,7 +672,7 @@ func TestReFetch(t *testing.T) {
 		},
 	})
 	defer teardownProxy()
-	if _, err := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, testDB, "appVersionLabel"); !errors.Is(err, derrors.DBModuleInsertInvalid) {
+	if _, err := FetchAndUpdateState(ctx, modulePath, version, proxyClient, sourceClient, fetch.Options{}, testDB, "appVersionLabel"); !errors.Is(err, derrors.DBModuleInsertInvalid) {
 		t.Fatalf("FetchAndUpdateState(%q, %q, %v, %v, %v): %v", modulePath, version, proxyClient, sourceClient, testDB, err)
 	}
 }
@@ -1048,7 +1048,7 @@ func TestFetchAndInsertModule(t *testing.T) {
 
 			sourceClient := source.NewClient(sourceTimeout)
 
-			if _, err := FetchAndUpdateState(ctx, test.modulePath, test.version, proxyClient, sourceClient, testDB, "appVersionLabel"); err != nil {
+			if _, err := FetchAndUpdateState(ctx, test.modulePath, test.version, proxyClient, sourceClient, fetch.Options{}, testDB, "appVersionLabel"); err != nil {
 				t.Fatalf("FetchAndUpdateState(%q, %q, %v, %v, %v): %v", test.modulePath, test.version, proxyClient, sourceClient, testDB, err)
 			}
 
@@ -1103,7 +1103,7 @@ func TestFetchAndInsertModuleTimeout(t *testing.T) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 0)
 	defer cancel()
-	_, err := FetchAndUpdateState(ctx, name, version, proxyClient, sourceClient, testDB, "appVersionLabel")
+	_, err := FetchAndUpdateState(ctx, name, version, proxyClient, sourceClient, fetch.Options{}, testDB, "appVersionLabel")
 	if err == nil || !strings.Contains(err.Error(), wantErrString) {
 		t.Fatalf("FetchAndUpdateState(%q, %q, %v, %v, %v) returned error %v, want error containing %q",
 			name, version, proxyClient, sourceClient, testDB, err, wantErrString)
@@ -24,6 +24,7 @@ import (
 	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/config"
 	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/fetch"
 	"golang.org/x/pkgsite/internal/index"
 	"golang.org/x/pkgsite/internal/log"
 	"golang.org/x/pkgsite/internal/middleware"
@@ -41,6 +42,7 @@ type Server struct {
 	indexClient          *index.Client
 	proxyClient          *proxy.Client
 	sourceClient         *source.Client
+	fetchOptions         fetch.Options
 	redisHAClient        *redis.Client
 	redisCacheClient     *redis.Client
 	db                   *postgres.DB
@@ -57,6 +59,7 @@ type ServerConfig struct {
 	IndexClient          *index.Client
 	ProxyClient          *proxy.Client
 	SourceClient         *source.Client
+	FetchOptions         fetch.Options
 	RedisHAClient        *redis.Client
 	RedisCacheClient     *redis.Client
 	Queue                queue.Queue
@@ -80,6 +83,7 @@ func NewServer(cfg *config.Config, scfg ServerConfig) (_ *Server, err error) {
 		indexClient:          scfg.IndexClient,
 		proxyClient:          scfg.ProxyClient,
 		sourceClient:         scfg.SourceClient,
+		fetchOptions:         scfg.FetchOptions,
 		redisHAClient:        scfg.RedisHAClient,
 		redisCacheClient:     scfg.RedisCacheClient,
 		queue:                scfg.Queue,
@@ -121,6 +125,17 @@ func (s *Server) Install(handle func(string, http.Handler)) {
 	// This endpoint is intended to be invoked periodically by a scheduler.
 	handle("/update-imported-by-count", rmw(s.errorHandler(s.handleUpdateImportedByCount)))
 
+	// scheduled: update-recent-version-counts updates the num_recent_versions
+	// for packages in search_documents, which is used to rank search results.
+	// This endpoint is intended to be invoked periodically by a scheduler.
+	handle("/update-recent-version-counts", rmw(s.errorHandler(s.handleUpdateRecentVersionCounts)))
+
+	// scheduled: delete-unused-rendered-packages deletes the packages in the
+	// render cache that were rendered by an earlier version of the worker, or
+	// have not been reused for renderCacheRetention.
+	// This endpoint is intended to be invoked periodically by a scheduler.
+	handle("/delete-unused-rendered-packages", rmw(s.errorHandler(s.handleDeleteUnusedRenderedPackages)))
+
 	// scheduled: download search document data and update the redis sorted
 	// set(s) used in auto-completion.
 	handle("/update-redis-indexes", rmw(s.errorHandler(s.handleUpdateRedisIndexes)))
@@ -173,6 +188,19 @@ func (s *Server) Install(handle func(string, http.Handler)) {
 	// "before" query parameter.
 	handle("/repopulate-search-documents", rmw(s.errorHandler(s.handleRepopulateSearchDocuments)))
 
+	// manual: search-boosts lists the search boosts, which multiply the search
+	// scores of the packages under a prefix.
+	handle("/search-boosts", rmw(s.errorHandler(s.handleSearchBoosts)))
+
+	// manual: set-search-boost sets the multiplier of the search boost for the
+	// "prefix" query parameter to the "multiplier" query parameter, recording
+	// the "user" and "reason" query parameters. It takes effect immediately.
+	handle("/set-search-boost", rmw(s.errorHandler(s.handleSetSearchBoost)))
+
+	// manual: delete-search-boost deletes the search boost for the "prefix"
+	// query parameter.
+	handle("/delete-search-boost", rmw(s.errorHandler(s.handleDeleteSearchBoost)))
+
 	// manual: clear-cache clears the redis cache.
 	handle("/clear-cache", rmw(s.errorHandler(s.clearCache)))
 
@@ -193,6 +221,77 @@ func (s *Server) handleUpdateImportedByCount(w http.ResponseWriter, r *http.Requ
 	return nil
 }
 
+// handleUpdateRecentVersionCounts updates num_recent_versions for all packages.
+func (s *Server) handleUpdateRecentVersionCounts(w http.ResponseWriter, r *http.Request) error {
+	n, err := s.db.UpdateSearchDocumentsRecentVersionCounts(r.Context())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "updated %d packages", n)
+	return nil
+}
+
+// renderCacheRetention is how long a package in the render cache is kept
+// after it was last used.
+const renderCacheRetention = 30 * 24 * time.Hour
+
+func (s *Server) handleDeleteUnusedRenderedPackages(w http.ResponseWriter, r *http.Request) error {
+	n, err := s.db.DeleteUnusedRenderedPackages(r.Context(), fetch.RendererVersion, time.Now().Add(-renderCacheRetention))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "deleted %d rendered packages", n)
+	return nil
+}
+
+// handleSearchBoosts lists the search boosts, one per line.
+func (s *Server) handleSearchBoosts(w http.ResponseWriter, r *http.Request) error {
+	boosts, err := s.db.GetSearchBoosts(r.Context())
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, b := range boosts {
+		fmt.Fprintf(w, "%s\t%g\t%s\t%s\n", b.Prefix, b.Multiplier, b.CreatedBy, b.Reason)
+	}
+	return nil
+}
+
+// handleSetSearchBoost inserts or updates a search boost.
+func (s *Server) handleSetSearchBoost(w http.ResponseWriter, r *http.Request) error {
+	multiplier, err := strconv.ParseFloat(r.FormValue("multiplier"), 64)
+	if err != nil {
+		return &serverError{http.StatusBadRequest, fmt.Errorf("invalid multiplier: %v", err)}
+	}
+	b := &internal.SearchBoost{
+		Prefix:     r.FormValue("prefix"),
+		Multiplier: multiplier,
+		CreatedBy:  r.FormValue("user"),
+		Reason:     r.FormValue("reason"),
+	}
+	if err := s.db.SetSearchBoost(r.Context(), b); err != nil {
+		if errors.Is(err, derrors.InvalidArgument) {
+			return &serverError{http.StatusBadRequest, err}
+		}
+		return err
+	}
+	fmt.Fprintf(w, "Set search boost for %q to %g", b.Prefix, b.Multiplier)
+	return nil
+}
+
+// handleDeleteSearchBoost deletes a search boost.
+func (s *Server) handleDeleteSearchBoost(w http.ResponseWriter, r *http.Request) error {
+	prefix := r.FormValue("prefix")
+	if err := s.db.DeleteSearchBoost(r.Context(), prefix); err != nil {
+		if errors.Is(err, derrors.NotFound) {
+			return &serverError{http.StatusNotFound, err}
+		}
+		return err
+	}
+	fmt.Fprintf(w, "Deleted search boost for %q", prefix)
+	return nil
+}
+
 // handleRepopulateSearchDocuments repopulates every row in the search_documents table
 // that was last updated before the given time.
 func (s *Server) handleRepopulateSearchDocuments(w http.ResponseWriter, r *http.Request) error {
@@ -262,7 +361,7 @@ func (s *Server) doFetch(r *http.Request) (string, int) {
 		return err.Error(), http.StatusBadRequest
 	}
 
-	code, err := FetchAndUpdateState(r.Context(), modulePath, version, s.proxyClient, s.sourceClient, s.db, s.cfg.AppVersionLabel())
+	code, err := FetchAndUpdateState(r.Context(), modulePath, version, s.proxyClient, s.sourceClient, s.fetchOptions, s.db, s.cfg.AppVersionLabel())
 	if err != nil {
 		return err.Error(), code
 	}
@@ -389,6 +488,7 @@ func (s *Server) doStatusPage(w http.ResponseWriter, r *http.Request) (_ string,
 		stats                   *postgres.VersionStats
 		experiments             []*internal.Experiment
 		excluded                []string
+		boosts                  []*internal.SearchBoost
 	)
 	type annotation struct {
 		error
@@ -443,6 +543,14 @@ func (s *Server) doStatusPage(w http.ResponseWriter, r *http.Request) (_ string,
 		}
 		return nil
 	})
+	g.Go(func() error {
+		var err error
+		boosts, err = s.db.GetSearchBoosts(ctx)
+		if err != nil {
+			return annotation{err, "error fetching search boosts"}
+		}
+		return nil
+	})
 	if err := g.Wait(); err != nil {
 		var e annotation
 		if errors.As(err, &e) {
@@ -486,6 +594,7 @@ func (s *Server) doStatusPage(w http.ResponseWriter, r *http.Request) (_ string,
 		Next, Recent, RecentFailures []*internal.ModuleVersionState
 		Experiments                  []*internal.Experiment
 		Excluded                     []string
+		SearchBoosts                 []*internal.SearchBoost
 	}{
 		Config:          s.cfg,
 		Env:             env,
@@ -497,6 +606,7 @@ func (s *Server) doStatusPage(w http.ResponseWriter, r *http.Request) (_ string,
 		RecentFailures:  failures,
 		Experiments:     experiments,
 		Excluded:        excluded,
+		SearchBoosts:    boosts,
 	}
 	var buf bytes.Buffer
 	if err := s.indexTemplate.Execute(&buf, page); err != nil {
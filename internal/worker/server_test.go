@@ -20,6 +20,7 @@ import (
 	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/config"
 	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/fetch"
 	"golang.org/x/pkgsite/internal/index"
 	"golang.org/x/pkgsite/internal/postgres"
 	"golang.org/x/pkgsite/internal/proxy"
@@ -176,7 +177,7 @@ func TestWorker(t *testing.T) {
 
 			// Use 10 workers to have parallelism consistent with the worker binary.
 			q := queue.NewInMemory(ctx, 10, nil, func(ctx context.Context, mpath, version string) (int, error) {
-				return FetchAndUpdateState(ctx, mpath, version, proxyClient, sourceClient, testDB, "")
+				return FetchAndUpdateState(ctx, mpath, version, proxyClient, sourceClient, fetch.Options{}, testDB, "")
 			})
 
 			s, err := NewServer(&config.Config{}, ServerConfig{
@@ -0,0 +1,23 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE symbols (
+    package_path text NOT NULL REFERENCES search_documents(package_path) ON DELETE CASCADE,
+    module_path text NOT NULL,
+    name text NOT NULL,
+    parent_name text DEFAULT '' NOT NULL, -- empty except for methods
+    kind text NOT NULL,
+    synopsis text NOT NULL,
+    PRIMARY KEY (package_path, parent_name, name)
+);
+COMMENT ON TABLE symbols IS
+'TABLE symbols contains the exported identifiers declared in the latest version of each package in search_documents. It is used to search for symbols.';
+
+CREATE INDEX idx_symbols_lower_name ON symbols (lower(name));
+COMMENT ON INDEX idx_symbols_lower_name IS
+'INDEX idx_symbols_lower_name is used for case-insensitive searches by symbol name.';
+
+END;
@@ -0,0 +1,16 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+-- pg_trgm is used to find search documents whose package path or name is
+-- similar to a search query that has few or no exact matches.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_search_documents_package_path_trgm ON search_documents
+    USING gin (package_path gin_trgm_ops);
+CREATE INDEX idx_search_documents_name_trgm ON search_documents
+    USING gin (name gin_trgm_ops);
+
+END;
@@ -0,0 +1,11 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+DROP FUNCTION popular_search(
+	text, integer, double precision, timestamp with time zone, text, real, real,
+	text[], text, text, boolean, version_type, real, real, real);
+
+END;
@@ -0,0 +1,237 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+-- Restore the definitions of popular_search from
+-- 000027_group_search_by_series.up.sql and 000028_add_search_cursor.up.sql.
+
+CREATE OR REPLACE FUNCTION popular_search(
+	rawquery text, lim integer, off integer, redist_factor real, go_mod_factor real,
+	license_filter text[], module_filter text, imports_filter text,
+	go_mod_filter boolean, version_type_filter version_type,
+	recency_weight real, recency_half_life real, active_versions real) RETURNS SETOF search_result
+    LANGUAGE plpgsql
+    AS $$
+	DECLARE cur CURSOR(query TSQUERY) FOR
+		SELECT
+			package_path,
+			module_path,
+			version,
+			commit_time,
+			imported_by_count,
+			(
+				-- default D, C, B, A weights are {0.1, 0.2, 0.4, 1.0}
+				ts_rank('{0.1, 0.2, 1.0, 1.0}', tsv_search_tokens, query) *
+				ln(exp(1)+imported_by_count) *
+				CASE WHEN redistributable THEN 1 ELSE redist_factor END *
+				CASE WHEN COALESCE(has_go_mod, true) THEN 1 ELSE go_mod_factor END *
+				-- The recency factor is at most 1, so that the early exit below remains
+				-- correct.
+				(1 - recency_weight * (1 - LEAST(1, GREATEST(
+					power(0.5, EXTRACT(EPOCH FROM (CURRENT_TIMESTAMP - commit_time)) /
+						(86400 * recency_half_life)),
+					num_recent_versions / active_versions)))) *
+				CASE WHEN tsv_search_tokens @@ query THEN 1 ELSE 0 END *
+				-- As with the tsv_search_tokens check above, documents that do not
+				-- match the filters have their score annihilated rather than being
+				-- excluded by a where clause, so that the query planner continues to
+				-- use the popular document index.
+				CASE WHEN
+					(license_filter IS NULL OR license_types && license_filter) AND
+					(module_filter IS NULL OR module_path = module_filter OR
+						starts_with(module_path, module_filter || '/')) AND
+					(imports_filter IS NULL OR package_path IN (
+						SELECT from_path FROM imports_unique WHERE to_path = imports_filter)) AND
+					(go_mod_filter IS NULL OR has_go_mod = go_mod_filter) AND
+					(version_type_filter IS NULL OR EXISTS (
+						SELECT 1 FROM modules m
+						WHERE m.module_path = search_documents.module_path
+						AND m.version = search_documents.version
+						AND m.version_type = version_type_filter))
+				THEN 1 ELSE 0 END
+			) score,
+			COALESCE(v1_path, package_path) AS v1_path
+			FROM search_documents
+			ORDER BY imported_by_count DESC;
+	top series_search_result[];
+	res series_search_result;
+	last_idx INT;
+	dup INT;
+BEGIN
+	last_idx := lim+off;
+	top := array_fill(NULL::series_search_result, array[last_idx]);
+	OPEN cur(query := websearch_to_tsquery(rawquery));
+	FETCH cur INTO res;
+	WHILE found LOOP
+		IF top[last_idx] IS NULL OR res.score >= top[last_idx].score THEN
+			-- Keep only the best result of each series: skip res if top already
+			-- has a better result of its series, and otherwise remove that result.
+			-- Results are compared in the same order as below.
+			dup := NULL;
+			FOR i IN 1..last_idx LOOP
+				IF top[i].v1_path = res.v1_path THEN
+					dup := i;
+					EXIT;
+				END IF;
+			END LOOP;
+			IF dup IS NOT NULL AND (
+				(res.score > top[dup].score) OR
+				(res.score = top[dup].score AND res.commit_time > top[dup].commit_time) OR
+				(res.score = top[dup].score AND res.commit_time = top[dup].commit_time AND
+				 res.package_path < top[dup].package_path)) THEN
+				top := array_append(top[1:dup-1] || top[dup+1:last_idx], NULL::series_search_result);
+				dup := NULL;
+			END IF;
+			IF dup IS NULL THEN
+				FOR i IN 1..last_idx LOOP
+					IF top[i] IS NULL OR
+						(res.score > top[i].score) OR
+						(res.score = top[i].score AND res.commit_time > top[i].commit_time) OR
+						(res.score = top[i].score AND res.commit_time = top[i].commit_time AND
+						 res.package_path < top[i].package_path) THEN
+						top := (top[1:i-1] || res) || top[i:last_idx-1];
+						EXIT;
+					END IF;
+				END LOOP;
+			END IF;
+		END IF;
+		IF top[last_idx].score > ln(exp(1)+res.imported_by_count) THEN
+			EXIT;
+		END IF;
+		FETCH cur INTO res;
+	END LOOP;
+	CLOSE cur;
+	RETURN QUERY
+		SELECT package_path, module_path, version, commit_time, imported_by_count, score
+		FROM UNNEST(top[off+1:last_idx])
+		WHERE package_path IS NOT NULL AND score > 0.1;
+END; $$;
+
+CREATE OR REPLACE FUNCTION popular_search(
+	rawquery text, lim integer,
+	cursor_score double precision, cursor_commit_time timestamp with time zone, cursor_path text,
+	redist_factor real, go_mod_factor real,
+	license_filter text[], module_filter text, imports_filter text,
+	go_mod_filter boolean, version_type_filter version_type,
+	recency_weight real, recency_half_life real, active_versions real) RETURNS SETOF search_result
+    LANGUAGE plpgsql
+    AS $$
+	DECLARE cur CURSOR(query TSQUERY) FOR
+		SELECT
+			package_path,
+			module_path,
+			version,
+			commit_time,
+			imported_by_count,
+			(
+				-- default D, C, B, A weights are {0.1, 0.2, 0.4, 1.0}
+				ts_rank('{0.1, 0.2, 1.0, 1.0}', tsv_search_tokens, query) *
+				ln(exp(1)+imported_by_count) *
+				CASE WHEN redistributable THEN 1 ELSE redist_factor END *
+				CASE WHEN COALESCE(has_go_mod, true) THEN 1 ELSE go_mod_factor END *
+				-- The recency factor is at most 1, so that the early exit below remains
+				-- correct.
+				(1 - recency_weight * (1 - LEAST(1, GREATEST(
+					power(0.5, EXTRACT(EPOCH FROM (CURRENT_TIMESTAMP - commit_time)) /
+						(86400 * recency_half_life)),
+					num_recent_versions / active_versions)))) *
+				CASE WHEN tsv_search_tokens @@ query THEN 1 ELSE 0 END *
+				-- As with the tsv_search_tokens check above, documents that do not
+				-- match the filters have their score annihilated rather than being
+				-- excluded by a where clause, so that the query planner continues to
+				-- use the popular document index.
+				CASE WHEN
+					(license_filter IS NULL OR license_types && license_filter) AND
+					(module_filter IS NULL OR module_path = module_filter OR
+						starts_with(module_path, module_filter || '/')) AND
+					(imports_filter IS NULL OR package_path IN (
+						SELECT from_path FROM imports_unique WHERE to_path = imports_filter)) AND
+					(go_mod_filter IS NULL OR has_go_mod = go_mod_filter) AND
+					(version_type_filter IS NULL OR EXISTS (
+						SELECT 1 FROM modules m
+						WHERE m.module_path = search_documents.module_path
+						AND m.version = search_documents.version
+						AND m.version_type = version_type_filter))
+				THEN 1 ELSE 0 END
+			) score,
+			COALESCE(v1_path, package_path) AS v1_path
+			FROM search_documents
+			ORDER BY imported_by_count DESC;
+	top series_search_result[];
+	res series_search_result;
+	last_idx INT;
+	dup INT;
+	-- The series that have a result at or before the cursor. They were
+	-- returned on an earlier page.
+	done text[];
+BEGIN
+	last_idx := lim;
+	top := array_fill(NULL::series_search_result, array[last_idx]);
+	done := ARRAY[]::text[];
+	OPEN cur(query := websearch_to_tsquery(rawquery));
+	FETCH cur INTO res;
+	WHILE found LOOP
+		IF cursor_score IS NOT NULL AND NOT (
+			(res.score < cursor_score) OR
+			(res.score = cursor_score AND res.commit_time < cursor_commit_time) OR
+			(res.score = cursor_score AND res.commit_time = cursor_commit_time AND
+			 res.package_path > cursor_path)) THEN
+			-- res is at or before the cursor, so its series has already been
+			-- returned: remove any later result of the series from top.
+			IF NOT res.v1_path = ANY(done) THEN
+				done := array_append(done, res.v1_path);
+				FOR i IN 1..last_idx LOOP
+					IF top[i].v1_path = res.v1_path THEN
+						top := array_append(top[1:i-1] || top[i+1:last_idx], NULL::series_search_result);
+						EXIT;
+					END IF;
+				END LOOP;
+			END IF;
+		ELSIF NOT res.v1_path = ANY(done) AND
+			(top[last_idx] IS NULL OR res.score >= top[last_idx].score) THEN
+			-- Keep only the best result of each series: skip res if top already
+			-- has a better result of its series, and otherwise remove that result.
+			-- Results are compared in the same order as below.
+			dup := NULL;
+			FOR i IN 1..last_idx LOOP
+				IF top[i].v1_path = res.v1_path THEN
+					dup := i;
+					EXIT;
+				END IF;
+			END LOOP;
+			IF dup IS NOT NULL AND (
+				(res.score > top[dup].score) OR
+				(res.score = top[dup].score AND res.commit_time > top[dup].commit_time) OR
+				(res.score = top[dup].score AND res.commit_time = top[dup].commit_time AND
+				 res.package_path < top[dup].package_path)) THEN
+				top := array_append(top[1:dup-1] || top[dup+1:last_idx], NULL::series_search_result);
+				dup := NULL;
+			END IF;
+			IF dup IS NULL THEN
+				FOR i IN 1..last_idx LOOP
+					IF top[i] IS NULL OR
+						(res.score > top[i].score) OR
+						(res.score = top[i].score AND res.commit_time > top[i].commit_time) OR
+						(res.score = top[i].score AND res.commit_time = top[i].commit_time AND
+						 res.package_path < top[i].package_path) THEN
+						top := (top[1:i-1] || res) || top[i:last_idx-1];
+						EXIT;
+					END IF;
+				END LOOP;
+			END IF;
+		END IF;
+		IF top[last_idx].score > ln(exp(1)+res.imported_by_count) THEN
+			EXIT;
+		END IF;
+		FETCH cur INTO res;
+	END LOOP;
+	CLOSE cur;
+	RETURN QUERY
+		SELECT package_path, module_path, version, commit_time, imported_by_count, score
+		FROM UNNEST(top)
+		WHERE package_path IS NOT NULL AND score > 0.1;
+END; $$;
+
+END;
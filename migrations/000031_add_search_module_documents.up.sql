@@ -0,0 +1,20 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+ALTER TABLE search_documents ADD COLUMN is_module boolean DEFAULT false NOT NULL;
+COMMENT ON COLUMN search_documents.is_module IS
+'COLUMN is_module reports whether the record is for a module that has no package at its root, rather than for a package. The package_path of such a record is the module path, and its synopsis is taken from the module README.';
+
+-- Module records have no corresponding row in packages, so reference the
+-- module instead.
+ALTER TABLE search_documents
+	DROP CONSTRAINT search_documents_package_path_module_path_version_fkey;
+ALTER TABLE search_documents
+	ADD CONSTRAINT search_documents_module_path_version_fkey
+	FOREIGN KEY (module_path, version)
+	REFERENCES modules(module_path, version) ON DELETE CASCADE;
+
+END;
@@ -0,0 +1,23 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE search_readme_sections (
+    package_path text NOT NULL,
+    section_number integer NOT NULL,
+    title text NOT NULL,
+    anchor text NOT NULL,
+    tsv_search_tokens tsvector NOT NULL,
+    PRIMARY KEY (package_path, section_number),
+    FOREIGN KEY (package_path) REFERENCES search_documents(package_path) ON DELETE CASCADE
+);
+COMMENT ON TABLE search_readme_sections IS
+'TABLE search_readme_sections contains the sections of the README of each search document whose README is indexed, split at its top-level markdown headings. It is used to tell which part of a README matches a search.';
+COMMENT ON COLUMN search_readme_sections.anchor IS
+'COLUMN anchor is the id of the section heading in the README on the overview tab.';
+
+CREATE INDEX idx_search_readme_sections_tsv_search_tokens ON search_readme_sections USING gin (tsv_search_tokens);
+
+END;
@@ -0,0 +1,31 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TYPE dependency_kind AS ENUM ('require', 'replace', 'exclude');
+
+CREATE TABLE module_dependencies (
+    module_id integer NOT NULL,
+    kind dependency_kind NOT NULL,
+    dependency_path text NOT NULL,
+    dependency_version text NOT NULL,
+    indirect boolean DEFAULT false NOT NULL,
+    replacement_path text DEFAULT ''::text NOT NULL,
+    replacement_version text DEFAULT ''::text NOT NULL,
+    PRIMARY KEY (module_id, kind, dependency_path, dependency_version),
+    FOREIGN KEY (module_id) REFERENCES modules(id) ON DELETE CASCADE
+);
+COMMENT ON TABLE module_dependencies IS
+'TABLE module_dependencies contains the require, replace and exclude directives of the go.mod file of each module version.';
+COMMENT ON COLUMN module_dependencies.dependency_version IS
+'COLUMN dependency_version is the required or excluded version of dependency_path. For a replace directive, it is the replaced version, or the empty string if all versions are replaced.';
+COMMENT ON COLUMN module_dependencies.replacement_path IS
+'COLUMN replacement_path is the target of a replace directive, and the empty string for other directives.';
+COMMENT ON COLUMN module_dependencies.replacement_version IS
+'COLUMN replacement_version is the empty string if replacement_path is a directory.';
+
+CREATE INDEX idx_module_dependencies_dependency_path ON module_dependencies(dependency_path);
+
+END;
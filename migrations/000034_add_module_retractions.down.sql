@@ -0,0 +1,14 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+ALTER TABLE modules
+    DROP COLUMN go_mod_retractions,
+    DROP COLUMN go_mod_deprecation,
+    DROP COLUMN retracted,
+    DROP COLUMN retraction_rationale,
+    DROP COLUMN deprecation;
+
+END;
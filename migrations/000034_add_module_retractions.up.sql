@@ -0,0 +1,25 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+ALTER TABLE modules
+    ADD COLUMN go_mod_retractions jsonb,
+    ADD COLUMN go_mod_deprecation text DEFAULT ''::text NOT NULL,
+    ADD COLUMN retracted boolean DEFAULT false NOT NULL,
+    ADD COLUMN retraction_rationale text DEFAULT ''::text NOT NULL,
+    ADD COLUMN deprecation text DEFAULT ''::text NOT NULL;
+
+COMMENT ON COLUMN modules.go_mod_retractions IS
+'COLUMN go_mod_retractions holds the retract directives of the go.mod file of this version.';
+COMMENT ON COLUMN modules.go_mod_deprecation IS
+'COLUMN go_mod_deprecation is the deprecation message of the go.mod file of this version.';
+COMMENT ON COLUMN modules.retracted IS
+'COLUMN retracted is true if this version is retracted by the go.mod file of the latest version of the module.';
+COMMENT ON COLUMN modules.retraction_rationale IS
+'COLUMN retraction_rationale is the rationale for the retraction of this version, if any.';
+COMMENT ON COLUMN modules.deprecation IS
+'COLUMN deprecation is the deprecation message of the go.mod file of the latest version of the module.';
+
+END;
@@ -0,0 +1,29 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE rendered_packages (
+    content_hash text NOT NULL,
+    renderer_version integer NOT NULL,
+    data bytea NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    used_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (content_hash, renderer_version)
+);
+
+COMMENT ON TABLE rendered_packages IS
+'TABLE rendered_packages holds packages rendered by the worker, so that packages with identical source in other module versions can reuse them.';
+COMMENT ON COLUMN rendered_packages.content_hash IS
+'COLUMN content_hash is a hash of the source files of the package and everything else that determines how it is rendered.';
+COMMENT ON COLUMN rendered_packages.renderer_version IS
+'COLUMN renderer_version identifies the version of the rendering code that produced data.';
+COMMENT ON COLUMN rendered_packages.data IS
+'COLUMN data is the rendered package, encoded by the worker.';
+COMMENT ON COLUMN rendered_packages.used_at IS
+'COLUMN used_at is the last time the package was stored or reused. Packages that have not been used for a while are deleted.';
+
+CREATE INDEX idx_rendered_packages_used_at ON rendered_packages (used_at);
+
+END;
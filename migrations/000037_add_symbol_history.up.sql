@@ -0,0 +1,24 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE symbol_history (
+    package_path text NOT NULL,
+    module_path text NOT NULL,
+    version text NOT NULL,
+    name text NOT NULL,
+    parent_name text DEFAULT '' NOT NULL, -- empty except for methods
+    kind text NOT NULL,
+    signature text NOT NULL,
+    PRIMARY KEY (package_path, module_path, version, parent_name, name),
+    FOREIGN KEY (module_path, version) REFERENCES modules(module_path, version) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE symbol_history IS
+'TABLE symbol_history contains the exported identifiers declared in every version of each package. It is used to determine the version in which each identifier was added.';
+COMMENT ON COLUMN symbol_history.signature IS
+'COLUMN signature is the one-line summary of the declaration of the identifier, as shown in the index of the package documentation. It is empty for packages that are not redistributable.';
+
+END;
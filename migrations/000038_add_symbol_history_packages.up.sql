@@ -0,0 +1,18 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE symbol_history_packages (
+    package_path text NOT NULL,
+    module_path text NOT NULL,
+    version text NOT NULL,
+    PRIMARY KEY (package_path, module_path, version),
+    FOREIGN KEY (module_path, version) REFERENCES modules(module_path, version) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE symbol_history_packages IS
+'TABLE symbol_history_packages contains the package versions whose exported identifiers are recorded in symbol_history, including those with no exported identifiers. Package versions processed before symbol_history was added are not in it.';
+
+END;